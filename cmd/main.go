@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"cloudblast-backend/internal/auth"
 	"cloudblast-backend/internal/handlers"
+	"cloudblast-backend/internal/repositories"
 	"cloudblast-backend/internal/services"
 	"encoding/json"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/robfig/cron"
@@ -40,6 +42,31 @@ func main() {
 	//Initialize router
 	router := mux.NewRouter()
 
+	// Make POST requests with an Idempotency-Key header safe to retry
+	idempotencyRepo, err := repositories.NewRedisRepo("localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	router.Use(handlers.IdempotencyMiddleware(idempotencyRepo))
+
+	// Game results must be signed with the session's signing key, nonces are tracked in Redis
+	signatureRepo, err := repositories.NewRedisRepo("localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+
+	// Banned and suspended users and revoked tokens are turned away by AuthMiddleware, both are kept in Redis.
+	// Sanctions are read from the users in DynamoDB while Redis cannot be reached
+	sessionRepo, err := repositories.NewRedisRepo("localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	userRepo, err := repositories.NewDynamoDBRepository()
+	if err != nil {
+		log.Fatalf("Failed to create DynamoDB repository: %v", err)
+	}
+	auth.EnforceSessionChecks(sessionRepo, userRepo)
+
 	//API Endpoints
 	router.HandleFunc("/", healthCheck).Methods("GET")
 	router.HandleFunc("/api/tournament/StartTournament", handlers.HandleStartTournamentRoute(ch)).Methods("POST")
@@ -47,25 +74,89 @@ func main() {
 	router.HandleFunc("/api/user/CreateUser", handlers.HandleCreateUserRoute(ch)).Methods("POST")
 	router.HandleFunc("/api/user/Login", handlers.HandleLoginRoute(ch)).Methods("GET")
 	router.HandleFunc("/api/user/SearchUser", handlers.HandleSearchUserRoute(ch)).Methods("GET")
-	router.HandleFunc("/api/user/UpdateProgress", auth.AuthMiddleware(handlers.HandleUpdateProgressRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/user/UpdateProgress", auth.AuthMiddleware(handlers.SignatureMiddleware(ch, signatureRepo, handlers.HandleUpdateProgressRoute(ch)))).Methods("POST")
+	router.HandleFunc("/api/user/UpdateProfile", auth.AuthMiddleware(handlers.HandleUpdateProfileRoute(ch))).Methods("POST")
 	router.HandleFunc("/api/tournament/EnterTournament", auth.AuthMiddleware(handlers.HandleEnterTournamentRoute(ch))).Methods("POST")
-	router.HandleFunc("/api/tournament/UpdateScore", auth.AuthMiddleware(handlers.HandleUpdateScoreRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/tournament/UpdateScore", auth.AuthMiddleware(handlers.SignatureMiddleware(ch, signatureRepo, handlers.HandleUpdateScoreRoute(ch)))).Methods("POST")
 	router.HandleFunc("/api/tournament/ClaimReward", auth.AuthMiddleware(handlers.HandleClaimRewardRoute(ch))).Methods("POST")
 	router.HandleFunc("/api/tournament/GetTournamentRank", auth.AuthMiddleware(handlers.HandleGetGroupUserRankRoute(ch))).Methods("GET")
 	router.HandleFunc("/api/tournament/GetTournamentLeaderboard", auth.AuthMiddleware(handlers.HandleGetGroupLeaderboardWithRanksRoute(ch))).Methods("GET")
 	router.HandleFunc("/api/user/GetCountryLeaderboard", auth.AuthMiddleware(handlers.HandleGetCountryLeaderboardRoute(ch))).Methods("GET")
 	router.HandleFunc("/api/user/GetGlobalLeaderboard", auth.AuthMiddleware(handlers.HandleGetGlobalLeaderboardRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/user/DailyCheckIn", auth.AuthMiddleware(handlers.HandleDailyCheckInRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/user/GetCheckInStatus", auth.AuthMiddleware(handlers.HandleGetCheckInStatusRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/user/GetLives", auth.AuthMiddleware(handlers.HandleGetLivesRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/user/BuyLives", auth.AuthMiddleware(handlers.HandleBuyLivesRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/tournament/GetHallOfFameLeaderboard", auth.AuthMiddleware(handlers.HandleGetHallOfFameLeaderboardRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/tournament/GetHallOfFame", auth.AuthMiddleware(handlers.HandleGetHallOfFameRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/tournament/GetLeague", auth.AuthMiddleware(handlers.HandleGetLeagueRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/season/GetSeasons", auth.AuthMiddleware(handlers.HandleGetSeasonsRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/season/GetSeasonStanding", auth.AuthMiddleware(handlers.HandleGetSeasonStandingRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/season/GetSeasonLeaderboard", auth.AuthMiddleware(handlers.HandleGetSeasonLeaderboardRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/wallet/GetBalances", auth.AuthMiddleware(handlers.HandleGetBalancesRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/wallet/GetTransactionHistory", auth.AuthMiddleware(handlers.HandleGetTransactionHistoryRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/admin/wallet/GetUserTransactionHistory", auth.AdminMiddleware(handlers.HandleGetUserTransactionHistoryRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/admin/wallet/GrantCoins", auth.AdminMiddleware(handlers.HandleGrantCoinsRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/admin/wallet/RefundCoins", auth.AdminMiddleware(handlers.HandleRefundCoinsRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/store/GetCatalog", auth.AuthMiddleware(handlers.HandleGetCatalogRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/store/PurchaseItem", auth.AuthMiddleware(handlers.HandlePurchaseItemRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/store/GetInventory", auth.AuthMiddleware(handlers.HandleGetInventoryRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/store/ConsumeItem", auth.AuthMiddleware(handlers.HandleConsumeItemRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/level/CompleteLevel", auth.AuthMiddleware(handlers.SignatureMiddleware(ch, signatureRepo, handlers.HandleCompleteLevelRoute(ch)))).Methods("POST")
+	router.HandleFunc("/api/level/GetLevelResults", auth.AuthMiddleware(handlers.HandleGetLevelResultsRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/admin/anticheat/GetQuarantines", auth.AdminMiddleware(handlers.HandleGetQuarantinesRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/admin/anticheat/ReviewQuarantine", auth.AdminMiddleware(handlers.HandleReviewQuarantineRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/admin/anticheat/GetSecurityEvents", auth.AdminMiddleware(handlers.HandleGetSecurityEventsRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/admin/moderation/ApplySanction", auth.AdminMiddleware(handlers.HandleApplySanctionRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/admin/moderation/LiftSanction", auth.AdminMiddleware(handlers.HandleLiftSanctionRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/admin/moderation/GetModerationHistory", auth.AdminMiddleware(handlers.HandleGetModerationHistoryRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/user/ExportMyData", auth.AuthMiddleware(handlers.HandleExportMyDataRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/user/RequestAccountDeletion", auth.AuthMiddleware(handlers.HandleRequestAccountDeletionRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/user/CancelAccountDeletion", auth.AuthMiddleware(handlers.HandleCancelAccountDeletionRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/friends/GetFriendCode", auth.AuthMiddleware(handlers.HandleGetFriendCodeRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/friends/SendFriendRequest", auth.AuthMiddleware(handlers.HandleSendFriendRequestRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/friends/AcceptFriendRequest", auth.AuthMiddleware(handlers.HandleAcceptFriendRequestRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/friends/RemoveFriend", auth.AuthMiddleware(handlers.HandleRemoveFriendRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/friends/BlockUser", auth.AuthMiddleware(handlers.HandleBlockUserRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/friends/UnblockUser", auth.AuthMiddleware(handlers.HandleUnblockUserRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/friends/GetFriends", auth.AuthMiddleware(handlers.HandleGetFriendsRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/friends/GetFriendsLeaderboard", auth.AuthMiddleware(handlers.HandleGetFriendsLeaderboardRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/friends/GetFriendsInTournament", auth.AuthMiddleware(handlers.HandleGetFriendsInTournamentRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/clans/CreateClan", auth.AuthMiddleware(handlers.HandleCreateClanRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/clans/GetClan", auth.AuthMiddleware(handlers.HandleGetClanRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/clans/JoinClan", auth.AuthMiddleware(handlers.HandleJoinClanRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/clans/InviteToClan", auth.AuthMiddleware(handlers.HandleInviteToClanRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/clans/AcceptJoinRequest", auth.AuthMiddleware(handlers.HandleAcceptJoinRequestRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/clans/DeclineClanInvitation", auth.AuthMiddleware(handlers.HandleDeclineClanInvitationRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/clans/LeaveClan", auth.AuthMiddleware(handlers.HandleLeaveClanRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/clans/KickClanMember", auth.AuthMiddleware(handlers.HandleKickClanMemberRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/clans/SetClanRole", auth.AuthMiddleware(handlers.HandleSetClanRoleRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/clans/GetMyClanInvitations", auth.AuthMiddleware(handlers.HandleGetMyClanInvitationsRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/clans/GetClanActivity", auth.AuthMiddleware(handlers.HandleGetClanActivityRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/clans/GetTeamLeaderboard", auth.AuthMiddleware(handlers.HandleGetTeamLeaderboardRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/challenges/CreateChallenge", auth.AuthMiddleware(handlers.HandleCreateChallengeRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/challenges/AcceptChallenge", auth.AuthMiddleware(handlers.HandleAcceptChallengeRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/challenges/DeclineChallenge", auth.AuthMiddleware(handlers.HandleDeclineChallengeRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/challenges/GetChallenges", auth.AuthMiddleware(handlers.HandleGetChallengesRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/ratings/GetRatingHistory", auth.AuthMiddleware(handlers.HandleGetRatingHistoryRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/achievements/GetAchievements", auth.AuthMiddleware(handlers.HandleGetAchievementsRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/achievements/ClaimAchievement", auth.AuthMiddleware(handlers.HandleClaimAchievementRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/events/GetEvents", auth.AuthMiddleware(handlers.HandleGetEventsRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/mail/GetMailbox", auth.AuthMiddleware(handlers.HandleGetMailboxRoute(ch))).Methods("GET")
+	router.HandleFunc("/api/mail/ClaimMail", auth.AuthMiddleware(handlers.HandleClaimMailRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/mail/ClaimAllMail", auth.AuthMiddleware(handlers.HandleClaimAllMailRoute(ch))).Methods("POST")
+	router.HandleFunc("/api/admin/mail/SendMail", auth.AdminMiddleware(handlers.HandleSendMailRoute(ch))).Methods("POST")
 
 	
 	//Start user service
-	userService, err := services.NewUserService(conn)
+	userService, err := services.NewUserService(conn, "localhost:6379")
 	if err != nil {
 		log.Fatalf("Failed to initialize user_handler: %v", err)
 	}
 	go userService.Start()
 
 	// Start tournament service
-	tournamentService, err := services.NewTournamentService(conn)
+	tournamentService, err := services.NewTournamentService(conn, "localhost:6379")
 	if err != nil {
 		log.Fatalf("Failed to initialize tournament_service: %v", err)
 	}
@@ -77,6 +168,120 @@ func main() {
 	}
 	go leaderboardService.Start()
 
+	// Start season service
+	seasonService, err := services.NewSeasonService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize season_service: %v", err)
+	}
+	go seasonService.Start()
+
+	// Start wallet service
+	walletService, err := services.NewWalletService(conn)
+	if err != nil {
+		log.Fatalf("Failed to initialize wallet_service: %v", err)
+	}
+	go walletService.Start()
+
+	// Start store service
+	storeService, err := services.NewStoreService(conn)
+	if err != nil {
+		log.Fatalf("Failed to initialize store_service: %v", err)
+	}
+	go storeService.Start()
+
+	// Start level service
+	levelService, err := services.NewLevelService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize level_service: %v", err)
+	}
+	go levelService.Start()
+
+	// Start anti-cheat service
+	antiCheatService, err := services.NewAntiCheatService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize anticheat_service: %v", err)
+	}
+	go antiCheatService.Start()
+
+	// Start moderation service
+	moderationService, err := services.NewModerationService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize moderation_service: %v", err)
+	}
+	go moderationService.Start()
+
+	// Start privacy service
+	privacyService, err := services.NewPrivacyService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize privacy_service: %v", err)
+	}
+	go privacyService.Start()
+
+	// Start friend service
+	friendService, err := services.NewFriendService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize friend_service: %v", err)
+	}
+	go friendService.Start()
+
+	// Start clan service
+	clanService, err := services.NewClanService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize clan_service: %v", err)
+	}
+	go clanService.Start()
+
+	// Start challenge service
+	challengeService, err := services.NewChallengeService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize challenge_service: %v", err)
+	}
+	go challengeService.Start()
+
+	// Start rating service
+	ratingService, err := services.NewRatingService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize rating_service: %v", err)
+	}
+	go ratingService.Start()
+
+	// Start achievement service
+	achievementService, err := services.NewAchievementService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize achievement_service: %v", err)
+	}
+	go achievementService.Start()
+
+	// Start event service
+	eventService, err := services.NewEventService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize event_service: %v", err)
+	}
+	go eventService.Start()
+
+	// Start mail service
+	mailService, err := services.NewMailService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize mail_service: %v", err)
+	}
+	go mailService.Start()
+
+	// Start live service and its WebSocket route
+	liveService, err := services.NewLiveService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize live_service: %v", err)
+	}
+	go liveService.Start()
+	router.HandleFunc("/api/tournament/LiveLeaderboard", handlers.HandleLiveLeaderboardRoute(liveService)).Methods("GET")
+
+	// Start notification service and its Server-Sent Events route
+	notificationService, err := services.NewNotificationService(conn, "localhost:6379")
+	if err != nil {
+		log.Fatalf("Failed to initialize notification_service: %v", err)
+	}
+	go notificationService.Start()
+	router.HandleFunc("/api/user/NotificationStream", handlers.HandleNotificationStreamRoute(notificationService)).Methods("GET")
+
 	//Handle graceful shutdown
 	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, syscall.SIGINT, syscall.SIGTERM)
@@ -100,6 +305,22 @@ func main() {
 	userService.Stop()
 	tournamentService.Stop()
 	leaderboardService.Stop()
+	seasonService.Stop()
+	walletService.Stop()
+	storeService.Stop()
+	levelService.Stop()
+	antiCheatService.Stop()
+	moderationService.Stop()
+	privacyService.Stop()
+	friendService.Stop()
+	clanService.Stop()
+	challengeService.Stop()
+	ratingService.Stop()
+	achievementService.Stop()
+	eventService.Stop()
+	mailService.Stop()
+	liveService.Stop()
+	notificationService.Stop()
 	fmt.Println("Main service stopped.")
 }
 
@@ -120,6 +341,8 @@ func CallStartTournament() {
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	// Every instance runs the cron job, only the first call of the day starts a tournament
+	req.Header.Set("Idempotency-Key", "StartTournament-"+time.Now().UTC().Format("2006-01-02"))
 
 	client := http.DefaultClient
 	resp, err := client.Do(req)
@@ -149,6 +372,8 @@ func CallEndTournament() {
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	// Every instance runs the cron job, only the first call of the day ends the tournament
+	req.Header.Set("Idempotency-Key", "EndTournament-"+time.Now().UTC().Format("2006-01-02"))
 
 	client := http.DefaultClient
 	resp, err := client.Do(req)
@@ -1 +1,412 @@
-package config
\ No newline at end of file
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Usernames of the support staff allowed to use the admin routes, from a comma separated list
+var AdminUsers = getEnvList("ADMIN_USERS")
+
+// RewardTier pays a number of coins to every rank up to and including MaxRank
+type RewardTier struct {
+	MaxRank int
+	Coins   int
+}
+
+// Season settings
+var (
+	// Length of a season: "weekly", "monthly" or a number of days
+	SeasonPeriod = getEnv("SEASON_PERIOD", "weekly")
+
+	// Start of the first season, every later season follows it back to back
+	SeasonEpoch = getEnvTime("SEASON_EPOCH", time.Date(2023, time.September, 4, 0, 0, 0, 0, time.UTC))
+
+	// End-of-season coin rewards, paid for each season leaderboard separately
+	SeasonRewardTiers = []RewardTier{
+		{MaxRank: 1, Coins: 5000},
+		{MaxRank: 10, Coins: 2000},
+		{MaxRank: 100, Coins: 500},
+	}
+)
+
+// Currency is a virtual currency held in the players' wallets
+type Currency struct {
+	Name string
+	// Balances may go below zero
+	AllowNegative bool
+	// Balances expire, every credit carries the time its balance expires
+	Expires bool
+	// Credits that do not carry an expiry time expire this long after they are posted
+	Lifetime time.Duration
+	// Ledger entry types that may credit the currency
+	EarnTypes []string
+	// Ledger entry types that may debit the currency
+	SpendTypes []string
+}
+
+// Currency of the existing coin balance, used where no currency is specified
+const DefaultCurrency = "coins"
+
+// Currencies of the wallet
+var Currencies = []Currency{
+	{
+		Name:       "coins",
+		EarnTypes:  []string{"level_reward", "tournament_prize", "team_prize", "challenge_prize", "season_reward", "achievement_reward", "check_in_reward", "mail_reward", "admin_grant", "refund"},
+		SpendTypes: []string{"entry_fee", "store_purchase", "challenge_stake", "lives_purchase"},
+	},
+	{
+		Name:       "gems",
+		EarnTypes:  []string{"tournament_prize", "achievement_reward", "check_in_reward", "mail_reward", "admin_grant", "refund"},
+		SpendTypes: []string{"entry_fee", "store_purchase"},
+	},
+	{
+		Name:       "event_tokens",
+		Expires:    true,
+		Lifetime:   7 * 24 * time.Hour,
+		EarnTypes:  []string{"tournament_prize", "mail_reward", "admin_grant", "refund"},
+		SpendTypes: []string{"entry_fee", "store_purchase"},
+	},
+}
+
+// File the store catalog is loaded from, it is reloaded whenever its version changes
+var StoreCatalogPath = getEnv("STORE_CATALOG_PATH", "config/store_catalog.json")
+
+// File the levels catalog is loaded from, it is reloaded whenever its version changes
+var LevelsCatalogPath = getEnv("LEVELS_CATALOG_PATH", "config/levels_catalog.json")
+
+// File the achievements catalog is loaded from, it is reloaded whenever its version changes
+var AchievementsCatalogPath = getEnv("ACHIEVEMENTS_CATALOG_PATH", "config/achievements_catalog.json")
+
+// File the live events catalog is loaded from, it is reloaded whenever its version changes
+var EventsCatalogPath = getEnv("EVENTS_CATALOG_PATH", "config/events_catalog.json")
+
+// Events starting within this period are listed as upcoming
+var EventsUpcomingWindow = getEnvDuration("EVENTS_UPCOMING_WINDOW", 7*24*time.Hour)
+
+// Mailbox settings
+var (
+	// Time mail can be claimed for when the sender gives no expiry
+	MailLifetime = getEnvDuration("MAIL_LIFETIME", 30*24*time.Hour)
+	// Time expired and claimed mail is kept before it is deleted
+	MailRetention = getEnvDuration("MAIL_RETENTION", 30*24*time.Hour)
+	// Most rewards a mail can carry
+	MaxMailRewards = getEnvInt("MAX_MAIL_REWARDS", 5)
+)
+
+// File the profanity and reserved-name lists are loaded from, it is reloaded whenever its version changes
+var NameFilterPath = getEnv("NAME_FILTER_PATH", "config/name_filter.json")
+
+// Length limits of usernames, in characters
+var (
+	UsernameMinLength = getEnvInt("USERNAME_MIN_LENGTH", 3)
+	UsernameMaxLength = getEnvInt("USERNAME_MAX_LENGTH", 20)
+)
+
+// Longest display name, in characters
+var DisplayNameMaxLength = getEnvInt("DISPLAY_NAME_MAX_LENGTH", 24)
+
+// Players can change their country once in this period, so they cannot hop between country leaderboards
+var CountryChangeCooldown = getEnvDuration("COUNTRY_CHANGE_COOLDOWN", 30*24*time.Hour)
+
+// Players can change their timezone once in this period, so they cannot check in twice on the same day
+var TimezoneChangeCooldown = getEnvDuration("TIMEZONE_CHANGE_COOLDOWN", 7*24*time.Hour)
+
+// CheckInReward is paid for one day of a check-in streak
+type CheckInReward struct {
+	Currency string `json:"currency"`
+	Amount   int    `json:"amount"`
+}
+
+// Daily check-in settings
+var (
+	// Rewards for the days of a check-in streak, starting over after the last day
+	CheckInRewardCalendar = []CheckInReward{
+		{Currency: "coins", Amount: 50},
+		{Currency: "coins", Amount: 75},
+		{Currency: "coins", Amount: 100},
+		{Currency: "coins", Amount: 150},
+		{Currency: "coins", Amount: 200},
+		{Currency: "coins", Amount: 300},
+		{Currency: "gems", Amount: 5},
+	}
+
+	// Days a streak can miss in total before it starts over
+	CheckInFreezeDays = getEnvInt("CHECK_IN_FREEZE_DAYS", 1)
+)
+
+// Lives settings, every level result or score submitted uses a life
+var (
+	// Lives refill up to this many
+	LivesMax = getEnvInt("LIVES_MAX", 5)
+	// Time it takes to refill one life
+	LifeRefillInterval = getEnvDuration("LIFE_REFILL_INTERVAL", 30*time.Minute)
+	// Price of one life in coins
+	LifePrice = getEnvInt("LIFE_PRICE", 200)
+)
+
+// Most friends a player can have, pending friend requests count towards it
+var MaxFriends = getEnvInt("MAX_FRIENDS", 500)
+
+// Clan settings
+var (
+	// Most members a clan can have, the leader included
+	ClanMaxMembers = getEnvInt("CLAN_MAX_MEMBERS", 30)
+
+	// Entries of a clan's activity feed are kept this long
+	ClanActivityRetention = getEnvDuration("CLAN_ACTIVITY_RETENTION", 30*24*time.Hour)
+
+	// Coin pools paid to the best clans of a team tournament, each pool is shared by the clan's contributing members
+	TeamRewardTiers = []RewardTier{
+		{MaxRank: 1, Coins: 20000},
+		{MaxRank: 3, Coins: 10000},
+		{MaxRank: 10, Coins: 4000},
+	}
+
+	// How a clan's pool is shared: "proportional" to each member's contribution, or "equal" among the contributors
+	TeamRewardSplit = getEnv("TEAM_REWARD_SPLIT", "proportional")
+)
+
+// Challenge settings
+var (
+	// Highest coin stake of a challenge, each player puts it in escrow
+	ChallengeMaxStake = getEnvInt("CHALLENGE_MAX_STAKE", 5000)
+
+	// Opponents have this long to accept a challenge before the stake is refunded
+	ChallengeAcceptWindow = getEnvDuration("CHALLENGE_ACCEPT_WINDOW", 24*time.Hour)
+
+	// Points scored this long after a challenge is accepted count towards it
+	ChallengeDuration = getEnvDuration("CHALLENGE_DURATION", time.Hour)
+
+	// Random opponents are at most this many levels above or below the challenger
+	ChallengeLevelRange = getEnvInt("CHALLENGE_LEVEL_RANGE", 5)
+
+	// Most pending and running challenges a player can have
+	MaxOpenChallenges = getEnvInt("MAX_OPEN_CHALLENGES", 10)
+)
+
+// Skill rating settings, ratings are recomputed from the archived results whenever one of them changes
+var (
+	// Rating, deviation and volatility of players who were never rated
+	RatingInitial           = getEnvFloat("RATING_INITIAL", 1500)
+	RatingInitialDeviation  = getEnvFloat("RATING_INITIAL_DEVIATION", 350)
+	RatingInitialVolatility = getEnvFloat("RATING_INITIAL_VOLATILITY", 0.06)
+
+	// Glicko-2 system constant, lower values keep volatilities from changing much
+	RatingTau = getEnvFloat("RATING_TAU", 0.5)
+
+	// Length of a rating period, the deviation grows for every period a player does not play
+	RatingPeriod = getEnvDuration("RATING_PERIOD", 24*time.Hour)
+
+	// Ratings with a higher deviation are shown as provisional
+	RatingProvisionalDeviation = getEnvFloat("RATING_PROVISIONAL_DEVIATION", 110)
+
+	// Width of the rating brackets tournament groups are formed within, 0 forms groups by league only
+	RatingGroupBracket = getEnvInt("RATING_GROUP_BRACKET", 0)
+)
+
+// Accounts are erased this long after their users ask for it, they can change their mind until then
+var AccountDeletionGracePeriod = getEnvDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+
+// Highest number of stars a level can be completed with
+const MaxLevelStars = 3
+
+// League is a tier of players that are grouped together in tournaments
+type League struct {
+	Name string
+	// Amount charged to enter a tournament, in EntryFeeCurrency
+	EntryFee         int
+	EntryFeeCurrency string
+	// Factor applied to the tournament rewards, which are paid in RewardCurrency
+	RewardMultiplier float64
+	RewardCurrency   string
+	// Players finishing in the top PromoteTop of their group move up a league
+	PromoteTop int
+	// Players finishing in the bottom RelegateBottom of their group move down a league
+	RelegateBottom int
+}
+
+// Leagues from lowest to highest, new players start in the first one
+var Leagues = []League{
+	{Name: "Bronze", EntryFee: 500, EntryFeeCurrency: DefaultCurrency, RewardMultiplier: 1, RewardCurrency: DefaultCurrency, PromoteTop: 5, RelegateBottom: 0},
+	{Name: "Silver", EntryFee: 750, EntryFeeCurrency: DefaultCurrency, RewardMultiplier: 1.5, RewardCurrency: DefaultCurrency, PromoteTop: 5, RelegateBottom: 5},
+	{Name: "Gold", EntryFee: 1000, EntryFeeCurrency: DefaultCurrency, RewardMultiplier: 2, RewardCurrency: DefaultCurrency, PromoteTop: 4, RelegateBottom: 6},
+	{Name: "Platinum", EntryFee: 1500, EntryFeeCurrency: DefaultCurrency, RewardMultiplier: 3, RewardCurrency: DefaultCurrency, PromoteTop: 3, RelegateBottom: 7},
+	{Name: "Diamond", EntryFee: 2000, EntryFeeCurrency: DefaultCurrency, RewardMultiplier: 4, RewardCurrency: DefaultCurrency, PromoteTop: 0, RelegateBottom: 8},
+}
+
+// Number of players in a tournament group
+const GroupSize = 35
+
+// Players of a tournament are told it is ending soon this long before its end time
+const TournamentEndingSoonWindow = 15 * time.Minute
+
+// Approximate number of notifications kept for clients resuming a stream
+const NotificationHistoryLength = 10000
+
+// Responses to requests with an Idempotency-Key are replayed for this long
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// A request with an Idempotency-Key blocks its duplicates for at most this long while it is processed
+const IdempotencyLockTTL = time.Minute
+
+// Anti-cheat settings
+var (
+	// Shortest time a level can be played in, scores submitted closer together are rejected
+	AntiCheatMinLevelDuration = getEnvDuration("ANTICHEAT_MIN_LEVEL_DURATION", 20*time.Second)
+
+	// Most scores a player can submit within a minute
+	AntiCheatMaxScoresPerMinute = getEnvInt("ANTICHEAT_MAX_SCORES_PER_MINUTE", 3)
+
+	// Length of the sliding window the points scored by each player are compared over
+	AntiCheatStatsWindow = getEnvDuration("ANTICHEAT_STATS_WINDOW", time.Hour)
+
+	// Players scoring this many standard deviations above the rest of their group in the window are quarantined
+	AntiCheatOutlierThreshold = 4.0
+
+	// Fewest other players of a group who scored in the window before the group is checked for outliers
+	AntiCheatMinGroupSample = getEnvInt("ANTICHEAT_MIN_GROUP_SAMPLE", 5)
+)
+
+// Request signing settings
+var (
+	// Versions of the secret the session signing keys are derived from, as comma separated "<version>:<secret>" pairs
+	// New sessions sign with the highest version, older sessions keep working until their version is removed
+	SigningSecrets = parseSigningSecrets(getEnvList("SIGNING_SECRETS"), map[int]string{1: "cloudblast-signing"})
+
+	// Signed requests are rejected when their timestamp is further than this from the server time
+	SignatureMaxClockSkew = getEnvDuration("SIGNATURE_MAX_CLOCK_SKEW", 5*time.Minute)
+)
+
+// Get the index and settings of a league by name
+// Unknown names, such as those of users created before leagues existed, map to the first league
+func LeagueByName(name string) (int, League) {
+	for i, league := range Leagues {
+		if league.Name == name {
+			return i, league
+		}
+	}
+	return 0, Leagues[0]
+}
+
+// Get the settings of a currency by name
+func CurrencyByName(name string) (Currency, bool) {
+	for _, currency := range Currencies {
+		if currency.Name == name {
+			return currency, true
+		}
+	}
+	return Currency{}, false
+}
+
+// Check whether a ledger entry type may credit or debit a currency
+func (currency Currency) Allows(entryType string, amount int) bool {
+	types := currency.SpendTypes
+	if amount > 0 {
+		types = currency.EarnTypes
+	}
+	for _, allowed := range types {
+		if allowed == entryType {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the version of the signing secret new sessions sign with
+func CurrentSigningKeyVersion() int {
+	current := 0
+	for version := range SigningSecrets {
+		if version > current {
+			current = version
+		}
+	}
+	return current
+}
+
+// Check whether a user is a member of the support staff
+func IsAdmin(username string) bool {
+	for _, admin := range AdminUsers {
+		if admin == username {
+			return true
+		}
+	}
+	return false
+}
+
+// Get a string setting from the environment
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Get a comma separated list setting from the environment
+func getEnvList(key string) []string {
+	values := make([]string, 0)
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Get an integer setting from the environment
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Get a decimal setting from the environment
+func getEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(getEnv(key, ""), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Get a duration setting, such as "20s", from the environment
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Parse "<version>:<secret>" pairs, falling back to the given secrets if none is valid
+func parseSigningSecrets(pairs []string, fallback map[int]string) map[int]string {
+	secrets := make(map[int]string)
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			continue
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || version <= 0 {
+			continue
+		}
+		secrets[version] = parts[1]
+	}
+	if len(secrets) == 0 {
+		return fallback
+	}
+	return secrets
+}
+
+// Get an RFC3339 time setting from the environment
+func getEnvTime(key string, fallback time.Time) time.Time {
+	value, err := time.Parse(time.RFC3339, getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value.UTC()
+}
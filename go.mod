@@ -5,6 +5,7 @@ go 1.19
 require (
 	github.com/aws/aws-sdk-go v1.44.330
 	github.com/gorilla/mux v1.8.0
+	github.com/gorilla/websocket v1.5.0
 )
 
 require (
@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"cloudblast-backend/config"
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -11,10 +13,19 @@ import (
 
 var jwtSecret = []byte("cloudblast")
 
-func CreateToken(username string) (string, error) {
+// Tokens expire this long after they are issued
+const TokenLifetime = 24 * time.Hour
+
+// Create the token of a login session
+// The session ID identifies the session's request signing key
+func CreateToken(username string, sessionID string) (string, error) {
+	now := time.Now()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"username": username,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
+		"sid":      sessionID,
+		"iat":      now.Unix(),
+		"iat_ms":   now.UnixMilli(),
+		"exp":      now.Add(TokenLifetime).Unix(), // Token expires in 24 hours
 	})
 
 	return token.SignedString(jwtSecret)
@@ -34,6 +45,61 @@ func extractTokenFromHeader(r *http.Request) string {
 	return tokenParts[1]
 }
 
+// Validate a JWT token and return its claims
+func ValidateToken(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+
+	return claims, nil
+}
+
+// Get the username of an authenticated request
+// Browsers cannot set headers on WebSocket and EventSource requests, so the token may also be passed as the "token" query parameter
+func UsernameFromRequest(r *http.Request) (string, error) {
+	tokenString := extractTokenFromHeader(r)
+	if tokenString == "" {
+		tokenString = r.URL.Query().Get("token")
+	}
+	if tokenString == "" {
+		return "", errors.New("missing token")
+	}
+
+	claims, err := ValidateToken(tokenString)
+	if err != nil {
+		return "", err
+	}
+
+	username, ok := claims["username"].(string)
+	if !ok || username == "" {
+		return "", errors.New("missing username claim")
+	}
+
+	message, err := sanctionedMessage(username)
+	if err != nil {
+		return "", err
+	}
+	if message != "" {
+		return "", errors.New(message)
+	}
+	if tokenRevoked(username, claims) {
+		return "", errors.New("token revoked")
+	}
+
+	return username, nil
+}
+
 func AuthMiddleware(next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tokenString := extractTokenFromHeader(r)
@@ -57,8 +123,49 @@ func AuthMiddleware(next func(http.ResponseWriter, *http.Request)) func(http.Res
 			return
 		}
 
+		// Banned and suspended users are turned away for as long as the sanction lasts
+		username, _ := claims["username"].(string)
+		message, err := sanctionedMessage(username)
+		if err != nil {
+			http.Error(w, "Failed to check account status", http.StatusServiceUnavailable)
+			return
+		}
+		if message != "" {
+			http.Error(w, message, http.StatusForbidden)
+			return
+		}
+
+		// Tokens issued before their user signed out everywhere or deleted the account no longer work
+		if tokenRevoked(username, claims) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
 		r = r.WithContext(context.WithValue(r.Context(), "user", claims))
 
 		next(w, r)
 	})
-}
\ No newline at end of file
+}
+
+// Only let support staff through, as configured with the ADMIN_USERS environment variable
+func AdminMiddleware(next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if !config.IsAdmin(UsernameFromContext(r)) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// Get the username of a request that went through AuthMiddleware
+func UsernameFromContext(r *http.Request) string {
+	claims, ok := r.Context().Value("user").(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+
+	username, _ := claims["username"].(string)
+	return username
+}
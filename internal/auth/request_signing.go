@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"cloudblast-backend/config"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Returned when a request is signed with a key version that is not configured
+var ErrUnknownKeyVersion = errors.New("unknown signing key version")
+
+// Derive the key a login session signs its game result requests with
+// Keys are derived from the versioned signing secret, so they do not have to be stored
+func SessionSigningKey(keyVersion int, username string, sessionID string) ([]byte, error) {
+	secret, ok := config.SigningSecrets[keyVersion]
+	if !ok {
+		return nil, ErrUnknownKeyVersion
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(username + ":" + sessionID))
+	return mac.Sum(nil), nil
+}
+
+// Build the canonical form of a request that is signed
+// It is the method, path, timestamp, nonce and the hex SHA-256 of the body, separated by newlines
+func CanonicalRequest(method string, path string, timestamp string, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{method, path, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")
+}
+
+// Sign a canonical request with a session signing key
+// Returns the hex HMAC-SHA256 of the canonical request
+func SignRequest(key []byte, canonicalRequest string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(canonicalRequest))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Get the session ID of a request that went through AuthMiddleware
+// Returns an empty string for tokens issued before sessions had IDs
+func SessionIDFromContext(r *http.Request) string {
+	claims, ok := r.Context().Value("user").(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+
+	sessionID, _ := claims["sid"].(string)
+	return sessionID
+}
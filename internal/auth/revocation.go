@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Get the key holding the time before which a user's tokens are revoked
+// It only needs to outlive the tokens it revokes, so it is set with TokenLifetime as its TTL
+func TokensRevokedKey(username string) string {
+	return "tokens-revoked:" + username
+}
+
+// Get the value that revokes the tokens issued up to and including the given time
+// It is kept in milliseconds, so that a token issued in the same second after the revocation stays valid
+func TokensRevokedValue(at time.Time) []byte {
+	return []byte(strconv.FormatInt(at.UnixMilli(), 10))
+}
+
+// Values below this were written in seconds, before revocations were kept in milliseconds
+const revokedSecondsBelow = 1e12
+
+// Check whether a token was issued before its user's tokens were revoked
+// Tokens without the "iat_ms" claim count as issued at the start of their "iat" second, and tokens from
+// before the "iat" claim existed as issued at the epoch
+func tokenRevoked(username string, claims jwt.MapClaims) bool {
+	if sessionStore == nil {
+		return false
+	}
+	value, err := sessionStore.GetValue(TokensRevokedKey(username))
+	if err != nil {
+		log.Printf("Failed to get token revocation of %s: %v", username, err)
+		return false
+	}
+	if value == nil {
+		return false
+	}
+
+	revokedUntil, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return false
+	}
+	if revokedUntil < revokedSecondsBelow {
+		revokedUntil = revokedUntil*1000 + 999
+	}
+	return tokenIssuedAt(claims) <= revokedUntil
+}
+
+// Get the time a token was issued at in Unix milliseconds
+func tokenIssuedAt(claims jwt.MapClaims) int64 {
+	if issuedAt, ok := claims["iat_ms"].(float64); ok {
+		return int64(issuedAt)
+	}
+	issuedAt, _ := claims["iat"].(float64)
+	return int64(issuedAt) * 1000
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// mapStore is a session store reading from a map
+type mapStore map[string][]byte
+
+func (store mapStore) GetValue(key string) ([]byte, error) {
+	return store[key], nil
+}
+
+func TestTokenRevoked(t *testing.T) {
+	revokedAt := time.Date(2024, time.March, 10, 12, 0, 0, 400*int(time.Millisecond), time.UTC)
+	sameSecondLater := revokedAt.Add(300 * time.Millisecond)
+
+	tests := []struct {
+		name    string
+		revoked []byte
+		claims  jwt.MapClaims
+		want    bool
+	}{
+		{"issued before", TokensRevokedValue(revokedAt), jwt.MapClaims{"iat_ms": float64(revokedAt.Add(-time.Second).UnixMilli())}, true},
+		{"issued at the revocation", TokensRevokedValue(revokedAt), jwt.MapClaims{"iat_ms": float64(revokedAt.UnixMilli())}, true},
+		{"issued later in the same second", TokensRevokedValue(revokedAt), jwt.MapClaims{"iat_ms": float64(sameSecondLater.UnixMilli())}, false},
+		{"issued in the next second", TokensRevokedValue(revokedAt), jwt.MapClaims{"iat_ms": float64(revokedAt.Add(time.Second).UnixMilli())}, false},
+		{"only iat, same second", TokensRevokedValue(revokedAt), jwt.MapClaims{"iat": float64(revokedAt.Unix())}, true},
+		{"only iat, next second", TokensRevokedValue(revokedAt), jwt.MapClaims{"iat": float64(revokedAt.Unix() + 1)}, false},
+		{"no iat", TokensRevokedValue(revokedAt), jwt.MapClaims{}, true},
+		{"revoked in seconds, same second", []byte(strconv.FormatInt(revokedAt.Unix(), 10)), jwt.MapClaims{"iat_ms": float64(sameSecondLater.UnixMilli())}, true},
+		{"revoked in seconds, next second", []byte(strconv.FormatInt(revokedAt.Unix(), 10)), jwt.MapClaims{"iat_ms": float64(revokedAt.Add(time.Second).UnixMilli())}, false},
+		{"not revoked", nil, jwt.MapClaims{"iat_ms": float64(revokedAt.UnixMilli())}, false},
+	}
+
+	t.Cleanup(func() { EnforceSessionChecks(nil, nil) })
+	for _, test := range tests {
+		EnforceSessionChecks(mapStore{TokensRevokedKey("alice"): test.revoked}, nil)
+		if got := tokenRevoked("alice", test.claims); got != test.want {
+			t.Errorf("%s: tokenRevoked() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"cloudblast-backend/internal/models"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// SessionStore holds what ends sessions before their tokens expire: sanctions and token revocations
+type SessionStore interface {
+	GetValue(key string) ([]byte, error)
+}
+
+// UserStore holds the users, whose sanction is read from their item when the session store cannot be read
+type UserStore interface {
+	GetUserByUsername(username string) (*models.User, error)
+}
+
+var sessionStore SessionStore
+var userStore UserStore
+
+// Turn away banned and suspended users and revoked tokens in AuthMiddleware, even if the token is still valid
+func EnforceSessionChecks(store SessionStore, users UserStore) {
+	sessionStore = store
+	userStore = users
+}
+
+// Get the key of a user's sanction
+func SanctionKey(username string) string {
+	return "sanction:" + username
+}
+
+// Get the sanction of a user, nil if the user is in good standing
+// Suspensions expire with their key, shadow-bans are stored but do not keep the user out
+func GetSanction(store SessionStore, username string) (*models.Sanction, error) {
+	value, err := store.GetValue(SanctionKey(username))
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	var sanction models.Sanction
+	err = json.Unmarshal(value, &sanction)
+	if err != nil {
+		return nil, err
+	}
+	if sanction.Status == models.ModerationSuspended && sanction.SuspendedUntil != nil && !time.Now().Before(*sanction.SuspendedUntil) {
+		return nil, nil
+	}
+	return &sanction, nil
+}
+
+// Get the reason a user is turned away, empty if the user can sign in
+func SanctionMessage(sanction *models.Sanction) string {
+	if sanction == nil {
+		return ""
+	}
+	switch sanction.Status {
+	case models.ModerationBanned:
+		return "Account is banned"
+	case models.ModerationSuspended:
+		if sanction.SuspendedUntil != nil {
+			return "Account is suspended until " + sanction.SuspendedUntil.UTC().Format(time.RFC3339)
+		}
+		return "Account is suspended"
+	}
+	return ""
+}
+
+// Get a user's sanction from their user item, nil if the user is in good standing
+func userSanction(users UserStore, username string, now time.Time) (*models.Sanction, error) {
+	user, err := users.GetUserByUsername(username)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	if !user.IsSanctionedAt(now) {
+		return nil, nil
+	}
+	return &models.Sanction{Status: user.ModerationStatusAt(now), SuspendedUntil: user.Suspended_Until}, nil
+}
+
+// Check whether a user is kept out by a sanction
+// The sanction is read from the user's item when the cached one cannot be read, and an error is
+// returned if neither can, so that sanctioned users are never let through
+func sanctionedMessage(username string) (string, error) {
+	if sessionStore == nil {
+		return "", nil
+	}
+	sanction, err := GetSanction(sessionStore, username)
+	if err != nil {
+		log.Printf("Failed to get sanction of %s: %v", username, err)
+		if userStore == nil {
+			return "", err
+		}
+		sanction, err = userSanction(userStore, username, time.Now())
+		if err != nil {
+			return "", err
+		}
+	}
+	return SanctionMessage(sanction), nil
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"cloudblast-backend/internal/models"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// unreachableStore is a session store whose reads always fail
+type unreachableStore struct{}
+
+func (unreachableStore) GetValue(key string) ([]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+// userStoreFunc turns a function into a UserStore
+type userStoreFunc func(username string) (*models.User, error)
+
+func (f userStoreFunc) GetUserByUsername(username string) (*models.User, error) {
+	return f(username)
+}
+
+func TestAuthMiddlewareReadsSanctionsFromUsersWhenTheCacheFails(t *testing.T) {
+	suspendedUntil := time.Now().Add(time.Hour)
+	ended := time.Now().Add(-time.Hour)
+	users := map[string]*models.User{
+		"banned":    {Username: "banned", Moderation_Status: models.ModerationBanned},
+		"suspended": {Username: "suspended", Moderation_Status: models.ModerationSuspended, Suspended_Until: &suspendedUntil},
+		"served":    {Username: "served", Moderation_Status: models.ModerationSuspended, Suspended_Until: &ended},
+		"shadow":    {Username: "shadow", Moderation_Status: models.ModerationShadowBanned},
+		"player":    {Username: "player"},
+	}
+	usersUp := userStoreFunc(func(username string) (*models.User, error) {
+		return users[username], nil
+	})
+	usersDown := userStoreFunc(func(username string) (*models.User, error) {
+		return nil, errors.New("throttled")
+	})
+
+	tests := []struct {
+		name     string
+		username string
+		users    UserStore
+		want     int
+	}{
+		{"banned user", "banned", usersUp, http.StatusForbidden},
+		{"suspended user", "suspended", usersUp, http.StatusForbidden},
+		{"suspension ended", "served", usersUp, http.StatusOK},
+		{"shadow-banned users can sign in", "shadow", usersUp, http.StatusOK},
+		{"user in good standing", "player", usersUp, http.StatusOK},
+		{"users cannot be read either", "player", usersDown, http.StatusServiceUnavailable},
+		{"no user store", "player", nil, http.StatusServiceUnavailable},
+	}
+
+	t.Cleanup(func() { EnforceSessionChecks(nil, nil) })
+	for _, test := range tests {
+		EnforceSessionChecks(unreachableStore{}, test.users)
+
+		token, err := CreateToken(test.username, "session-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodGet, "/api/user/GetUser", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {})(w, r)
+
+		if w.Code != test.want {
+			t.Errorf("%s: status %d, want %d", test.name, w.Code, test.want)
+		}
+	}
+}
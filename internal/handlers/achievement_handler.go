@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"cloudblast-backend/internal/auth"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// Handler for the /api/achievements/GetAchievements route
+func HandleGetAchievementsRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own achievements
+        requestData.Username = auth.UsernameFromContext(r)
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the achievement queue - send to AchievementService
+        PublishToRabbitMQ(ch, "achievementQueue", "GetAchievements", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/achievements/ClaimAchievement route
+func HandleClaimAchievementRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action        string `json:"action"`
+            Username      string `json:"username"`
+            AchievementID string `json:"achievement_id"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own achievements
+        requestData.Username = auth.UsernameFromContext(r)
+
+        if requestData.AchievementID == "" {
+            http.Error(w, "Achievement ID is required", http.StatusBadRequest)
+            return
+        }
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the achievement queue - send to AchievementService
+        PublishToRabbitMQ(ch, "achievementQueue", "ClaimAchievement", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"cloudblast-backend/internal/auth"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// Handler for the /api/admin/anticheat/GetQuarantines route
+func HandleGetQuarantinesRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action string `json:"action"`
+            Admin  string `json:"admin"`
+            Status string `json:"status"`
+            Limit  int64  `json:"limit"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Record which member of the support staff made the request
+        requestData.Admin = auth.UsernameFromContext(r)
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the anti-cheat queue - send to anticheat_service
+        PublishToRabbitMQ(ch, "antiCheatQueue", "GetQuarantines", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/admin/anticheat/ReviewQuarantine route
+func HandleReviewQuarantineRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action       string `json:"action"`
+            Admin        string `json:"admin"`
+            Username     string `json:"username"`
+            TournamentID string `json:"tournament_id"`
+            Decision     string `json:"decision"`
+            Note         string `json:"note"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        if requestData.Username == "" {
+            http.Error(w, "Username is required", http.StatusBadRequest)
+            return
+        }
+
+        if requestData.TournamentID == "" {
+            http.Error(w, "Tournament ID is required", http.StatusBadRequest)
+            return
+        }
+
+        if requestData.Decision == "" {
+            http.Error(w, "Decision is required", http.StatusBadRequest)
+            return
+        }
+
+        if requestData.Note == "" {
+            http.Error(w, "Note is required", http.StatusBadRequest)
+            return
+        }
+
+        // Record which member of the support staff reviewed the scores
+        requestData.Admin = auth.UsernameFromContext(r)
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the anti-cheat queue - send to anticheat_service
+        PublishToRabbitMQ(ch, "antiCheatQueue", "ReviewQuarantine", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/admin/anticheat/GetSecurityEvents route
+func HandleGetSecurityEventsRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Admin    string `json:"admin"`
+            Username string `json:"username"`
+            Limit    int64  `json:"limit"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        if requestData.Username == "" {
+            http.Error(w, "Username is required", http.StatusBadRequest)
+            return
+        }
+
+        // Record which member of the support staff made the request
+        requestData.Admin = auth.UsernameFromContext(r)
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the anti-cheat queue - send to anticheat_service
+        PublishToRabbitMQ(ch, "antiCheatQueue", "GetSecurityEvents", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
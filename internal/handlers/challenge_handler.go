@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"cloudblast-backend/internal/auth"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// Handler for the /api/challenges/CreateChallenge route
+func HandleCreateChallengeRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+            Opponent string `json:"opponent"`
+            Random   bool   `json:"random"`
+            Stake    int    `json:"stake"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own challenges
+        requestData.Username = auth.UsernameFromContext(r)
+
+        if requestData.Opponent == "" && !requestData.Random {
+            http.Error(w, "Opponent is required unless the opponent is random", http.StatusBadRequest)
+            return
+        }
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the challenge queue - send to ChallengeService
+        PublishToRabbitMQ(ch, "challengeQueue", "CreateChallenge", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/challenges/AcceptChallenge route
+func HandleAcceptChallengeRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action      string `json:"action"`
+            Username    string `json:"username"`
+            ChallengeID string `json:"challenge_id"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own challenges
+        requestData.Username = auth.UsernameFromContext(r)
+
+        if requestData.ChallengeID == "" {
+            http.Error(w, "Challenge ID is required", http.StatusBadRequest)
+            return
+        }
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the challenge queue - send to ChallengeService
+        PublishToRabbitMQ(ch, "challengeQueue", "AcceptChallenge", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/challenges/DeclineChallenge route
+func HandleDeclineChallengeRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action      string `json:"action"`
+            Username    string `json:"username"`
+            ChallengeID string `json:"challenge_id"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own challenges
+        requestData.Username = auth.UsernameFromContext(r)
+
+        if requestData.ChallengeID == "" {
+            http.Error(w, "Challenge ID is required", http.StatusBadRequest)
+            return
+        }
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the challenge queue - send to ChallengeService
+        PublishToRabbitMQ(ch, "challengeQueue", "DeclineChallenge", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/challenges/GetChallenges route
+func HandleGetChallengesRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own challenges
+        requestData.Username = auth.UsernameFromContext(r)
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the challenge queue - send to ChallengeService
+        PublishToRabbitMQ(ch, "challengeQueue", "GetChallenges", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
@@ -0,0 +1,1155 @@
+package handlers
+
+import (
+	"cloudblast-backend/internal/auth"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// Handler for the /api/clans/CreateClan route
+func HandleCreateClanRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action      string `json:"action"`
+            Username    string `json:"username"`
+            Name        string `json:"name"`
+            Description string `json:"description"`
+            JoinPolicy  string `json:"join_policy"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own clan membership
+        requestData.Username = auth.UsernameFromContext(r)
+
+        if requestData.Name == "" {
+            http.Error(w, "Clan name is required", http.StatusBadRequest)
+            return
+        }
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the clan queue - send to ClanService
+        PublishToRabbitMQ(ch, "clanQueue", "CreateClan", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/clans/GetClan route
+func HandleGetClanRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+            ClanID   string `json:"clan_id"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own clan membership
+        requestData.Username = auth.UsernameFromContext(r)
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the clan queue - send to ClanService
+        PublishToRabbitMQ(ch, "clanQueue", "GetClan", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/clans/JoinClan route
+func HandleJoinClanRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+            ClanID   string `json:"clan_id"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own clan membership
+        requestData.Username = auth.UsernameFromContext(r)
+
+        if requestData.ClanID == "" {
+            http.Error(w, "Clan ID is required", http.StatusBadRequest)
+            return
+        }
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the clan queue - send to ClanService
+        PublishToRabbitMQ(ch, "clanQueue", "JoinClan", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/clans/InviteToClan route
+func HandleInviteToClanRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+            Target   string `json:"target"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own clan membership
+        requestData.Username = auth.UsernameFromContext(r)
+
+        if requestData.Target == "" {
+            http.Error(w, "Target is required", http.StatusBadRequest)
+            return
+        }
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the clan queue - send to ClanService
+        PublishToRabbitMQ(ch, "clanQueue", "InviteToClan", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/clans/AcceptJoinRequest route
+func HandleAcceptJoinRequestRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+            Target   string `json:"target"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own clan membership
+        requestData.Username = auth.UsernameFromContext(r)
+
+        if requestData.Target == "" {
+            http.Error(w, "Target is required", http.StatusBadRequest)
+            return
+        }
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the clan queue - send to ClanService
+        PublishToRabbitMQ(ch, "clanQueue", "AcceptJoinRequest", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/clans/DeclineClanInvitation route
+func HandleDeclineClanInvitationRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+            ClanID   string `json:"clan_id"`
+            Target   string `json:"target"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own clan membership
+        requestData.Username = auth.UsernameFromContext(r)
+
+        if requestData.ClanID == "" && requestData.Target == "" {
+            http.Error(w, "Clan ID or target is required", http.StatusBadRequest)
+            return
+        }
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the clan queue - send to ClanService
+        PublishToRabbitMQ(ch, "clanQueue", "DeclineClanInvitation", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/clans/LeaveClan route
+func HandleLeaveClanRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own clan membership
+        requestData.Username = auth.UsernameFromContext(r)
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the clan queue - send to ClanService
+        PublishToRabbitMQ(ch, "clanQueue", "LeaveClan", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/clans/KickClanMember route
+func HandleKickClanMemberRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+            Target   string `json:"target"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own clan membership
+        requestData.Username = auth.UsernameFromContext(r)
+
+        if requestData.Target == "" {
+            http.Error(w, "Target is required", http.StatusBadRequest)
+            return
+        }
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the clan queue - send to ClanService
+        PublishToRabbitMQ(ch, "clanQueue", "KickClanMember", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/clans/SetClanRole route
+func HandleSetClanRoleRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+            Target   string `json:"target"`
+            Role     string `json:"role"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own clan membership
+        requestData.Username = auth.UsernameFromContext(r)
+
+        if requestData.Target == "" {
+            http.Error(w, "Target is required", http.StatusBadRequest)
+            return
+        }
+
+        if requestData.Role == "" {
+            http.Error(w, "Role is required", http.StatusBadRequest)
+            return
+        }
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the clan queue - send to ClanService
+        PublishToRabbitMQ(ch, "clanQueue", "SetClanRole", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/clans/GetMyClanInvitations route
+func HandleGetMyClanInvitationsRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own clan membership
+        requestData.Username = auth.UsernameFromContext(r)
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the clan queue - send to ClanService
+        PublishToRabbitMQ(ch, "clanQueue", "GetMyClanInvitations", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/clans/GetClanActivity route
+func HandleGetClanActivityRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+            Limit    int    `json:"limit"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own clan membership
+        requestData.Username = auth.UsernameFromContext(r)
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the clan queue - send to ClanService
+        PublishToRabbitMQ(ch, "clanQueue", "GetClanActivity", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
+
+// Handler for the /api/clans/GetTeamLeaderboard route
+func HandleGetTeamLeaderboardRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+    return func(w http.ResponseWriter, r *http.Request) {
+        // Parse the request body
+        var requestData struct {
+            Action   string `json:"action"`
+            Username string `json:"username"`
+        }
+
+        err := json.NewDecoder(r.Body).Decode(&requestData)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+
+        // Players act on their own clan membership
+        requestData.Username = auth.UsernameFromContext(r)
+
+        // Generate a correlation ID for the request
+        correlationID := uuid.New().String()
+
+        // Create a reply queue for the response
+        replyQueue, err := ch.QueueDeclare(
+            "",
+            false,
+            true,
+            true,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to create reply queue", http.StatusInternalServerError)
+            return
+        }
+
+        // Set up a consumer for the reply queue
+        msgs, err := ch.Consume(
+            replyQueue.Name,
+            "",
+            true,
+            false,
+            false,
+            false,
+            nil,
+        )
+        if err != nil {
+            http.Error(w, "Failed to set up reply consumer", http.StatusInternalServerError)
+            return
+        }
+
+        // Publish the request to the clan queue - send to ClanService
+        PublishToRabbitMQ(ch, "clanQueue", "GetTeamLeaderboard", requestData, replyQueue.Name, correlationID)
+
+        // Wait for a response
+        for msg := range msgs {
+            if msg.CorrelationId == correlationID {
+                var response struct {
+                    Action string                 `json:"action"`
+                    Data   map[string]interface{} `json:"data"`
+                }
+
+                err := json.Unmarshal(msg.Body, &response)
+                if err != nil {
+                    http.Error(w, "Failed to unmarshal response data", http.StatusInternalServerError)
+                    return
+                }
+                data := response.Data
+
+                errorVal, errorExists := data["error"].(string)
+                if errorExists {
+                    http.Error(w, errorVal, http.StatusInternalServerError)
+                    return
+                }
+
+                responseDataJSON, err := json.Marshal(data)
+                if err != nil {
+                    http.Error(w, "Failed to marshal response data", http.StatusInternalServerError)
+                    return
+                }
+
+                w.Header().Set("Content-Type", "application/json")
+                w.WriteHeader(http.StatusOK)
+                w.Write(responseDataJSON)
+                return
+            }
+        }
+
+        http.Error(w, "No response received", http.StatusRequestTimeout)
+    }
+}
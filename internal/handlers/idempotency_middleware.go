@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bytes"
+	"cloudblast-backend/config"
+	"cloudblast-backend/internal/auth"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// IdempotencyStore keeps the state of requests with an Idempotency-Key, it is Redis in production
+type IdempotencyStore interface {
+	SetValueIfNotExists(key string, value []byte, ttl time.Duration) (bool, error)
+	SetValue(key string, value []byte, ttl time.Duration) error
+	GetValue(key string) ([]byte, error)
+	DeleteKeys(keys ...string) error
+}
+
+// Header clients send to make a POST request safe to retry
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyRecord is the state of a request with an Idempotency-Key
+type idempotencyRecord struct {
+	// Hash of the request, a key may only be reused for the same request
+	Fingerprint string `json:"fingerprint"`
+	Completed   bool   `json:"completed"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// idempotencyRecorder captures the response of a request while writing it to the client
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) WriteHeader(statusCode int) {
+	rec.statusCode = statusCode
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *idempotencyRecorder) Write(data []byte) (int, error) {
+	rec.body.Write(data)
+	return rec.ResponseWriter.Write(data)
+}
+
+// Make POST requests with an Idempotency-Key header safe to retry
+// The first successful response for a key and user is stored and replayed for every retry. Reusing
+// the key for a different request is rejected, and so are duplicates sent while the first is still processed.
+func IdempotencyMiddleware(store IdempotencyStore) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > 255 {
+				http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := sha256.New()
+			hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+			hash.Write(body)
+			fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+			// Keys are scoped to the user. Requests without a token are scoped to their content, credentials
+			// included, so that a client cannot be replayed the response to another client's request
+			scope, err := auth.UsernameFromRequest(r)
+			if err != nil || scope == "" {
+				scope = "anonymous:" + fingerprint
+			}
+			recordKey := "idempotency:" + scope + ":" + key
+
+			// Claim the key, only one of concurrent duplicates gets through
+			processing, err := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+			if err != nil {
+				http.Error(w, "Failed to check Idempotency-Key", http.StatusInternalServerError)
+				return
+			}
+			first, err := store.SetValueIfNotExists(recordKey, processing, config.IdempotencyLockTTL)
+			if err != nil {
+				http.Error(w, "Failed to check Idempotency-Key", http.StatusInternalServerError)
+				return
+			}
+
+			if !first {
+				replayIdempotentResponse(w, store, recordKey, fingerprint)
+				return
+			}
+
+			recorder := &idempotencyRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			// Failed requests give the key up, so that retrying them runs them again
+			if recorder.statusCode < 200 || recorder.statusCode >= 300 {
+				err = store.DeleteKeys(recordKey)
+				if err != nil {
+					log.Printf("Failed to release Idempotency-Key %s: %v", key, err)
+				}
+				return
+			}
+
+			record, err := json.Marshal(idempotencyRecord{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				StatusCode:  recorder.statusCode,
+				ContentType: recorder.Header().Get("Content-Type"),
+				Body:        recorder.body.Bytes(),
+			})
+			if err == nil {
+				err = store.SetValue(recordKey, record, config.IdempotencyKeyTTL)
+			}
+			if err != nil {
+				log.Printf("Failed to store response for Idempotency-Key %s: %v", key, err)
+			}
+		})
+	}
+}
+
+// Answer a request whose Idempotency-Key was already used
+func replayIdempotentResponse(w http.ResponseWriter, store IdempotencyStore, recordKey, fingerprint string) {
+	data, err := store.GetValue(recordKey)
+	if err != nil {
+		http.Error(w, "Failed to check Idempotency-Key", http.StatusInternalServerError)
+		return
+	}
+
+	// The first request gave up its claim in the meantime
+	if data == nil {
+		http.Error(w, "A request with this Idempotency-Key is being processed", http.StatusConflict)
+		return
+	}
+
+	var record idempotencyRecord
+	err = json.Unmarshal(data, &record)
+	if err != nil {
+		http.Error(w, "Failed to check Idempotency-Key", http.StatusInternalServerError)
+		return
+	}
+
+	if record.Fingerprint != fingerprint {
+		http.Error(w, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+		return
+	}
+	if !record.Completed {
+		http.Error(w, "A request with this Idempotency-Key is being processed", http.StatusConflict)
+		return
+	}
+
+	if record.ContentType != "" {
+		w.Header().Set("Content-Type", record.ContentType)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
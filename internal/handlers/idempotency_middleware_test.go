@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"cloudblast-backend/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore is an IdempotencyStore kept in memory, TTLs are ignored
+type memoryStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{values: map[string][]byte{}}
+}
+
+func (s *memoryStore) SetValueIfNotExists(key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		return false, nil
+	}
+	s.values[key] = value
+	return true, nil
+}
+
+func (s *memoryStore) SetValue(key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *memoryStore) GetValue(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key], nil
+}
+
+func (s *memoryStore) DeleteKeys(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.values, key)
+	}
+	return nil
+}
+
+func postWithKey(handler http.Handler, key string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/store/Purchase", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(idempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyMiddlewareReplaysSuccessfulResponses(t *testing.T) {
+	calls := 0
+	handler := IdempotencyMiddleware(newMemoryStore())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"order":1}`))
+	}))
+
+	first := postWithKey(handler, "key-1", `{"item":"gems"}`)
+	retry := postWithKey(handler, "key-1", `{"item":"gems"}`)
+
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %q, want %d %q", retry.Code, retry.Body.String(), http.StatusCreated, first.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry is not marked as replayed")
+	}
+	if retry.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("retry Content-Type = %q, want application/json", retry.Header().Get("Content-Type"))
+	}
+
+	// Requests without a key always run
+	postWithKey(handler, "", `{"item":"gems"}`)
+	postWithKey(handler, "", `{"item":"gems"}`)
+	if calls != 3 {
+		t.Errorf("handler ran %d times, want 3", calls)
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsDuplicatesInProgress(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := IdempotencyMiddleware(newMemoryStore())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.Write([]byte("done"))
+	}))
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- postWithKey(handler, "key-1", "{}")
+	}()
+	<-entered
+
+	if duplicate := postWithKey(handler, "key-1", "{}"); duplicate.Code != http.StatusConflict {
+		t.Errorf("duplicate in progress = %d, want %d", duplicate.Code, http.StatusConflict)
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusOK || first.Body.String() != "done" {
+		t.Errorf("first = %d %q, want %d %q", first.Code, first.Body.String(), http.StatusOK, "done")
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsKeysReusedForAnotherRequest(t *testing.T) {
+	handler := IdempotencyMiddleware(newMemoryStore())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	token, err := auth.CreateToken("alice", "session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/store/Purchase", strings.NewReader(body))
+		r.Header.Set(idempotencyKeyHeader, "key-1")
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if first := post(`{"item":"gems"}`); first.Code != http.StatusOK {
+		t.Fatalf("first = %d, want %d", first.Code, http.StatusOK)
+	}
+	if reused := post(`{"item":"coins"}`); reused.Code != http.StatusUnprocessableEntity {
+		t.Errorf("key reused for another body = %d, want %d", reused.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestIdempotencyMiddlewareDoesNotStoreFailures(t *testing.T) {
+	store := newMemoryStore()
+	status := http.StatusServiceUnavailable
+	calls := 0
+	handler := IdempotencyMiddleware(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+
+	if failed := postWithKey(handler, "key-1", "{}"); failed.Code != http.StatusServiceUnavailable {
+		t.Fatalf("failed = %d, want %d", failed.Code, http.StatusServiceUnavailable)
+	}
+	if len(store.values) != 0 {
+		t.Errorf("store holds %d records after a failure, want 0", len(store.values))
+	}
+
+	// The retry runs the request again and its success is the one replayed
+	status = http.StatusOK
+	postWithKey(handler, "key-1", "{}")
+	if replay := postWithKey(handler, "key-1", "{}"); replay.Code != http.StatusOK || replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay = %d, replayed %q, want %d, replayed", replay.Code, replay.Header().Get("Idempotent-Replayed"), http.StatusOK)
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"cloudblast-backend/internal/auth"
+	"cloudblast-backend/internal/services"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Time allowed to write a message to the client
+	liveWriteWait = 10 * time.Second
+	// Time allowed to read the next pong from the client
+	livePongWait = 60 * time.Second
+	// Pings are sent more often than pongs are expected
+	livePingPeriod = (livePongWait * 9) / 10
+)
+
+// Mobile clients do not send an Origin header, so every origin is accepted
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// Handler for the /api/tournament/LiveLeaderboard WebSocket route
+// Pushes the user's group standings and tournament lifecycle events as they happen
+func HandleLiveLeaderboardRoute(live *services.LiveService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, err := auth.UsernameFromRequest(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		subscriber, snapshot, err := live.Subscribe(username)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer live.Unsubscribe(subscriber)
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("Failed to upgrade to WebSocket: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		// Read from the client only to notice pongs and a closed connection
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			conn.SetReadLimit(512)
+			conn.SetReadDeadline(time.Now().Add(livePongWait))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(livePongWait))
+			})
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+		if err := conn.WriteMessage(websocket.TextMessage, snapshot); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(livePingPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case message, ok := <-subscriber.Messages:
+				conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+				if !ok {
+					conn.WriteMessage(websocket.CloseMessage, []byte{})
+					return
+				}
+				if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+					return
+				}
+			case <-ticker.C:
+				conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}
+}
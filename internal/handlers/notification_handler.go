@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"cloudblast-backend/internal/auth"
+	"cloudblast-backend/internal/models"
+	"cloudblast-backend/internal/services"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Interval of the keep-alive comments that stop proxies from closing idle streams
+const notificationKeepAlivePeriod = 30 * time.Second
+
+// Handler for the /api/user/NotificationStream Server-Sent Events route
+// Streams the lifecycle events of the user's tournament and the user's personal events
+// Clients resume after a reconnect with the Last-Event-ID header or the "last_event_id" query parameter
+func HandleNotificationStreamRoute(notificationService *services.NotificationService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, err := auth.UsernameFromRequest(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		// Subscribe before replaying the history so that nothing is missed in between
+		subscriber, err := notificationService.Subscribe(username)
+		if err != nil {
+			http.Error(w, "Failed to subscribe to notifications", http.StatusInternalServerError)
+			return
+		}
+		defer notificationService.Unsubscribe(subscriber)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get("last_event_id")
+		}
+
+		// Replay what the client missed while it was disconnected
+		if lastEventID != "" {
+			missed, complete, err := notificationService.GetNotificationsSince(subscriber, lastEventID)
+			if err != nil || !complete {
+				// The client has to reload its state, the history does not go back far enough
+				writeNotification(w, models.Notification{Type: "resync", Time: time.Now().UTC()})
+			}
+			for _, notification := range missed {
+				writeNotification(w, notification)
+				lastEventID = notification.ID
+			}
+			flusher.Flush()
+		}
+
+		ticker := time.NewTicker(notificationKeepAlivePeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case notification, ok := <-subscriber.Notifications:
+				if !ok {
+					return
+				}
+				// Skip notifications that were already replayed from the history
+				if lastEventID != "" && services.CompareNotificationIDs(notification.ID, lastEventID) <= 0 {
+					continue
+				}
+				writeNotification(w, notification)
+				flusher.Flush()
+			case <-ticker.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// Write a notification as a Server-Sent Event
+func writeNotification(w http.ResponseWriter, notification models.Notification) {
+	notificationJSON, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("Failed to marshal notification: %v", err)
+		return
+	}
+
+	if notification.ID != "" {
+		fmt.Fprintf(w, "id: %s\n", notification.ID)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", notification.Type, notificationJSON)
+}
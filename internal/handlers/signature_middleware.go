@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"cloudblast-backend/config"
+	"cloudblast-backend/internal/auth"
+	"cloudblast-backend/internal/models"
+	"crypto/hmac"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Headers of a signed request
+const (
+	signatureHeader           = "X-Signature"
+	signatureKeyVersionHeader = "X-Signature-Key-Version"
+	signatureTimestampHeader  = "X-Signature-Timestamp"
+	signatureNonceHeader      = "X-Signature-Nonce"
+)
+
+// Shortest and longest nonce accepted
+const (
+	minSignatureNonceLength = 16
+	maxSignatureNonceLength = 128
+)
+
+// NonceStore remembers the nonces of signed requests, it is Redis in production
+type NonceStore interface {
+	SetValueIfNotExists(key string, value []byte, ttl time.Duration) (bool, error)
+}
+
+// Only let game results through that are signed with the session's signing key
+// The client signs the canonical request (see auth.CanonicalRequest) with the key issued at login and sends the
+// hex signature, the key version, the Unix timestamp in seconds and a unique nonce in the X-Signature headers.
+// Requests outside the allowed clock skew and reused nonces are rejected, and every rejection is logged as a security event.
+// It must be wrapped by auth.AuthMiddleware
+func SignatureMiddleware(ch *amqp.Channel, nonces NonceStore, next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return signatureMiddleware(nonces, func(event models.SecurityEvent) {
+		PublishToRabbitMQ(ch, "antiCheatQueue", "RecordSecurityEvent", event, "", "")
+	}, next)
+}
+
+// Check request signatures, passing every rejection to recordSecurityEvent
+func signatureMiddleware(nonces NonceStore, recordSecurityEvent func(models.SecurityEvent), next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := auth.UsernameFromContext(r)
+		keyVersion := r.Header.Get(signatureKeyVersionHeader)
+		timestamp := r.Header.Get(signatureTimestampHeader)
+		nonce := r.Header.Get(signatureNonceHeader)
+		signature := r.Header.Get(signatureHeader)
+
+		reject := func(eventType string, message string) {
+			recordSecurityEvent(models.SecurityEvent{
+				Username:   username,
+				Type:       eventType,
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				RemoteAddr: r.RemoteAddr,
+				KeyVersion: keyVersion,
+				Timestamp:  timestamp,
+				Nonce:      nonce,
+				CreatedAt:  time.Now().UTC(),
+			})
+			http.Error(w, message, http.StatusUnauthorized)
+		}
+
+		if signature == "" || keyVersion == "" || timestamp == "" || nonce == "" {
+			reject("missing_signature", "Request is not signed")
+			return
+		}
+		if len(nonce) < minSignatureNonceLength || len(nonce) > maxSignatureNonceLength {
+			reject("missing_signature", "Invalid signature nonce")
+			return
+		}
+
+		// Tokens issued before sessions had signing keys have to log in again
+		sessionID := auth.SessionIDFromContext(r)
+		if sessionID == "" {
+			reject("missing_signature", "Session has no signing key, log in again")
+			return
+		}
+
+		version, err := strconv.Atoi(keyVersion)
+		if err != nil {
+			reject("unknown_key_version", "Unknown signing key version")
+			return
+		}
+		key, err := auth.SessionSigningKey(version, username, sessionID)
+		if err != nil {
+			reject("unknown_key_version", "Unknown signing key version")
+			return
+		}
+
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			reject("stale_timestamp", "Invalid signature timestamp")
+			return
+		}
+		skew := time.Since(time.Unix(seconds, 0))
+		if skew > config.SignatureMaxClockSkew || skew < -config.SignatureMaxClockSkew {
+			reject("stale_timestamp", "Signature timestamp is outside the allowed window")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		expected := auth.SignRequest(key, auth.CanonicalRequest(r.Method, r.URL.Path, timestamp, nonce, body))
+		if !hmac.Equal([]byte(expected), []byte(signature)) {
+			reject("bad_signature", "Invalid signature")
+			return
+		}
+
+		// Nonces are remembered for as long as their timestamp is accepted
+		first, err := nonces.SetValueIfNotExists("nonce:"+username+":"+nonce, []byte("1"), 2*config.SignatureMaxClockSkew)
+		if err != nil {
+			log.Printf("Failed to check signature nonce: %v", err)
+			http.Error(w, "Failed to check signature", http.StatusInternalServerError)
+			return
+		}
+		if !first {
+			reject("replayed_nonce", "Signature nonce was already used")
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bytes"
+	"cloudblast-backend/config"
+	"cloudblast-backend/internal/auth"
+	"cloudblast-backend/internal/models"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const testSessionID = "session-1"
+
+// Build a request to UpdateScore signed by alice's session at the given time
+func signedRequest(t *testing.T, body string, at time.Time, nonce string) *http.Request {
+	t.Helper()
+
+	version := config.CurrentSigningKeyVersion()
+	key, err := auth.SessionSigningKey(version, "alice", testSessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/tournament/UpdateScore", bytes.NewReader([]byte(body)))
+	r.Header.Set(signatureKeyVersionHeader, strconv.Itoa(version))
+	r.Header.Set(signatureTimestampHeader, timestamp)
+	r.Header.Set(signatureNonceHeader, nonce)
+	r.Header.Set(signatureHeader, auth.SignRequest(key, auth.CanonicalRequest(r.Method, r.URL.Path, timestamp, nonce, []byte(body))))
+
+	// As if the request went through auth.AuthMiddleware
+	claims := jwt.MapClaims{"username": "alice", "sid": testSessionID}
+	return r.WithContext(context.WithValue(r.Context(), "user", claims))
+}
+
+func TestSignatureMiddleware(t *testing.T) {
+	now := time.Now()
+	skew := config.SignatureMaxClockSkew
+
+	tests := []struct {
+		name  string
+		build func(t *testing.T) *http.Request
+		event string
+	}{
+		{
+			name: "valid signature",
+			build: func(t *testing.T) *http.Request {
+				return signedRequest(t, `{"score":10}`, now, "nonce-valid-000001")
+			},
+		},
+		{
+			name: "timestamp at the edge of the allowed skew",
+			build: func(t *testing.T) *http.Request {
+				return signedRequest(t, `{"score":10}`, now.Add(-skew+time.Minute), "nonce-edge-0000001")
+			},
+		},
+		{
+			name: "timestamp too far in the past",
+			build: func(t *testing.T) *http.Request {
+				return signedRequest(t, `{"score":10}`, now.Add(-skew-time.Minute), "nonce-past-0000001")
+			},
+			event: "stale_timestamp",
+		},
+		{
+			name: "timestamp too far in the future",
+			build: func(t *testing.T) *http.Request {
+				return signedRequest(t, `{"score":10}`, now.Add(skew+time.Minute), "nonce-future-00001")
+			},
+			event: "stale_timestamp",
+		},
+		{
+			name: "body changed after signing",
+			build: func(t *testing.T) *http.Request {
+				r := signedRequest(t, `{"score":10}`, now, "nonce-body-0000001")
+				tampered := signedRequest(t, `{"score":9999}`, now, "nonce-body-0000001")
+				tampered.Header.Set(signatureHeader, r.Header.Get(signatureHeader))
+				return tampered
+			},
+			event: "bad_signature",
+		},
+		{
+			name: "unknown key version",
+			build: func(t *testing.T) *http.Request {
+				r := signedRequest(t, `{"score":10}`, now, "nonce-version-0001")
+				r.Header.Set(signatureKeyVersionHeader, "999")
+				return r
+			},
+			event: "unknown_key_version",
+		},
+		{
+			name: "nonce too short",
+			build: func(t *testing.T) *http.Request {
+				return signedRequest(t, `{"score":10}`, now, "short")
+			},
+			event: "missing_signature",
+		},
+		{
+			name: "unsigned",
+			build: func(t *testing.T) *http.Request {
+				r := signedRequest(t, `{"score":10}`, now, "nonce-unsigned-001")
+				r.Header.Del(signatureHeader)
+				return r
+			},
+			event: "missing_signature",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var events []models.SecurityEvent
+			var body []byte
+			handler := signatureMiddleware(newMemoryStore(), func(event models.SecurityEvent) {
+				events = append(events, event)
+			}, func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+			})
+
+			w := httptest.NewRecorder()
+			handler(w, test.build(t))
+
+			if test.event == "" {
+				if w.Code != http.StatusOK || len(events) != 0 {
+					t.Fatalf("status = %d with %d security events, want %d with none", w.Code, len(events), http.StatusOK)
+				}
+				// The handler still gets the body the middleware read
+				if string(body) != `{"score":10}` {
+					t.Errorf("handler read body %q, want %q", body, `{"score":10}`)
+				}
+				return
+			}
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+			if len(events) != 1 || events[0].Type != test.event || events[0].Username != "alice" {
+				t.Errorf("security events = %+v, want one %q event for alice", events, test.event)
+			}
+		})
+	}
+}
+
+func TestSignatureMiddlewareRejectsReusedNonces(t *testing.T) {
+	nonces := newMemoryStore()
+	var events []string
+	calls := 0
+	handler := signatureMiddleware(nonces, func(event models.SecurityEvent) {
+		events = append(events, event.Type)
+	}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	})
+
+	now := time.Now()
+	first := httptest.NewRecorder()
+	handler(first, signedRequest(t, `{"score":10}`, now, "nonce-replayed-001"))
+	replay := httptest.NewRecorder()
+	handler(replay, signedRequest(t, `{"score":10}`, now, "nonce-replayed-001"))
+	other := httptest.NewRecorder()
+	handler(other, signedRequest(t, `{"score":10}`, now, "nonce-replayed-002"))
+
+	if first.Code != http.StatusOK || replay.Code != http.StatusUnauthorized || other.Code != http.StatusOK {
+		t.Errorf("statuses = %d, %d, %d, want %d, %d, %d", first.Code, replay.Code, other.Code, http.StatusOK, http.StatusUnauthorized, http.StatusOK)
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+	if len(events) != 1 || events[0] != "replayed_nonce" {
+		t.Errorf("security events = %v, want [replayed_nonce]", events)
+	}
+
+	// Nonces are per user
+	if _, ok := nonces.values["nonce:alice:nonce-replayed-001"]; !ok {
+		t.Error("nonce is not stored under the user")
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// Kinds of achievements
+const (
+	// Completed once the amounts of its event add up to the threshold
+	AchievementKindCounter = "counter"
+	// Completed once its event happens on the threshold number of consecutive days
+	AchievementKindStreak = "streak"
+	// Completed the first time its event happens with at least the minimum amount
+	AchievementKindOnce = "once"
+)
+
+// Events achievements are counted from
+const (
+	AchievementEventLevelUp           = "level_up"
+	AchievementEventTournamentEntered = "tournament_entered"
+	AchievementEventPodium            = "podium"
+	AchievementEventTournamentWon     = "tournament_won"
+	AchievementEventCoinsEarned       = "coins_earned"
+	AchievementEventDailyCheckIn      = "daily_check_in"
+)
+
+// AchievementsCatalog is a version of the achievements catalog, loaded from a file
+type AchievementsCatalog struct {
+	Version      int                     `json:"version"`
+	Achievements []AchievementDefinition `json:"achievements"`
+}
+
+// AchievementDefinition describes what completes an achievement and what claiming it pays
+type AchievementDefinition struct {
+	AchievementID string `json:"achievement_id"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	Kind          string `json:"kind"`
+	Event         string `json:"event"`
+	// Amount a counter adds up to, or number of days of a streak
+	Threshold int `json:"threshold,omitempty"`
+	// Smallest amount of the event that completes a one-time achievement
+	MinAmount      int    `json:"min_amount,omitempty"`
+	RewardCurrency string `json:"reward_currency"`
+	RewardAmount   int    `json:"reward_amount"`
+}
+
+// AchievementProgress is a user's progress towards an achievement
+type AchievementProgress struct {
+	Username      string `json:"username"`
+	AchievementID string `json:"achievement_id"`
+	// Counted amount, or length of the current streak
+	Progress int `json:"progress"`
+	// Last day, in UTC, a streak was extended on
+	StreakDay   string     `json:"streak_day,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ClaimedAt   *time.Time `json:"claimed_at,omitempty"`
+	// Incremented on every write, so concurrent evaluations of the same user do not overwrite each other
+	Version int `json:"-" dynamodbav:"version"`
+	// Latest events counted, so an event evaluated again is not counted twice
+	RecentEventIDs []string `json:"-" dynamodbav:"recent_event_ids,omitempty"`
+}
+
+// Get the settings of an achievement
+// Returns false if the catalog has no such achievement
+func (catalog AchievementsCatalog) Achievement(achievementID string) (AchievementDefinition, bool) {
+	for _, achievement := range catalog.Achievements {
+		if achievement.AchievementID == achievementID {
+			return achievement, true
+		}
+	}
+	return AchievementDefinition{}, false
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// States of a challenge
+const (
+	// Waiting for the opponent to accept
+	ChallengeStatusPending = "pending"
+	// Accepted, both players' points count until the challenge ends
+	ChallengeStatusActive = "active"
+	// The opponent declined, the challenger's stake was refunded
+	ChallengeStatusDeclined = "declined"
+	// The challenger withdrew before the opponent accepted, or a player's account was erased
+	ChallengeStatusCancelled = "cancelled"
+	// The opponent did not accept in time, the challenger's stake was refunded
+	ChallengeStatusExpired = "expired"
+	// Ended and paid out, to the winner or back to both players on a tie
+	ChallengeStatusSettled = "settled"
+)
+
+// Challenge is a timed duel between two players, who each put a coin stake in escrow
+// The player scoring more points from level results while it runs wins both stakes
+type Challenge struct {
+	ChallengeID string `json:"challenge_id"`
+	Challenger  string `json:"challenger"`
+	Opponent    string `json:"opponent"`
+	// The opponent was picked at random among players of a similar level
+	Random          bool   `json:"random"`
+	Stake           int    `json:"stake"`
+	Status          string `json:"status"`
+	ChallengerScore int    `json:"challenger_score"`
+	OpponentScore   int    `json:"opponent_score"`
+	// Empty on a tie and for challenges that never started
+	Winner    string    `json:"winner,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// The opponent must accept before this time
+	AcceptBy  time.Time  `json:"accept_by"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	// Points count until this time
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+}
+
+// Check whether a challenge is still waiting for its opponent or running
+func (challenge *Challenge) IsOpen() bool {
+	return challenge.Status == ChallengeStatusPending || challenge.Status == ChallengeStatusActive
+}
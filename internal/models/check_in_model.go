@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// DailyCheckIn is a player's daily check-in streak
+type DailyCheckIn struct {
+	Username string `json:"username"`
+	// Days checked in in a row, missed days covered by the streak freeze do not break it
+	Streak        int `json:"streak"`
+	LongestStreak int `json:"longest_streak"`
+	// Missed days of the current streak covered by the streak freeze
+	FreezesUsed   int `json:"freezes_used"`
+	TotalCheckIns int `json:"total_check_ins"`
+	// Day of the last check-in in the player's timezone at the time
+	LastDay       string    `json:"last_day"`
+	LastCheckInAt time.Time `json:"last_check_in_at"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Roles of clan members, from highest to lowest
+const (
+	// Runs the clan, there is always exactly one
+	ClanRoleLeader = "leader"
+	// Invites players, answers join requests and kicks members
+	ClanRoleOfficer = "officer"
+	ClanRoleMember  = "member"
+)
+
+// Ways players can join a clan
+const (
+	// Players are invited, or ask to join and wait for an officer to accept them
+	ClanJoinPolicyRequest = "request"
+	// Players can only join when invited
+	ClanJoinPolicyInviteOnly = "invite_only"
+)
+
+// Kinds of pending clan invitations
+const (
+	// An officer invited the player, who can accept it by joining
+	ClanInvitationInvite = "invite"
+	// The player asked to join, an officer can accept it
+	ClanInvitationRequest = "request"
+)
+
+// Clan is a team of players whose tournament scores add up to a team total
+type Clan struct {
+	ClanID      string    `json:"clan_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	JoinPolicy  string    `json:"join_policy"`
+	Leader      string    `json:"leader"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ClanMember is a player in a clan
+type ClanMember struct {
+	ClanID   string    `json:"clan_id"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// ClanInvitation is an invite or join request waiting for an answer
+type ClanInvitation struct {
+	ClanID    string    `json:"clan_id"`
+	Username  string    `json:"username"`
+	Kind      string    `json:"kind"`
+	InvitedBy string    `json:"invited_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ClanActivity is an entry of a clan's activity feed
+type ClanActivity struct {
+	ClanID     string                 `json:"clan_id"`
+	ActivityID string                 `json:"activity_id"`
+	Type       string                 `json:"type"`
+	Actor      string                 `json:"actor,omitempty"`
+	Target     string                 `json:"target,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	// Unix time DynamoDB deletes the entry at
+	ExpiresAt int64 `json:"-" dynamodbav:"expires_at"`
+}
+
+// TeamStanding is a clan's place in a team tournament
+type TeamStanding struct {
+	ClanID string `json:"clan_id"`
+	Name   string `json:"name"`
+	Score  int    `json:"score"`
+	Rank   int    `json:"rank"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// Effects a live event can have
+const (
+	// Multiplies the coins earned for levels
+	EventEffectLevelCoins = "level_coins"
+	// Multiplies the tournament points earned for levels
+	EventEffectTournamentPoints = "tournament_points"
+)
+
+// Audiences a live event can target
+const (
+	EventAudienceEveryone   = "everyone"
+	EventAudienceCountry    = "country"
+	EventAudienceLevelRange = "level_range"
+)
+
+// EventsCatalog is a version of the live events catalog, loaded from a file
+type EventsCatalog struct {
+	Version int     `json:"version"`
+	Events  []Event `json:"events"`
+}
+
+// Event is a live event, it changes the rewards of the players it targets while it runs
+type Event struct {
+	EventID     string        `json:"event_id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	StartsAt    time.Time     `json:"starts_at"`
+	EndsAt      time.Time     `json:"ends_at"`
+	Audience    EventAudience `json:"audience"`
+	Effects     []EventEffect `json:"effects"`
+	// Rewards mailed once to every targeted player while the event runs
+	Gift []MailReward `json:"gift,omitempty"`
+}
+
+// EventAudience is the players a live event or a mail targets
+type EventAudience struct {
+	Type string `json:"type"`
+	// ISO 3166-1 alpha-2 codes, for country audiences
+	Countries []string `json:"countries,omitempty"`
+	// Progress levels, for level range audiences. No maximum when it is 0
+	MinLevel int `json:"min_level,omitempty"`
+	MaxLevel int `json:"max_level,omitempty"`
+}
+
+// EventEffect multiplies one kind of reward
+type EventEffect struct {
+	Type       string  `json:"type"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// Check whether the event is running at the given time
+func (event Event) IsActive(now time.Time) bool {
+	return !now.Before(event.StartsAt) && now.Before(event.EndsAt)
+}
+
+// Check whether the event targets a user
+func (event Event) Targets(user *User) bool {
+	return event.Audience.Includes(user)
+}
+
+// Check whether a user is part of the audience
+func (audience EventAudience) Includes(user *User) bool {
+	switch audience.Type {
+	case EventAudienceEveryone:
+		return true
+	case EventAudienceCountry:
+		for _, country := range audience.Countries {
+			if country == user.Country {
+				return true
+			}
+		}
+		return false
+	case EventAudienceLevelRange:
+		return user.Progress_Level >= audience.MinLevel &&
+			(audience.MaxLevel == 0 || user.Progress_Level <= audience.MaxLevel)
+	}
+	return false
+}
+
+// Get the multiplier the event applies to a kind of reward, 1 if it does not change it
+func (event Event) Multiplier(effectType string) float64 {
+	for _, effect := range event.Effects {
+		if effect.Type == effectType {
+			return effect.Multiplier
+		}
+	}
+	return 1
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// States of a friendship, as seen by the user it is stored under
+const (
+	FriendStatusFriends = "friends"
+	// The user sent a friend request that was not answered yet
+	FriendStatusPendingOut = "pending_out"
+	// The user received a friend request that was not answered yet
+	FriendStatusPendingIn = "pending_in"
+	// The user blocked the friend, who cannot send them friend requests
+	FriendStatusBlocked = "blocked"
+)
+
+// Friendship is one side of a relation between two users
+// Both users keep their own side, so that each can list their friends with one query.
+// Blocks are only kept on the side of the user who blocked
+type Friendship struct {
+	Username  string    `json:"username"`
+	Friend    string    `json:"friend"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FriendEntry is a friend shown in a friends leaderboard
+type FriendEntry struct {
+	Username      string `json:"username"`
+	DisplayName   string `json:"display_name"`
+	AvatarID      string `json:"avatar_id"`
+	FrameID       string `json:"frame_id"`
+	Country       string `json:"country"`
+	ProgressLevel int    `json:"progress_level"`
+	Rank          int    `json:"rank"`
+}
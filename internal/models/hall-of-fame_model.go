@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// HallOfFameEntry represents the winner of a finished tournament
+type HallOfFameEntry struct {
+	TournamentID string    `json:"tournament_id"`
+	Username     string    `json:"username"`
+	Country      string    `json:"country"`
+	Score        int       `json:"score"`
+	Reward       int       `json:"reward"`
+	Date         time.Time `json:"date"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// LevelsCatalog is a version of the levels catalog, loaded from a file
+type LevelsCatalog struct {
+	Version int `json:"version"`
+	// One extra tournament point is earned for every this many moves left at the end of a level
+	MovesPerBonusPoint int `json:"moves_per_bonus_point"`
+	// Settings of the levels that are not listed
+	Default LevelDefinition   `json:"default"`
+	Levels  []LevelDefinition `json:"levels"`
+}
+
+// LevelDefinition holds the limits and rewards of a level
+type LevelDefinition struct {
+	LevelID       int `json:"level_id"`
+	MoveLimit     int `json:"move_limit"`
+	CoinsPerStar  int `json:"coins_per_star"`
+	PointsPerStar int `json:"points_per_star"`
+}
+
+// LevelCompletion is a recorded result of a level
+type LevelCompletion struct {
+	Username     string `json:"username"`
+	CompletionID string `json:"completion_id"`
+	LevelID      int    `json:"level_id"`
+	MovesUsed    int    `json:"moves_used"`
+	Stars        int    `json:"stars"`
+	DurationMs   int64  `json:"duration_ms"`
+	// Whether the completion unlocked the next level
+	FirstCompletion bool      `json:"first_completion"`
+	Coins           int       `json:"coins"`
+	Points          int       `json:"points"`
+	TournamentID    string    `json:"tournament_id,omitempty"`
+	CatalogVersion  int       `json:"catalog_version"`
+	CompletedAt     time.Time `json:"completed_at"`
+	// Live events that multiplied the coins and the points
+	CoinsEventID  string `json:"coins_event_id,omitempty"`
+	PointsEventID string `json:"points_event_id,omitempty"`
+}
+
+// LevelBest is a user's best result of a level
+type LevelBest struct {
+	Username    string    `json:"username"`
+	LevelID     int       `json:"level_id"`
+	MovesUsed   int       `json:"moves_used"`
+	Stars       int       `json:"stars"`
+	DurationMs  int64     `json:"duration_ms"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// Get the settings of a level, levels that are not listed use the default settings
+func (catalog LevelsCatalog) Level(levelID int) LevelDefinition {
+	for _, level := range catalog.Levels {
+		if level.LevelID == levelID {
+			return level
+		}
+	}
+	level := catalog.Default
+	level.LevelID = levelID
+	return level
+}
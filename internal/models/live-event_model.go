@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// LiveEvent is a tournament event pushed to connected clients
+// Group events carry a GroupID, tournament-wide events leave it at 0
+type LiveEvent struct {
+	Type         string      `json:"type"`
+	TournamentID string      `json:"tournament_id"`
+	GroupID      int         `json:"group_id"`
+	Username     string      `json:"username"`
+	Data         interface{} `json:"data"`
+	Time         time.Time   `json:"time"`
+}
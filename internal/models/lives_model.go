@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Lives are the attempts a player has left, stored only once the first one is used
+// They are counted when read, so nothing has to run to refill them
+type Lives struct {
+	Username string `json:"username"`
+	// Lives held when they were last counted, bought lives can go above the cap
+	Lives int `json:"lives"`
+	// Time the next life is refilled from, only meaningful below the cap
+	RefillFrom time.Time `json:"refill_from"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Count a player's lives at the given time, refilling one per interval up to the cap
+// Players with no stored lives have the full cap
+func LivesAt(stored *Lives, username string, now time.Time, max int, interval time.Duration) Lives {
+	if stored == nil {
+		return Lives{Username: username, Lives: max, RefillFrom: now}
+	}
+
+	lives := *stored
+	if lives.Lives >= max {
+		lives.RefillFrom = now
+		return lives
+	}
+
+	refilled := int(now.Sub(lives.RefillFrom) / interval)
+	if refilled > 0 {
+		lives.Lives += refilled
+		lives.RefillFrom = lives.RefillFrom.Add(time.Duration(refilled) * interval)
+	}
+	if lives.Lives >= max {
+		lives.Lives = max
+		lives.RefillFrom = now
+	}
+	return lives
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLivesAtRefillsOverTime(t *testing.T) {
+	const max = 5
+	const interval = 30 * time.Minute
+	start := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	// A player who has not played yet has the full cap
+	lives := LivesAt(nil, "alice", start, max, interval)
+	if lives.Lives != max || lives.Username != "alice" {
+		t.Fatalf("new player has %d lives as %q, want %d as alice", lives.Lives, lives.Username, max)
+	}
+
+	// Three lives used at once, the refill starts with the first
+	lives.Lives -= 3
+	lives.RefillFrom = start
+
+	steps := []struct {
+		after      time.Duration
+		lives      int
+		refillFrom time.Time
+	}{
+		{29 * time.Minute, 2, start},
+		{30 * time.Minute, 3, start.Add(30 * time.Minute)},
+		// Time past a refill carries over to the next one
+		{75 * time.Minute, 4, start.Add(60 * time.Minute)},
+		{89 * time.Minute, 4, start.Add(60 * time.Minute)},
+		// Refilled to the cap, time at the cap does not count towards the next life
+		{90 * time.Minute, max, start.Add(90 * time.Minute)},
+		{10 * time.Hour, max, start.Add(10 * time.Hour)},
+	}
+	for _, step := range steps {
+		now := start.Add(step.after)
+		got := LivesAt(&lives, "alice", now, max, interval)
+		if got.Lives != step.lives || !got.RefillFrom.Equal(step.refillFrom) {
+			t.Errorf("after %v: %d lives refilling from %v, want %d from %v", step.after, got.Lives, got.RefillFrom, step.lives, step.refillFrom)
+		}
+	}
+
+	// Counting does not change the stored lives
+	if lives.Lives != 2 || !lives.RefillFrom.Equal(start) {
+		t.Errorf("stored lives changed to %d from %v", lives.Lives, lives.RefillFrom)
+	}
+}
+
+func TestLivesAtKeepsBoughtLivesAboveTheCap(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	stored := &Lives{Username: "alice", Lives: 8, RefillFrom: now.Add(-5 * time.Hour)}
+
+	got := LivesAt(stored, "alice", now, 5, 30*time.Minute)
+	if got.Lives != 8 {
+		t.Errorf("lives = %d, want the 8 bought", got.Lives)
+	}
+	if !got.RefillFrom.Equal(now) {
+		t.Errorf("refill from %v, want %v", got.RefillFrom, now)
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Subsystems mail is sent by
+const (
+	MailSourceAdmin       = "admin"
+	MailSourceTournament  = "tournament"
+	MailSourceEvent       = "event"
+	MailSourceAchievement = "achievement"
+)
+
+// Mail is a message in a player's mailbox, its rewards are paid when it is claimed
+type Mail struct {
+	Username string `json:"username"`
+	// Unique per player, sending the same mail ID again has no effect
+	MailID  string       `json:"mail_id"`
+	Source  string       `json:"source"`
+	Title   string       `json:"title"`
+	Body    string       `json:"body,omitempty"`
+	Rewards []MailReward `json:"rewards,omitempty"`
+	// Member of the support staff who sent the mail
+	SentBy    string     `json:"sent_by,omitempty"`
+	SentAt    time.Time  `json:"sent_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+	// Unix time DynamoDB deletes the mail at
+	DeleteAt int64 `json:"-" dynamodbav:"delete_at"`
+}
+
+// MailReward is an amount of a currency attached to a mail
+type MailReward struct {
+	Currency string `json:"currency"`
+	Amount   int    `json:"amount"`
+}
+
+// Check whether the mail can no longer be claimed
+func (mail Mail) IsExpired(now time.Time) bool {
+	return !now.Before(mail.ExpiresAt)
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Moderation states of a user
+const (
+	ModerationActive       = "active"
+	ModerationSuspended    = "suspended"
+	ModerationBanned       = "banned"
+	ModerationShadowBanned = "shadow_banned"
+)
+
+// ModerationAction is an entry of the moderation audit trail
+type ModerationAction struct {
+	Username string `json:"username"`
+	// Sorts the actions taken on a user by time
+	ActionID string `json:"action_id"`
+	// "apply" or "lift"
+	Action         string     `json:"action"`
+	Status         string     `json:"status"`
+	PreviousStatus string     `json:"previous_status"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	Reason         string     `json:"reason"`
+	Admin          string     `json:"admin"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Sanction is a sanction that keeps a user from signing in, cached for the authentication middleware
+type Sanction struct {
+	Status         string     `json:"status"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+}
+
+// Get a user's moderation state at a given time, users whose suspension ended are active again
+func (user *User) ModerationStatusAt(now time.Time) string {
+	switch user.Moderation_Status {
+	case "", ModerationActive:
+		return ModerationActive
+	case ModerationSuspended:
+		if user.Suspended_Until == nil || !now.Before(*user.Suspended_Until) {
+			return ModerationActive
+		}
+	}
+	return user.Moderation_Status
+}
+
+// Check whether a user is left out of the leaderboards and the tournament settlements
+func (user *User) IsSanctionedAt(now time.Time) bool {
+	return user.ModerationStatusAt(now) != ModerationActive
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Notification is a tournament lifecycle or personal event streamed to a player
+// Tournament events leave Username empty, personal events are only sent to Username
+type Notification struct {
+	ID           string      `json:"id,omitempty"`
+	Type         string      `json:"type"`
+	TournamentID string      `json:"tournament_id"`
+	Username     string      `json:"username"`
+	Data         interface{} `json:"data"`
+	Time         time.Time   `json:"time"`
+}
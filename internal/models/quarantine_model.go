@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Review states of a quarantine
+const (
+	QuarantinePending   = "pending"
+	QuarantineCleared   = "cleared"
+	QuarantineConfirmed = "confirmed"
+)
+
+// Quarantine holds back a player's scores in a tournament until support staff review them
+// Quarantined scores are only shown to the player and are left out of the tournament settlement
+type Quarantine struct {
+	Username     string `json:"username"`
+	TournamentID string `json:"tournament_id"`
+	GroupID      int    `json:"group_id"`
+	Status       string `json:"status"`
+	Reason       string `json:"reason"`
+	// Points the player scored in the stats window, and the mean and standard deviation of the rest of the group
+	WindowPoints int        `json:"window_points"`
+	GroupMean    float64    `json:"group_mean"`
+	GroupStdDev  float64    `json:"group_std_dev"`
+	FlaggedAt    time.Time  `json:"flagged_at"`
+	ReviewedBy   string     `json:"reviewed_by,omitempty"`
+	ReviewNote   string     `json:"review_note,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// SkillRating is a player's Glicko-2 skill rating, on the familiar scale where new players start at 1500
+type SkillRating struct {
+	Rating float64 `json:"rating"`
+	// How unsure the rating is, it shrinks with every tournament played and grows while the player is away
+	Deviation float64 `json:"deviation"`
+	// How erratic the player's results are
+	Volatility float64 `json:"volatility"`
+	// Number of tournaments the rating was computed from
+	Tournaments int       `json:"tournaments"`
+	RatedAt     time.Time `json:"rated_at"`
+}
+
+// RatingHistoryEntry is a player's rating after one of their tournaments
+type RatingHistoryEntry struct {
+	Username     string    `json:"username"`
+	TournamentID string    `json:"tournament_id"`
+	Rating       float64   `json:"rating"`
+	Deviation    float64   `json:"deviation"`
+	Volatility   float64   `json:"volatility"`
+	Change       float64   `json:"change"`
+	GroupRank    int       `json:"group_rank"`
+	GroupSize    int       `json:"group_size"`
+	RatedAt      time.Time `json:"rated_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Season represents a finished, archived season
+type Season struct {
+	SeasonID  string    `json:"season_id"`
+	Number    int       `json:"number"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Finished  bool      `json:"finished"`
+}
+
+// SeasonStanding represents a user's archived final standing in a season
+type SeasonStanding struct {
+	SeasonID   string `json:"season_id"`
+	Username   string `json:"username"`
+	Levels     int    `json:"levels"`
+	LevelsRank int    `json:"levels_rank"`
+	Coins      int    `json:"coins"`
+	CoinsRank  int    `json:"coins_rank"`
+	Points     int    `json:"points"`
+	PointsRank int    `json:"points_rank"`
+	Reward     int    `json:"reward"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// SecurityEvent is a rejected signed request, kept in the security event log
+type SecurityEvent struct {
+	Username string `json:"username"`
+	// Sorts the events of a user by time
+	EventID string `json:"event_id"`
+	// "missing_signature", "unknown_key_version", "stale_timestamp", "bad_signature" or "replayed_nonce"
+	Type       string    `json:"type"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	RemoteAddr string    `json:"remote_addr"`
+	KeyVersion string    `json:"key_version,omitempty"`
+	Timestamp  string    `json:"timestamp,omitempty"`
+	Nonce      string    `json:"nonce,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
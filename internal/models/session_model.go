@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Session is a login of a user, kept so that players can see where they signed in from
+type Session struct {
+	Username  string `json:"username"`
+	SessionID string `json:"session_id"`
+	// Version of the secret the session's signing key is derived from
+	KeyVersion int       `json:"key_version"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// StoreCatalog is a version of the store catalog, loaded from a file
+type StoreCatalog struct {
+	Version int         `json:"version"`
+	Items   []StoreItem `json:"items"`
+}
+
+// StoreItem is an item sold in the store
+type StoreItem struct {
+	ItemID string `json:"item_id"`
+	Name   string `json:"name"`
+	// "booster", "extra_moves", "frame" or "avatar"
+	Type     string `json:"type"`
+	Price    int    `json:"price"`
+	Currency string `json:"currency"`
+	// Number of units granted by one purchase
+	Quantity int `json:"quantity"`
+	// Total number of purchases allowed, 0 for unlimited
+	Stock int `json:"stock"`
+	// Items that can only be owned once, such as cosmetic frames
+	Unique bool `json:"unique"`
+	// Purchased units expire this many hours after the purchase, 0 if they never expire
+	ExpiresAfterHours int        `json:"expires_after_hours"`
+	AvailableFrom     *time.Time `json:"available_from,omitempty"`
+	AvailableUntil    *time.Time `json:"available_until,omitempty"`
+}
+
+// InventoryItem is a user's holding of a store item
+type InventoryItem struct {
+	Username  string     `json:"username"`
+	ItemID    string     `json:"item_id"`
+	Type      string     `json:"type"`
+	Quantity  int        `json:"quantity"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Check whether an item can be bought at a given time
+func (item StoreItem) IsAvailable(now time.Time) bool {
+	if item.AvailableFrom != nil && now.Before(*item.AvailableFrom) {
+		return false
+	}
+	if item.AvailableUntil != nil && !now.Before(*item.AvailableUntil) {
+		return false
+	}
+	return true
+}
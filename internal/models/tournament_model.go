@@ -9,4 +9,7 @@ type Tournament struct {
 	NumRegisteredUsers    	int       `json:"num_registered_users"`
 	Finished				bool	  `json:"finished"`
 	LatestGroupID			int 	  `json:"latest_group_id"`
+	LeagueGroups			map[string]int `json:"league_groups"`
+	LeagueGroupSizes		map[string]int `json:"league_group_sizes"`
+	EndingSoonNotified		bool	  `json:"ending_soon_notified"`
 }
\ No newline at end of file
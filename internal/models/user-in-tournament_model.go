@@ -8,4 +8,8 @@ type UserInTournament struct {
 	Score        int       `json:"score"`
 	Rank         int       `json:"rank"`
 	Claimed	  	 bool      `json:"claimed"`
+	League       string    `json:"league"`
+	GroupRank    int       `json:"group_rank"`
+	LeagueChange string    `json:"league_change"`
+	Quarantined  bool      `json:"quarantined"`
 }
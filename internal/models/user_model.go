@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type User struct {
 	ID	   				  	string 	`json:"id"`
 	Username 				string 	`json:"username"`
@@ -9,4 +11,27 @@ type User struct {
 	Coins   				int    	`json:"coins"`
 	Latest_Tournament_ID 	string 	`json:"latest_tournament_id"`
 	Latest_Group_ID 		int 	`json:"latest_group_id"`
+	League 					string 	`json:"league"`
+	// Name shown to other players, the username is used when it is empty
+	Display_Name			string	`json:"display_name" dynamodbav:"display_name,omitempty"`
+	// Inventory items shown on the profile
+	Avatar_ID				string	`json:"avatar_id" dynamodbav:"avatar_id,omitempty"`
+	Frame_ID				string	`json:"frame_id" dynamodbav:"frame_id,omitempty"`
+	Country_Changed_At		*time.Time	`json:"-" dynamodbav:"country_changed_at,omitempty"`
+	// IANA timezone daily check-ins are counted in, UTC when it is empty
+	Timezone				string	`json:"timezone" dynamodbav:"timezone,omitempty"`
+	Timezone_Changed_At		*time.Time	`json:"-" dynamodbav:"timezone_changed_at,omitempty"`
+	// Code other players add the user as a friend with, created the first time it is asked for
+	Friend_Code				string	`json:"-" dynamodbav:"friend_code,omitempty"`
+	// Clan the user is a member of
+	Clan_ID					string	`json:"clan_id" dynamodbav:"clan_id,omitempty"`
+	// Skill rating from tournament placements, empty until the first rated tournament
+	Skill_Rating			*SkillRating	`json:"-" dynamodbav:"skill_rating,omitempty"`
+	// Moderation state is stored but never shown in leaderboards, so shadow-banned users cannot tell
+	Moderation_Status		string	`json:"-" dynamodbav:"moderation_status,omitempty"`
+	Suspended_Until			*time.Time	`json:"-" dynamodbav:"suspended_until,omitempty"`
+	// Time the account is erased, set while a deletion request is in its grace period
+	Deletion_Scheduled_At	*time.Time	`json:"-" dynamodbav:"deletion_scheduled_at,omitempty"`
+	// Name the user is replaced with in the results that outlive the account
+	Deletion_Alias			string	`json:"-" dynamodbav:"deletion_alias,omitempty"`
 }
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Types of ledger entries
+const (
+	LedgerLevelReward       = "level_reward"
+	LedgerEntryFee          = "entry_fee"
+	LedgerTournamentPrize   = "tournament_prize"
+	LedgerTeamPrize         = "team_prize"
+	LedgerSeasonReward      = "season_reward"
+	LedgerAdminGrant        = "admin_grant"
+	LedgerRefund            = "refund"
+	LedgerExpiry            = "expiry"
+	LedgerStorePurchase     = "store_purchase"
+	LedgerChallengeStake    = "challenge_stake"
+	LedgerChallengePrize    = "challenge_prize"
+	LedgerAchievementReward = "achievement_reward"
+	LedgerCheckInReward     = "check_in_reward"
+	LedgerLivesPurchase     = "lives_purchase"
+	LedgerMailReward        = "mail_reward"
+)
+
+// System accounts on the other side of player transactions
+const (
+	// Currency paid out by the game as level rewards, prizes and season rewards
+	SystemAccountRewards = "system:rewards"
+	// Currency paid in as tournament entry fees and paid back as refunds
+	SystemAccountFees = "system:fees"
+	// Currency granted by support staff
+	SystemAccountAdmin = "system:admin"
+	// Expired balances
+	SystemAccountExpiry = "system:expiry"
+	// Currency spent in the store
+	SystemAccountStore = "system:store"
+	// Challenge stakes held until the challenge is settled
+	SystemAccountEscrow = "system:escrow"
+)
+
+// LedgerEntry is one side of a wallet transaction
+// Every transaction is recorded twice, once for the player and once for the system account,
+// with opposite amounts so that the ledger always sums to zero
+type LedgerEntry struct {
+	TransactionID string    `json:"transaction_id"`
+	Account       string    `json:"account"`
+	Currency      string    `json:"currency"`
+	Counterparty  string    `json:"counterparty"`
+	Type          string    `json:"type"`
+	Amount        int       `json:"amount"`
+	Balance       int       `json:"balance"`
+	Reference     string    `json:"reference,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	CreatedBy     string    `json:"created_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	// Live event that multiplied the amount
+	EventID string `json:"event_id,omitempty"`
+}
+
+// WalletBalance is a user's balance in a currency
+type WalletBalance struct {
+	Username  string     `json:"username"`
+	Currency  string     `json:"currency"`
+	Balance   int        `json:"balance"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+//ACHIEVEMENT
+// Get a user's progress towards every achievement they made progress on
+func (repo *DynamoDBRepository) GetAchievementProgress(username string) ([]models.AchievementProgress, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("AchievementProgress"),
+		KeyConditionExpression: aws.String("username = :username"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+	}
+
+	progress := make([]models.AchievementProgress, 0)
+	var unmarshalErr error
+	err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var entry models.AchievementProgress
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &entry); unmarshalErr != nil {
+				return false
+			}
+			progress = append(progress, entry)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+
+	return progress, nil
+}
+
+// Store a user's progress towards an achievement, if it was not changed since it was read
+// Returns false if another write changed it first
+func (repo *DynamoDBRepository) SaveAchievementProgress(progress *models.AchievementProgress) (bool, error) {
+	readVersion := progress.Version
+	progress.Version++
+	item, err := dynamodbattribute.MarshalMap(progress)
+	if err != nil {
+		progress.Version = readVersion
+		return false, err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String("AchievementProgress"),
+		Item:      item,
+	}
+	if readVersion == 0 {
+		input.ConditionExpression = aws.String("attribute_not_exists(achievement_id)")
+	} else {
+		input.ConditionExpression = aws.String("#version = :version")
+		input.ExpressionAttributeNames = map[string]*string{
+			"#version": aws.String("version"),
+		}
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":version": {N: aws.String(strconv.Itoa(readVersion))},
+		}
+	}
+
+	_, err = repo.client.PutItem(input)
+	if err != nil {
+		progress.Version = readVersion
+	}
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Mark a completed achievement as claimed
+// Returns false if the achievement is not completed or was already claimed
+func (repo *DynamoDBRepository) MarkAchievementClaimed(username, achievementID string, claimedAt time.Time) (bool, error) {
+	claimed, err := dynamodbattribute.Marshal(claimedAt.UTC())
+	if err != nil {
+		return false, err
+	}
+
+	_, err = repo.client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String("AchievementProgress"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username":       {S: aws.String(username)},
+			"achievement_id": {S: aws.String(achievementID)},
+		},
+		UpdateExpression:    aws.String("SET claimed_at = :claimedAt ADD #version :one"),
+		ConditionExpression: aws.String("attribute_exists(completed_at) AND attribute_not_exists(claimed_at)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#version": aws.String("version"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":claimedAt": claimed,
+			":one":       {N: aws.String("1")},
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete a user's achievement progress
+func (repo *DynamoDBRepository) DeleteAchievementProgress(username string) error {
+	progress, err := repo.GetAchievementProgress(username)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range progress {
+		_, err := repo.client.DeleteItem(&dynamodb.DeleteItemInput{
+			TableName: aws.String("AchievementProgress"),
+			Key: map[string]*dynamodb.AttributeValue{
+				"username":       {S: aws.String(username)},
+				"achievement_id": {S: aws.String(entry.AchievementID)},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Returned when a quarantine that was already reviewed is reviewed again
+var ErrQuarantineReviewed = errors.New("Quarantine is already reviewed")
+
+// Returned when a quarantine does not exist
+var ErrQuarantineNotFound = errors.New("Quarantine not found")
+
+//ANTICHEAT
+// Quarantine a player's scores in a tournament
+// Returns false if the player is already quarantined in the tournament
+func (repo *DynamoDBRepository) CreateQuarantine(quarantine *models.Quarantine) (bool, error) {
+	av, err := dynamodbattribute.MarshalMap(quarantine)
+	if err != nil {
+		return false, err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName:           aws.String("Quarantine"),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(username)"),
+	}
+
+	_, err = repo.client.PutItem(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Get a player's quarantine in a tournament
+// Returns nil if the player is not quarantined in the tournament
+func (repo *DynamoDBRepository) GetQuarantine(username, tournamentID string) (*models.Quarantine, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String("Quarantine"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username":      {S: aws.String(username)},
+			"tournament_id": {S: aws.String(tournamentID)},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	result, err := repo.client.GetItem(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil
+	}
+
+	var quarantine models.Quarantine
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &quarantine); err != nil {
+		return nil, err
+	}
+
+	return &quarantine, nil
+}
+
+// Record the review of a pending quarantine
+// Returns the reviewed quarantine
+func (repo *DynamoDBRepository) ReviewQuarantine(username, tournamentID, status, reviewedBy, note string) (*models.Quarantine, error) {
+	reviewedAt, err := dynamodbattribute.Marshal(time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String("Quarantine"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username":      {S: aws.String(username)},
+			"tournament_id": {S: aws.String(tournamentID)},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":status":      {S: aws.String(status)},
+			":pending":     {S: aws.String(models.QuarantinePending)},
+			":reviewed_by": {S: aws.String(reviewedBy)},
+			":review_note": {S: aws.String(note)},
+			":reviewed_at": reviewedAt,
+		},
+		UpdateExpression:    aws.String("SET #status = :status, reviewed_by = :reviewed_by, review_note = :review_note, reviewed_at = :reviewed_at"),
+		ConditionExpression: aws.String("attribute_exists(username) AND #status = :pending"),
+		ReturnValues:        aws.String("ALL_NEW"),
+	}
+
+	result, err := repo.client.UpdateItem(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		existing, err := repo.GetQuarantine(username, tournamentID)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, ErrQuarantineNotFound
+		}
+		return nil, ErrQuarantineReviewed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var quarantine models.Quarantine
+	if err := dynamodbattribute.UnmarshalMap(result.Attributes, &quarantine); err != nil {
+		return nil, err
+	}
+
+	return &quarantine, nil
+}
+
+// Get the most recently flagged quarantines in a review state
+func (repo *DynamoDBRepository) GetQuarantinesByStatus(status string, limit int64) ([]models.Quarantine, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("Quarantine"),
+		IndexName:              aws.String("status-flagged_at-index"),
+		KeyConditionExpression: aws.String("#status = :status"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":status": {S: aws.String(status)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int64(limit),
+	}
+
+	result, err := repo.client.Query(input)
+	if err != nil {
+		return nil, err
+	}
+
+	quarantines := make([]models.Quarantine, 0, len(result.Items))
+	for _, item := range result.Items {
+		var quarantine models.Quarantine
+		if err := dynamodbattribute.UnmarshalMap(item, &quarantine); err != nil {
+			return nil, err
+		}
+		quarantines = append(quarantines, quarantine)
+	}
+
+	return quarantines, nil
+}
+
+// Mark whether a user's scores in a tournament are held back from the settlement
+func (repo *DynamoDBRepository) UpdateUserInTournamentQuarantined(username, tournamentID string, quarantined bool) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String("UserInTournament"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username":      {S: aws.String(username)},
+			"tournament_id": {S: aws.String(tournamentID)},
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":quarantined": {BOOL: aws.Bool(quarantined)},
+		},
+		UpdateExpression:    aws.String("SET quarantined = :quarantined"),
+		ConditionExpression: aws.String("attribute_exists(username)"),
+	}
+
+	_, err := repo.client.UpdateItem(input)
+	return err
+}
+
+// Add an event to the security event log
+func (repo *DynamoDBRepository) CreateSecurityEvent(event *models.SecurityEvent) error {
+	av, err := dynamodbattribute.MarshalMap(event)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String("SecurityEvent"),
+		Item:      av,
+	}
+
+	_, err = repo.client.PutItem(input)
+	return err
+}
+
+// Get a user's most recent security events, newest first
+func (repo *DynamoDBRepository) GetSecurityEvents(username string, limit int64) ([]models.SecurityEvent, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("SecurityEvent"),
+		KeyConditionExpression: aws.String("username = :username"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int64(limit),
+	}
+
+	result, err := repo.client.Query(input)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.SecurityEvent, 0, len(result.Items))
+	for _, item := range result.Items {
+		var event models.SecurityEvent
+		if err := dynamodbattribute.UnmarshalMap(item, &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
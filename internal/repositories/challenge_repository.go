@@ -0,0 +1,333 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+//CHALLENGE
+// Store a new challenge
+func (repo *DynamoDBRepository) CreateChallenge(challenge *models.Challenge) error {
+	item, err := dynamodbattribute.MarshalMap(challenge)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.client.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String("Challenge"),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(challenge_id)"),
+	})
+	return err
+}
+
+// Get a challenge by ID
+// Returns nil if the challenge does not exist
+func (repo *DynamoDBRepository) GetChallenge(challengeID string) (*models.Challenge, error) {
+	result, err := repo.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("Challenge"),
+		Key:       challengeKey(challengeID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil
+	}
+
+	var challenge models.Challenge
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &challenge); err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
+
+// Get every challenge a user sent or received
+func (repo *DynamoDBRepository) GetUserChallenges(username string) ([]models.Challenge, error) {
+	challenges := make([]models.Challenge, 0)
+	for _, side := range []string{"challenger", "opponent"} {
+		input := &dynamodb.QueryInput{
+			TableName:              aws.String("Challenge"),
+			IndexName:              aws.String(side + "-index"),
+			KeyConditionExpression: aws.String(side + " = :username"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":username": {S: aws.String(username)},
+			},
+		}
+
+		var unmarshalErr error
+		err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+			for _, item := range page.Items {
+				var challenge models.Challenge
+				if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &challenge); unmarshalErr != nil {
+					return false
+				}
+				challenges = append(challenges, challenge)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if unmarshalErr != nil {
+			return nil, unmarshalErr
+		}
+	}
+
+	return challenges, nil
+}
+
+// Start a challenge its opponent accepted
+// Returns false if the challenge is no longer pending or its time to accept has passed
+func (repo *DynamoDBRepository) StartChallenge(challengeID, opponent string, startedAt, endsAt time.Time) (bool, error) {
+	started, err := dynamodbattribute.Marshal(startedAt.UTC())
+	if err != nil {
+		return false, err
+	}
+	ends, err := dynamodbattribute.Marshal(endsAt.UTC())
+	if err != nil {
+		return false, err
+	}
+
+	_, err = repo.client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:           aws.String("Challenge"),
+		Key:                 challengeKey(challengeID),
+		UpdateExpression:    aws.String("SET #status = :active, started_at = :startedAt, ends_at = :endsAt"),
+		ConditionExpression: aws.String("#status = :pending AND opponent = :opponent AND accept_by > :startedAt"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":active":    {S: aws.String(models.ChallengeStatusActive)},
+			":pending":   {S: aws.String(models.ChallengeStatusPending)},
+			":opponent":  {S: aws.String(opponent)},
+			":startedAt": started,
+			":endsAt":    ends,
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Move a challenge out of the given status into a closed one, recording its winner
+// Returns false if the challenge is no longer in the given status
+func (repo *DynamoDBRepository) CloseChallenge(challengeID, fromStatus, toStatus, winner string, closedAt time.Time) (bool, error) {
+	closed, err := dynamodbattribute.Marshal(closedAt.UTC())
+	if err != nil {
+		return false, err
+	}
+
+	update := "SET #status = :to, closed_at = :closedAt"
+	values := map[string]*dynamodb.AttributeValue{
+		":from":     {S: aws.String(fromStatus)},
+		":to":       {S: aws.String(toStatus)},
+		":closedAt": closed,
+	}
+	if winner != "" {
+		update += ", winner = :winner"
+		values[":winner"] = &dynamodb.AttributeValue{S: aws.String(winner)}
+	}
+
+	_, err = repo.client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:           aws.String("Challenge"),
+		Key:                 challengeKey(challengeID),
+		UpdateExpression:    aws.String(update),
+		ConditionExpression: aws.String("#status = :from"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: values,
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Add points to a player's score in a running challenge
+// Returns false if the challenge is not running any more
+func (repo *DynamoDBRepository) AddChallengeScore(challengeID string, challenger bool, points int, at time.Time) (bool, error) {
+	now, err := dynamodbattribute.Marshal(at.UTC())
+	if err != nil {
+		return false, err
+	}
+
+	scoreField := "opponent_score"
+	if challenger {
+		scoreField = "challenger_score"
+	}
+	pointsValue, err := dynamodbattribute.Marshal(points)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = repo.client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:           aws.String("Challenge"),
+		Key:                 challengeKey(challengeID),
+		UpdateExpression:    aws.String("ADD " + scoreField + " :points"),
+		ConditionExpression: aws.String("#status = :active AND ends_at > :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":points": pointsValue,
+			":active": {S: aws.String(models.ChallengeStatusActive)},
+			":now":    now,
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Get the challenges whose time to accept ran out and the running challenges that ended
+func (repo *DynamoDBRepository) GetDueChallenges(now time.Time) ([]models.Challenge, error) {
+	cutoff, err := dynamodbattribute.Marshal(now.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String("Challenge"),
+		FilterExpression: aws.String("(#status = :pending AND accept_by <= :now) OR (#status = :active AND ends_at <= :now)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pending": {S: aws.String(models.ChallengeStatusPending)},
+			":active":  {S: aws.String(models.ChallengeStatusActive)},
+			":now":     cutoff,
+		},
+	}
+
+	challenges := make([]models.Challenge, 0)
+	var unmarshalErr error
+	err = repo.client.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var challenge models.Challenge
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &challenge); unmarshalErr != nil {
+				return false
+			}
+			challenges = append(challenges, challenge)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+
+	return challenges, nil
+}
+
+// Get up to limit users whose progress level is within the given range
+// Sanctioned users and users whose account is being deleted are left out
+func (repo *DynamoDBRepository) FindUsersNearLevel(minLevel, maxLevel, limit int) ([]models.User, error) {
+	minValue, err := dynamodbattribute.Marshal(minLevel)
+	if err != nil {
+		return nil, err
+	}
+	maxValue, err := dynamodbattribute.Marshal(maxLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String("User"),
+		FilterExpression:     aws.String("progress_level BETWEEN :min AND :max AND attribute_not_exists(deletion_scheduled_at)"),
+		ProjectionExpression: aws.String("username, progress_level, moderation_status, suspended_until"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":min": minValue,
+			":max": maxValue,
+		},
+	}
+
+	now := time.Now()
+	users := make([]models.User, 0, limit)
+	var unmarshalErr error
+	err = repo.client.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var user models.User
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &user); unmarshalErr != nil {
+				return false
+			}
+			if user.IsSanctionedAt(now) {
+				continue
+			}
+			users = append(users, user)
+			if len(users) >= limit {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+
+	return users, nil
+}
+
+// Replace a user with their alias in the challenges they sent or received
+func (repo *DynamoDBRepository) AnonymizeChallenges(username, alias string) error {
+	challenges, err := repo.GetUserChallenges(username)
+	if err != nil {
+		return err
+	}
+
+	for _, challenge := range challenges {
+		if challenge.Challenger == username {
+			challenge.Challenger = alias
+		}
+		if challenge.Opponent == username {
+			challenge.Opponent = alias
+		}
+		if challenge.Winner == username {
+			challenge.Winner = alias
+		}
+
+		item, err := dynamodbattribute.MarshalMap(challenge)
+		if err != nil {
+			return err
+		}
+		_, err = repo.client.PutItem(&dynamodb.PutItemInput{
+			TableName: aws.String("Challenge"),
+			Item:      item,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get the key of a challenge
+func challengeKey(challengeID string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"challenge_id": {S: aws.String(challengeID)},
+	}
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+//CHECK-IN
+// Get a user's daily check-in streak
+// Returns nil if the user never checked in
+func (repo *DynamoDBRepository) GetDailyCheckIn(username string) (*models.DailyCheckIn, error) {
+	result, err := repo.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("DailyCheckIn"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil
+	}
+
+	var checkIn models.DailyCheckIn
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &checkIn); err != nil {
+		return nil, err
+	}
+
+	return &checkIn, nil
+}
+
+// Returned while a check-in is saved when another check-in was stored since it was read
+var errCheckInChanged = errors.New("Check-in changed")
+
+// Store a user's check-in and pay its reward in one transaction, if no other check-in was stored since previousCheckInAt
+// previousCheckInAt is nil for the first check-in. Returns false if another check-in was stored first
+func (repo *DynamoDBRepository) SaveDailyCheckIn(checkIn *models.DailyCheckIn, previousCheckInAt *time.Time, reward WalletTransaction) (*models.LedgerEntry, bool, error) {
+	reward.prepare = func() ([]*dynamodb.TransactWriteItem, error) {
+		stored, err := repo.GetDailyCheckIn(checkIn.Username)
+		if err != nil {
+			return nil, err
+		}
+		if (stored == nil) != (previousCheckInAt == nil) || (stored != nil && !stored.LastCheckInAt.Equal(*previousCheckInAt)) {
+			return nil, errCheckInChanged
+		}
+
+		item, err := dynamodbattribute.MarshalMap(checkIn)
+		if err != nil {
+			return nil, err
+		}
+		put := &dynamodb.Put{
+			TableName: aws.String("DailyCheckIn"),
+			Item:      item,
+		}
+		if previousCheckInAt == nil {
+			put.ConditionExpression = aws.String("attribute_not_exists(username)")
+		} else {
+			previous, err := dynamodbattribute.Marshal(previousCheckInAt.UTC())
+			if err != nil {
+				return nil, err
+			}
+			put.ConditionExpression = aws.String("last_check_in_at = :previous")
+			put.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+				":previous": previous,
+			}
+		}
+		return []*dynamodb.TransactWriteItem{{Put: put}}, nil
+	}
+
+	// A reward already posted was paid together with a concurrent check-in
+	entry, posted, err := repo.postWalletTransaction(reward)
+	if err == errCheckInChanged {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return entry, posted, nil
+}
+
+// Delete a user's daily check-in streak
+func (repo *DynamoDBRepository) DeleteDailyCheckIn(username string) error {
+	_, err := repo.client.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String("DailyCheckIn"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+	})
+	return err
+}
@@ -0,0 +1,628 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Returned when a clan is created with a name that looks like the name of another clan
+var ErrClanNameTaken = errors.New("Clan name is taken")
+
+// Returned when a player who is already in a clan creates or joins one
+var ErrAlreadyInClan = errors.New("Already in a clan")
+
+// Returned when a player joins a clan that reached its size limit
+var ErrClanFull = errors.New("Clan is full")
+
+//CLAN
+// Create a clan with its leader as the only member
+// The clan claims the skeleton of its name, so no other clan can have a name that looks the same
+func (repo *DynamoDBRepository) CreateClan(clan models.Clan, skeleton string, leader models.ClanMember) error {
+	clanItem, err := dynamodbattribute.MarshalMap(clan)
+	if err != nil {
+		return err
+	}
+	memberItem, err := dynamodbattribute.MarshalMap(leader)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{
+				TableName: aws.String("ClanName"),
+				Item: map[string]*dynamodb.AttributeValue{
+					"skeleton": {S: aws.String(skeleton)},
+					"clan_id":  {S: aws.String(clan.ClanID)},
+				},
+				ConditionExpression: aws.String("attribute_not_exists(skeleton)"),
+			}},
+			{Put: &dynamodb.Put{
+				TableName:           aws.String("Clan"),
+				Item:                clanItem,
+				ConditionExpression: aws.String("attribute_not_exists(clan_id)"),
+			}},
+			{Put: &dynamodb.Put{
+				TableName: aws.String("ClanMember"),
+				Item:      memberItem,
+			}},
+			setUserClan(leader.Username, clan.ClanID),
+		},
+	})
+	if canceled, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		if conditionFailed(canceled, 0) {
+			return ErrClanNameTaken
+		}
+		return ErrAlreadyInClan
+	}
+	return err
+}
+
+// Get a clan by ID
+// Returns nil if the clan does not exist
+func (repo *DynamoDBRepository) GetClan(clanID string) (*models.Clan, error) {
+	result, err := repo.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("Clan"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"clan_id": {S: aws.String(clanID)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil
+	}
+
+	var clan models.Clan
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &clan); err != nil {
+		return nil, err
+	}
+
+	return &clan, nil
+}
+
+// Get many clans at once, leaving out those that do not exist
+func (repo *DynamoDBRepository) GetClansByIDs(clanIDs []string) ([]models.Clan, error) {
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(clanIDs))
+	for _, clanID := range clanIDs {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{
+			"clan_id": {S: aws.String(clanID)},
+		})
+	}
+
+	clans := make([]models.Clan, 0, len(clanIDs))
+	err := repo.batchGet("Clan", keys, &dynamodb.KeysAndAttributes{}, func(item map[string]*dynamodb.AttributeValue) error {
+		var clan models.Clan
+		if err := dynamodbattribute.UnmarshalMap(item, &clan); err != nil {
+			return err
+		}
+		clans = append(clans, clan)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clans, nil
+}
+
+// Get the members of a clan
+func (repo *DynamoDBRepository) GetClanMembers(clanID string) ([]models.ClanMember, error) {
+	items, err := repo.queryClanItems("ClanMember", clanID)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]models.ClanMember, 0, len(items))
+	for _, item := range items {
+		var member models.ClanMember
+		if err := dynamodbattribute.UnmarshalMap(item, &member); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// Get a member of a clan
+// Returns nil if the user is not in the clan
+func (repo *DynamoDBRepository) GetClanMember(clanID, username string) (*models.ClanMember, error) {
+	result, err := repo.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("ClanMember"),
+		Key:       clanUserKey(clanID, username),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil
+	}
+
+	var member models.ClanMember
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &member); err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// Add a player to a clan as a member, using up their invitation or join request
+// Returns ErrClanFull if the clan has no room left and ErrAlreadyInClan if the player is in a clan
+func (repo *DynamoDBRepository) AddClanMember(clanID, username string, maxMembers int) error {
+	memberItem, err := dynamodbattribute.MarshalMap(models.ClanMember{
+		ClanID:   clanID,
+		Username: username,
+		Role:     models.ClanRoleMember,
+		JoinedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Update: &dynamodb.Update{
+				TableName: aws.String("Clan"),
+				Key: map[string]*dynamodb.AttributeValue{
+					"clan_id": {S: aws.String(clanID)},
+				},
+				UpdateExpression:    aws.String("ADD member_count :one"),
+				ConditionExpression: aws.String("attribute_exists(clan_id) AND member_count < :max"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":one": {N: aws.String("1")},
+					":max": {N: aws.String(strconv.Itoa(maxMembers))},
+				},
+			}},
+			{Put: &dynamodb.Put{
+				TableName:           aws.String("ClanMember"),
+				Item:                memberItem,
+				ConditionExpression: aws.String("attribute_not_exists(username)"),
+			}},
+			setUserClan(username, clanID),
+			{Delete: &dynamodb.Delete{
+				TableName: aws.String("ClanInvitation"),
+				Key:       clanUserKey(clanID, username),
+			}},
+		},
+	})
+	if canceled, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		if conditionFailed(canceled, 0) {
+			return ErrClanFull
+		}
+		return ErrAlreadyInClan
+	}
+	return err
+}
+
+// Remove a member from a clan
+// Returns false if the user is not in the clan
+func (repo *DynamoDBRepository) RemoveClanMember(clanID, username string) (bool, error) {
+	_, err := repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Update: &dynamodb.Update{
+				TableName: aws.String("Clan"),
+				Key: map[string]*dynamodb.AttributeValue{
+					"clan_id": {S: aws.String(clanID)},
+				},
+				UpdateExpression:    aws.String("ADD member_count :minusOne"),
+				ConditionExpression: aws.String("attribute_exists(clan_id)"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":minusOne": {N: aws.String("-1")},
+				},
+			}},
+			{Delete: &dynamodb.Delete{
+				TableName:           aws.String("ClanMember"),
+				Key:                 clanUserKey(clanID, username),
+				ConditionExpression: aws.String("attribute_exists(username)"),
+			}},
+			clearUserClan(username, clanID),
+		},
+	})
+	if _, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Change the role of a clan member below the leader
+// Returns false if the user is not a member or officer of the clan
+func (repo *DynamoDBRepository) SetClanMemberRole(clanID, username, role string) (bool, error) {
+	_, err := repo.client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:           aws.String("ClanMember"),
+		Key:                 clanUserKey(clanID, username),
+		UpdateExpression:    aws.String("SET #role = :role"),
+		ConditionExpression: aws.String("attribute_exists(username) AND #role <> :leader"),
+		ExpressionAttributeNames: map[string]*string{
+			"#role": aws.String("role"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":role":   {S: aws.String(role)},
+			":leader": {S: aws.String(models.ClanRoleLeader)},
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Hand the leadership of a clan to another member, the old leader becomes an officer
+// Returns false if the old leader no longer leads the clan or the new one is not a member
+func (repo *DynamoDBRepository) TransferClanLeadership(clanID, from, to string) (bool, error) {
+	setRole := func(username, role, condition string, values map[string]*dynamodb.AttributeValue) *dynamodb.TransactWriteItem {
+		values[":role"] = &dynamodb.AttributeValue{S: aws.String(role)}
+		return &dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+			TableName:           aws.String("ClanMember"),
+			Key:                 clanUserKey(clanID, username),
+			UpdateExpression:    aws.String("SET #role = :role"),
+			ConditionExpression: aws.String(condition),
+			ExpressionAttributeNames: map[string]*string{
+				"#role": aws.String("role"),
+			},
+			ExpressionAttributeValues: values,
+		}}
+	}
+
+	_, err := repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			setRole(from, models.ClanRoleOfficer, "#role = :leader", map[string]*dynamodb.AttributeValue{
+				":leader": {S: aws.String(models.ClanRoleLeader)},
+			}),
+			setRole(to, models.ClanRoleLeader, "attribute_exists(username)", map[string]*dynamodb.AttributeValue{}),
+			{Update: &dynamodb.Update{
+				TableName: aws.String("Clan"),
+				Key: map[string]*dynamodb.AttributeValue{
+					"clan_id": {S: aws.String(clanID)},
+				},
+				UpdateExpression: aws.String("SET leader = :to"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":to": {S: aws.String(to)},
+				},
+			}},
+		},
+	})
+	if _, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Disband a clan whose leader is its last member, freeing its name
+// Returns false if the clan has other members or another leader
+func (repo *DynamoDBRepository) DeleteClan(clanID, skeleton, leader string) (bool, error) {
+	_, err := repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Delete: &dynamodb.Delete{
+				TableName: aws.String("Clan"),
+				Key: map[string]*dynamodb.AttributeValue{
+					"clan_id": {S: aws.String(clanID)},
+				},
+				ConditionExpression: aws.String("member_count = :one AND leader = :leader"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":one":    {N: aws.String("1")},
+					":leader": {S: aws.String(leader)},
+				},
+			}},
+			{Delete: &dynamodb.Delete{
+				TableName: aws.String("ClanMember"),
+				Key:       clanUserKey(clanID, leader),
+			}},
+			clearUserClan(leader, clanID),
+			{Delete: &dynamodb.Delete{
+				TableName: aws.String("ClanName"),
+				Key: map[string]*dynamodb.AttributeValue{
+					"skeleton": {S: aws.String(skeleton)},
+				},
+				ConditionExpression: aws.String("clan_id = :clanID"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":clanID": {S: aws.String(clanID)},
+				},
+			}},
+		},
+	})
+	if _, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// The feed and pending invitations of the clan go with it
+	for _, table := range []string{"ClanInvitation", "ClanActivity"} {
+		err := repo.deleteClanItems(table, clanID)
+		if err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// Store an invite or join request, replacing the one the player had for the clan
+func (repo *DynamoDBRepository) PutClanInvitation(invitation models.ClanInvitation) error {
+	item, err := dynamodbattribute.MarshalMap(invitation)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.client.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String("ClanInvitation"),
+		Item:      item,
+	})
+	return err
+}
+
+// Get a player's invite or join request for a clan
+// Returns nil if there is none
+func (repo *DynamoDBRepository) GetClanInvitation(clanID, username string) (*models.ClanInvitation, error) {
+	result, err := repo.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("ClanInvitation"),
+		Key:       clanUserKey(clanID, username),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil
+	}
+
+	var invitation models.ClanInvitation
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &invitation); err != nil {
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// Get the pending invites and join requests of a clan
+func (repo *DynamoDBRepository) GetClanInvitations(clanID string) ([]models.ClanInvitation, error) {
+	items, err := repo.queryClanItems("ClanInvitation", clanID)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalClanInvitations(items)
+}
+
+// Get the pending invites and join requests of a player, for all clans
+func (repo *DynamoDBRepository) GetUserClanInvitations(username string) ([]models.ClanInvitation, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("ClanInvitation"),
+		IndexName:              aws.String("username-index"),
+		KeyConditionExpression: aws.String("username = :username"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+	}
+
+	items := make([]map[string]*dynamodb.AttributeValue, 0)
+	err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		items = append(items, page.Items...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalClanInvitations(items)
+}
+
+// Delete a player's invite or join request for a clan
+// Returns false if there was none
+func (repo *DynamoDBRepository) DeleteClanInvitation(clanID, username string) (bool, error) {
+	_, err := repo.client.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:           aws.String("ClanInvitation"),
+		Key:                 clanUserKey(clanID, username),
+		ConditionExpression: aws.String("attribute_exists(username)"),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Add an entry to a clan's activity feed
+func (repo *DynamoDBRepository) AddClanActivity(activity models.ClanActivity) error {
+	item, err := dynamodbattribute.MarshalMap(activity)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.client.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String("ClanActivity"),
+		Item:      item,
+	})
+	return err
+}
+
+// Get the latest entries of a clan's activity feed, newest first
+func (repo *DynamoDBRepository) GetClanActivity(clanID string, limit int) ([]models.ClanActivity, error) {
+	result, err := repo.client.Query(&dynamodb.QueryInput{
+		TableName:              aws.String("ClanActivity"),
+		KeyConditionExpression: aws.String("clan_id = :clanID"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":clanID": {S: aws.String(clanID)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int64(int64(limit)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	activities := make([]models.ClanActivity, 0, len(result.Items))
+	for _, item := range result.Items {
+		var activity models.ClanActivity
+		if err := dynamodbattribute.UnmarshalMap(item, &activity); err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+
+	return activities, nil
+}
+
+// Replace a user with their alias in the activity feed of a clan
+func (repo *DynamoDBRepository) AnonymizeClanActivity(clanID, username, alias string) error {
+	items, err := repo.queryClanItems("ClanActivity", clanID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		var activity models.ClanActivity
+		if err := dynamodbattribute.UnmarshalMap(item, &activity); err != nil {
+			return err
+		}
+		if activity.Actor != username && activity.Target != username {
+			continue
+		}
+		if activity.Actor == username {
+			activity.Actor = alias
+		}
+		if activity.Target == username {
+			activity.Target = alias
+		}
+		if err := repo.AddClanActivity(activity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete every invite and join request of a player
+func (repo *DynamoDBRepository) DeleteUserClanInvitations(username string) error {
+	invitations, err := repo.GetUserClanInvitations(username)
+	if err != nil {
+		return err
+	}
+
+	for _, invitation := range invitations {
+		if _, err := repo.DeleteClanInvitation(invitation.ClanID, invitation.Username); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Query all rows a table keeps under a clan
+func (repo *DynamoDBRepository) queryClanItems(table, clanID string) ([]map[string]*dynamodb.AttributeValue, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(table),
+		KeyConditionExpression: aws.String("clan_id = :clanID"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":clanID": {S: aws.String(clanID)},
+		},
+	}
+
+	items := make([]map[string]*dynamodb.AttributeValue, 0)
+	err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		items = append(items, page.Items...)
+		return true
+	})
+	return items, err
+}
+
+// Delete all rows a table keeps under a clan
+func (repo *DynamoDBRepository) deleteClanItems(table, clanID string) error {
+	items, err := repo.queryClanItems(table, clanID)
+	if err != nil {
+		return err
+	}
+
+	sortKey := "username"
+	if table == "ClanActivity" {
+		sortKey = "activity_id"
+	}
+	for _, item := range items {
+		_, err := repo.client.DeleteItem(&dynamodb.DeleteItemInput{
+			TableName: aws.String(table),
+			Key: map[string]*dynamodb.AttributeValue{
+				"clan_id": item["clan_id"],
+				sortKey:   item[sortKey],
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode invitation rows
+func unmarshalClanInvitations(items []map[string]*dynamodb.AttributeValue) ([]models.ClanInvitation, error) {
+	invitations := make([]models.ClanInvitation, 0, len(items))
+	for _, item := range items {
+		var invitation models.ClanInvitation
+		if err := dynamodbattribute.UnmarshalMap(item, &invitation); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, nil
+}
+
+// Set the clan of a user who is in no clan
+func setUserClan(username, clanID string) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+		TableName: aws.String("User"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+		UpdateExpression:    aws.String("SET clan_id = :clanID"),
+		ConditionExpression: aws.String("attribute_exists(username) AND attribute_not_exists(clan_id)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":clanID": {S: aws.String(clanID)},
+		},
+	}}
+}
+
+// Clear the clan of a user, if it is the given one
+func clearUserClan(username, clanID string) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+		TableName: aws.String("User"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+		UpdateExpression:    aws.String("REMOVE clan_id"),
+		ConditionExpression: aws.String("clan_id = :clanID"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":clanID": {S: aws.String(clanID)},
+		},
+	}}
+}
+
+// Check whether the write at the given index of a cancelled transaction failed its condition
+func conditionFailed(canceled *dynamodb.TransactionCanceledException, index int) bool {
+	return index < len(canceled.CancellationReasons) &&
+		aws.StringValue(canceled.CancellationReasons[index].Code) == "ConditionalCheckFailed"
+}
+
+// Get the key of a user's row in a clan table
+func clanUserKey(clanID, username string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"clan_id":  {S: aws.String(clanID)},
+		"username": {S: aws.String(username)},
+	}
+}
@@ -1,14 +1,17 @@
 package repositories
 
 import (
+	"cloudblast-backend/config"
 	"cloudblast-backend/internal/models"
 	"log"
+	"math"
 	"sort"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
@@ -90,6 +93,38 @@ func (repo *DynamoDBRepository) UpdateUserField(username, fieldName string, valu
     return nil
 }
 
+// Atomically add an amount to a numeric user field
+// Returns the new value of the field
+func (repo *DynamoDBRepository) IncrementUserField(username, fieldName string, amount int) (int, error) {
+    input := &dynamodb.UpdateItemInput{
+        TableName: aws.String("User"),
+        Key: map[string]*dynamodb.AttributeValue{
+            "username": {S: aws.String(username)},
+        },
+        ExpressionAttributeNames: map[string]*string{
+            "#fieldName": aws.String(fieldName),
+        },
+        ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+            ":val": {N: aws.String(strconv.Itoa(amount))},
+        },
+        UpdateExpression: aws.String("SET #fieldName = #fieldName + :val"),
+        ConditionExpression: aws.String("attribute_exists(username)"),
+        ReturnValues:     aws.String("UPDATED_NEW"),
+    }
+
+    result, err := repo.client.UpdateItem(input)
+    if err != nil {
+        return 0, err
+    }
+
+    value, err := strconv.Atoi(*result.Attributes[fieldName].N)
+    if err != nil {
+        return 0, err
+    }
+
+    return value, nil
+}
+
 // Get all users from the database
 func (repo *DynamoDBRepository) GetAllUsers() ([]models.User, error) {
     input := &dynamodb.ScanInput{
@@ -339,8 +374,8 @@ func (repo *DynamoDBRepository) GetUserInTournamentByUsernameAndTournamentID(use
 }
 
 // Register a user to the latest tournament
-// Returns the group ID if successful, -1 if the user is already registered, -2 if the user does not have enough coins,
-// -3 if the user is not at least level 10, and -4 if the user does not exist
+// Returns the group ID if successful, -1 if the user does not exist, -2 if the user is already registered,
+// -3 if the user does not have enough coins for their league's entry fee, and -4 if the user is not at least level 10
 // This function is thread-safe to ensure atomicity for the number of registered users
 func (repo *DynamoDBRepository) RegisterToTournament(username string) (int, error) {
     // Lock the mutex to ensure atomicity
@@ -362,10 +397,8 @@ func (repo *DynamoDBRepository) RegisterToTournament(username string) (int, erro
         return -4, nil
     }
 
-    if user.Coins < 500 {
-        defer repo.mu.Unlock()
-        return -3, nil
-    }
+    // The entry fee depends on the user's league
+    _, league := config.LeagueByName(user.League)
 
     tournamentID, err := repo.GetLatestTournament()
     if err != nil {
@@ -391,10 +424,45 @@ func (repo *DynamoDBRepository) RegisterToTournament(username string) (int, erro
         return -1, err
     }
 
-    // Calculate group ID, set score and rank as 0, and claimed as false
+    // Groups are formed within a league, and within a skill rating bracket when brackets are set,
+    // a new group is opened once the current group of the league or bracket is full
     currentRegisteredUsers := tournament.NumRegisteredUsers
-    groupID := (currentRegisteredUsers / 35) + 1
+    if tournament.LeagueGroups == nil {
+        tournament.LeagueGroups = map[string]int{}
+    }
+    if tournament.LeagueGroupSizes == nil {
+        tournament.LeagueGroupSizes = map[string]int{}
+    }
+    groupKey := tournamentGroupKey(league.Name, user.Skill_Rating)
+    groupID, hasGroup := tournament.LeagueGroups[groupKey]
+    if !hasGroup || tournament.LeagueGroupSizes[groupKey] >= config.GroupSize {
+        groupID = tournament.LatestGroupID + 1
+        tournament.LatestGroupID = groupID
+        tournament.LeagueGroups[groupKey] = groupID
+        tournament.LeagueGroupSizes[groupKey] = 0
+    }
+    tournament.LeagueGroupSizes[groupKey]++
+
+    // Charge the entry fee, the transaction ID makes sure it is charged once per tournament
+    _, err = repo.PostWalletTransaction(WalletTransaction{
+        TransactionID: models.LedgerEntryFee + ":" + tournamentID + ":" + username,
+        Username:      username,
+        Currency:      league.EntryFeeCurrency,
+        Counterparty:  models.SystemAccountFees,
+        Type:          models.LedgerEntryFee,
+        Amount:        -league.EntryFee,
+        Reference:     tournamentID,
+    })
+    if err == ErrInsufficientFunds {
+        defer repo.mu.Unlock()
+        return -3, nil
+    }
+    if err != nil {
+        defer repo.mu.Unlock()
+        return -1, err
+    }
 
+    // Set score and rank as 0, and claimed as false
     newUserInTournament := models.UserInTournament{
         Username:     username,
         TournamentID: tournamentID,
@@ -402,6 +470,7 @@ func (repo *DynamoDBRepository) RegisterToTournament(username string) (int, erro
         Score:        0,
         Rank:         0,
         Claimed:      false,
+        League:       league.Name,
     }
 
     // Marshal and put the new user in the tournament into the database
@@ -418,12 +487,15 @@ func (repo *DynamoDBRepository) RegisterToTournament(username string) (int, erro
     _, err = repo.client.PutItem(input)
     if err != nil {
         defer repo.mu.Unlock()
+        repo.refundEntryFee(username, tournamentID, league)
         return -1, err
     }
 
     // Update the tournament's number of registered users atomically
     _ = repo.UpdateTournamentField(tournamentID, "num_registered_users", currentRegisteredUsers+1)
-    _ = repo.UpdateTournamentField(tournamentID, "latest_group_id", groupID)
+    _ = repo.UpdateTournamentField(tournamentID, "latest_group_id", tournament.LatestGroupID)
+    _ = repo.UpdateTournamentField(tournamentID, "league_groups", tournament.LeagueGroups)
+    _ = repo.UpdateTournamentField(tournamentID, "league_group_sizes", tournament.LeagueGroupSizes)
     defer repo.mu.Unlock()
 
     // Update the user's Latest_Tournament_ID field using UpdateUserField
@@ -432,13 +504,36 @@ func (repo *DynamoDBRepository) RegisterToTournament(username string) (int, erro
         return -1, err
     }
 
-    newCoinBalance := user.Coins - 500
-    err = repo.UpdateUserField(username, "coins", newCoinBalance)
-    if err != nil {
-        return -1, err
+    return groupID, nil
+}
+
+// Get the key of the groups a player is placed in: their league, followed by their rating bracket when brackets are set
+func tournamentGroupKey(league string, rating *models.SkillRating) string {
+    if config.RatingGroupBracket <= 0 {
+        return league
     }
+    value := config.RatingInitial
+    if rating != nil {
+        value = rating.Rating
+    }
+    return league + ":" + strconv.Itoa(int(math.Floor(value/float64(config.RatingGroupBracket))))
+}
 
-    return groupID, nil
+// Give back an entry fee when the registration could not be completed
+func (repo *DynamoDBRepository) refundEntryFee(username, tournamentID string, league config.League) {
+    _, err := repo.PostWalletTransaction(WalletTransaction{
+        TransactionID: models.LedgerRefund + ":" + models.LedgerEntryFee + ":" + tournamentID + ":" + username,
+        Username:      username,
+        Currency:      league.EntryFeeCurrency,
+        Counterparty:  models.SystemAccountFees,
+        Type:          models.LedgerRefund,
+        Amount:        league.EntryFee,
+        Reference:     tournamentID,
+        Reason:        "Registration failed",
+    })
+    if err != nil {
+        log.Printf("Failed to refund entry fee of %s: %v", username, err)
+    }
 }
 
 // Get a user's most recent joined tournament
@@ -469,54 +564,27 @@ func (repo *DynamoDBRepository) IsTournamentActive(tournamentID string) (bool, e
     return !tournament.Finished, nil
 }
 
-// Increments both the user's progress_level and the tournament's score by 1 - adds coins
-func (repo *DynamoDBRepository) IncrementUserScoreInTournament(username, tournamentID string) (int, int, int, error) {
-    updateExpression := "SET score = score + :val"
-
-    input := &dynamodb.UpdateItemInput{
-        TableName: aws.String("UserInTournament"),
-        Key: map[string]*dynamodb.AttributeValue{
-            "username":     {S: aws.String(username)},
-            "tournament_id": {S: aws.String(tournamentID)},
-        },
-        ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-            ":val": {N: aws.String("1")}, // Increment score by 1
+// Increments the user's progress_level by 1 and the tournament's score by the points - adds the coins
+// The life the level uses, the level, its points and its coins are written together.
+// eventID is the live event that multiplied the coins, if any
+func (repo *DynamoDBRepository) IncrementUserScoreInTournament(username, tournamentID string, points int, coins int, eventID string) (int, *LevelPlayResult, error) {
+    return repo.levelUp(LevelPlay{
+        Username: username,
+        Reward: WalletTransaction{
+            Counterparty: models.SystemAccountRewards,
+            Type:         models.LedgerLevelReward,
+            Amount:       coins,
+            Reference:    tournamentID,
+            EventID:      eventID,
         },
-        UpdateExpression: aws.String(updateExpression),
-        ReturnValues:     aws.String("ALL_NEW"), // Return updated attributes
-    }
-
-    result, err := repo.client.UpdateItem(input)
-    if err != nil {
-        return 0, 0, 0, err
-    }
-
-    // Fetch the user's level and coins
-    user, err := repo.GetUserByUsername(username)
-    if user == nil {
-        return 0, 0, 0, nil
-    }
-    if err != nil {
-        return 0, 0, 0, err
-    }
-
-    // Increment user's level by one and coins by 100
-    err = repo.UpdateUserField(username, "progress_level", user.Progress_Level+1)
-    if err != nil {
-        return 0, 0, 0, err
-    }
-
-    err = repo.UpdateUserField(username, "coins", user.Coins+100)
-    if err != nil {
-        return 0, 0, 0, err
-    }
-
-    score, err := strconv.Atoi(*result.Attributes["score"].N)
-    if err != nil {
-        return 0, 0, 0, err
-    }
+        TournamentID: tournamentID,
+        Points:       points,
+    })
+}
 
-    return user.Progress_Level + 1, user.Coins + 100, score, nil
+// Get the ID of the transaction rewarding a user for reaching a level
+func LevelRewardTransactionID(username string, progressLevel int) string {
+    return models.LedgerLevelReward + ":" + username + ":" + strconv.Itoa(progressLevel)
 }
 
 
@@ -558,6 +626,22 @@ func (repo *DynamoDBRepository) FindRankedPlayersForLatestTournament() ([]models
         return nil, err
     }
 
+    // Banned, shadow-banned and suspended players forfeit their prizes to the next players
+    sanctioned, err := repo.GetSanctionedUsernames()
+    if err != nil {
+        log.Printf("Failed to fetch sanctioned users: %v", err)
+        return nil, err
+    }
+
+    // Quarantined scores are left out until they are reviewed
+    settledUsers := make([]models.UserInTournament, 0, len(usersInTournament))
+    for _, user := range usersInTournament {
+        if !user.Quarantined && !sanctioned[user.Username] {
+            settledUsers = append(settledUsers, user)
+        }
+    }
+    usersInTournament = settledUsers
+
     // Sort usersInTournament based on score in descending order
     if len(usersInTournament) >= 1 {
         sort.Slice(usersInTournament, func(i, j int) bool {
@@ -611,6 +695,32 @@ func (repo *DynamoDBRepository) EndLatestTournament() (string, error) {
     return latestTournamentID, nil
 }
 
+// Mark that the players of a tournament were told it is ending soon
+// Returns false if another instance already marked it
+func (repo *DynamoDBRepository) MarkTournamentEndingSoonNotified(tournamentID string) (bool, error) {
+    input := &dynamodb.UpdateItemInput{
+        TableName: aws.String("Tournament"),
+        Key: map[string]*dynamodb.AttributeValue{
+            "tournament_id": {S: aws.String(tournamentID)},
+        },
+        ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+            ":true": {BOOL: aws.Bool(true)},
+        },
+        UpdateExpression:    aws.String("SET ending_soon_notified = :true"),
+        ConditionExpression: aws.String("attribute_not_exists(ending_soon_notified) OR ending_soon_notified <> :true"),
+    }
+
+    _, err := repo.client.UpdateItem(input)
+    if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+        return false, nil
+    }
+    if err != nil {
+        return false, err
+    }
+
+    return true, nil
+}
+
 // Mark a player's reward as claimed
 func (repo *DynamoDBRepository) UpdateUserInTournamentClaimed(username, tournamentID string, claimed bool) error {
     updateExpression := "SET #cl = :claimed"
@@ -740,6 +850,8 @@ func (repo *DynamoDBRepository) GetCountryLeaderboard(username string) ([]models
     }
 
     // Filter and sort users
+    // Sanctioned users are hidden, shadow-banned users still see themselves
+    now := time.Now()
     var users []models.User
     for _, item := range result.Items {
         user := models.User{}
@@ -747,6 +859,9 @@ func (repo *DynamoDBRepository) GetCountryLeaderboard(username string) ([]models
         if err != nil {
             return nil, err
         }
+        if user.IsSanctionedAt(now) && user.Username != username {
+            continue
+        }
         users = append(users, user)
     }
 
@@ -790,8 +905,8 @@ func (repo *DynamoDBRepository) GetCountryForUser(username string) (string, erro
     return *countryAttr.S, nil
 }
 
-// Get the top 1000 users globally
-func (repo *DynamoDBRepository) GetGlobalLeaderboard() ([]models.User, error) {
+// Get the top 1000 users globally as seen by the given username
+func (repo *DynamoDBRepository) GetGlobalLeaderboard(viewer string) ([]models.User, error) {
     // Scan all users
     input := &dynamodb.ScanInput{
         TableName: aws.String("User"),
@@ -803,6 +918,8 @@ func (repo *DynamoDBRepository) GetGlobalLeaderboard() ([]models.User, error) {
     }
 
     // Filter and sort users
+    // Sanctioned users are hidden, shadow-banned users still see themselves
+    now := time.Now()
     var users []models.User
     for _, item := range result.Items {
         user := models.User{}
@@ -810,6 +927,9 @@ func (repo *DynamoDBRepository) GetGlobalLeaderboard() ([]models.User, error) {
         if err != nil {
             return nil, err
         }
+        if user.IsSanctionedAt(now) && user.Username != viewer {
+            continue
+        }
         users = append(users, user)
     }
 
@@ -825,3 +945,84 @@ func (repo *DynamoDBRepository) GetGlobalLeaderboard() ([]models.User, error) {
     }
     return users[:maxUsers], nil
 }
+
+// Get all users in a tournament group
+func (repo *DynamoDBRepository) GetUsersInGroup(tournamentID string, groupID int) ([]models.UserInTournament, error) {
+    usersInTournament, err := repo.GetUsersInTournament(tournamentID)
+    if err != nil {
+        return nil, err
+    }
+
+    usersInGroup := make([]models.UserInTournament, 0)
+    for _, user := range usersInTournament {
+        if user.GroupID == groupID {
+            usersInGroup = append(usersInGroup, user)
+        }
+    }
+
+    return usersInGroup, nil
+}
+
+// Record a user's final placement in their group and move them to the league it earns, in one transaction
+// The league only changes if the user is still in the league they played the tournament in, otherwise the
+// placement is recorded without a league change. Returns false if the placement was already recorded
+func (repo *DynamoDBRepository) PlaceUserInTournament(username, tournamentID string, groupRank int, leagueChange string, fromLeague string, toLeague string) (bool, error) {
+    if leagueChange == "" {
+        toLeague = ""
+    }
+
+    items := []*dynamodb.TransactWriteItem{{
+        Update: &dynamodb.Update{
+            TableName: aws.String("UserInTournament"),
+            Key: map[string]*dynamodb.AttributeValue{
+                "username":      {S: aws.String(username)},
+                "tournament_id": {S: aws.String(tournamentID)},
+            },
+            ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+                ":group_rank":    {N: aws.String(strconv.Itoa(groupRank))},
+                ":league_change": {S: aws.String(leagueChange)},
+                ":unplaced":      {N: aws.String("0")},
+            },
+            UpdateExpression: aws.String("SET group_rank = :group_rank, league_change = :league_change"),
+            // Entries get their group rank when they are placed
+            ConditionExpression: aws.String("attribute_exists(tournament_id) AND (attribute_not_exists(group_rank) OR group_rank = :unplaced)"),
+        },
+    }}
+
+    if toLeague != "" {
+        // Users who never changed league have no league stored
+        conditionExpression := "league = :from"
+        if fromLeague == config.Leagues[0].Name {
+            conditionExpression = "(attribute_not_exists(league) OR league = :from)"
+        }
+        items = append(items, &dynamodb.TransactWriteItem{
+            Update: &dynamodb.Update{
+                TableName: aws.String("User"),
+                Key: map[string]*dynamodb.AttributeValue{
+                    "username": {S: aws.String(username)},
+                },
+                ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+                    ":from": {S: aws.String(fromLeague)},
+                    ":to":   {S: aws.String(toLeague)},
+                },
+                UpdateExpression:    aws.String("SET league = :to"),
+                ConditionExpression: aws.String("attribute_exists(username) AND " + conditionExpression),
+            },
+        })
+    }
+
+    _, err := repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: items})
+    if canceled, ok := err.(*dynamodb.TransactionCanceledException); ok {
+        if conditionFailed(canceled, 0) {
+            return false, nil
+        }
+        if conditionFailed(canceled, 1) {
+            return repo.PlaceUserInTournament(username, tournamentID, groupRank, "", fromLeague, "")
+        }
+    }
+    if err != nil {
+        return false, err
+    }
+
+    return true, nil
+}
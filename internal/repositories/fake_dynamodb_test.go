@@ -0,0 +1,213 @@
+package repositories
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Key attributes of the tables the fake knows, using any other table fails the test
+var fakeTableKeys = map[string][]string{
+	"User":          {"username"},
+	"WalletBalance": {"username", "currency"},
+	"WalletLedger":  {"transaction_id", "account"},
+	"Mailbox":       {"username", "mail_id"},
+	"LevelBest":     {"username", "level_id"},
+	"Lives":         {"username"},
+}
+
+// fakeDynamoDB is a DynamoDB endpoint serving GetItem, UpdateItem and TransactWriteItems from memory
+// It does not evaluate expressions: transactions succeed unless cancel says otherwise, and only their Puts
+// are stored. Updates are only recorded, and fail their condition when failUpdate says so. Tests change items directly to play the part of concurrent writers.
+type fakeDynamoDB struct {
+	t  *testing.T
+	mu sync.Mutex
+	// Items by table and key
+	items map[string]map[string]map[string]*dynamodb.AttributeValue
+	// Every TransactWriteItems request received, canceled ones included
+	transactions []*dynamodb.TransactWriteItemsInput
+	// Gives the cancellation reason codes of a transaction, nil lets it succeed
+	cancel func(attempt int, input *dynamodb.TransactWriteItemsInput) []string
+	// Every UpdateItem request received
+	updates []*dynamodb.UpdateItemInput
+	// Tells whether the condition of an update fails, nil lets every update succeed
+	failUpdate func(input *dynamodb.UpdateItemInput) bool
+}
+
+// Start a fake DynamoDB endpoint and get a repository talking to it
+func newFakeDynamoDB(t *testing.T) (*fakeDynamoDB, *DynamoDBRepository) {
+	t.Helper()
+
+	fake := &fakeDynamoDB{t: t, items: map[string]map[string]map[string]*dynamodb.AttributeValue{}}
+	server := httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(server.Close)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("eu-central-1"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("test", "test", ""),
+		MaxRetries:  aws.Int(0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, &DynamoDBRepository{client: dynamodb.New(sess)}
+}
+
+// Store an item, replacing the one with the same key
+func (fake *fakeDynamoDB) put(table string, item map[string]*dynamodb.AttributeValue) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.putLocked(table, item)
+}
+
+func (fake *fakeDynamoDB) putLocked(table string, item map[string]*dynamodb.AttributeValue) {
+	if fake.items[table] == nil {
+		fake.items[table] = map[string]map[string]*dynamodb.AttributeValue{}
+	}
+	fake.items[table][fake.key(table, item)] = item
+}
+
+// Get a stored item, nil if there is none
+func (fake *fakeDynamoDB) get(table string, key map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.items[table][fake.key(table, key)]
+}
+
+// Get the TransactWriteItems requests received so far
+func (fake *fakeDynamoDB) transactWrites() []*dynamodb.TransactWriteItemsInput {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([]*dynamodb.TransactWriteItemsInput(nil), fake.transactions...)
+}
+
+// Get the UpdateItem requests received so far
+func (fake *fakeDynamoDB) updateItems() []*dynamodb.UpdateItemInput {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([]*dynamodb.UpdateItemInput(nil), fake.updates...)
+}
+
+func (fake *fakeDynamoDB) key(table string, item map[string]*dynamodb.AttributeValue) string {
+	names, ok := fakeTableKeys[table]
+	if !ok {
+		fake.t.Errorf("fake DynamoDB does not know table %s", table)
+	}
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		value := item[name]
+		switch {
+		case value != nil && value.S != nil:
+			parts = append(parts, *value.S)
+		case value != nil && value.N != nil:
+			parts = append(parts, *value.N)
+		default:
+			fake.t.Errorf("item of %s has no key attribute %s", table, name)
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func (fake *fakeDynamoDB) serve(w http.ResponseWriter, r *http.Request) {
+	operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.")
+	switch operation {
+	case "GetItem":
+		var input dynamodb.GetItemInput
+		if !fake.decode(w, r, &input) {
+			return
+		}
+		fake.respond(w, &dynamodb.GetItemOutput{Item: fake.get(*input.TableName, input.Key)})
+
+	case "UpdateItem":
+		var input dynamodb.UpdateItemInput
+		if !fake.decode(w, r, &input) {
+			return
+		}
+
+		fake.mu.Lock()
+		fake.updates = append(fake.updates, &input)
+		fake.mu.Unlock()
+
+		if fake.failUpdate != nil && fake.failUpdate(&input) {
+			fake.fail(w, "ConditionalCheckFailedException", `"Message":"The conditional request failed"`)
+			return
+		}
+		fake.respond(w, &dynamodb.UpdateItemOutput{})
+
+	case "TransactWriteItems":
+		var input dynamodb.TransactWriteItemsInput
+		if !fake.decode(w, r, &input) {
+			return
+		}
+
+		fake.mu.Lock()
+		fake.transactions = append(fake.transactions, &input)
+		attempt := len(fake.transactions)
+		fake.mu.Unlock()
+
+		if fake.cancel != nil {
+			if codes := fake.cancel(attempt, &input); codes != nil {
+				fake.cancelTransaction(w, codes)
+				return
+			}
+		}
+
+		fake.mu.Lock()
+		for _, item := range input.TransactItems {
+			if item.Put != nil {
+				fake.putLocked(*item.Put.TableName, item.Put.Item)
+			}
+		}
+		fake.mu.Unlock()
+		fake.respond(w, &dynamodb.TransactWriteItemsOutput{})
+
+	default:
+		fake.t.Errorf("fake DynamoDB does not serve %s", operation)
+		fake.fail(w, "UnknownOperationException", `"Message":"unknown operation"`)
+	}
+}
+
+func (fake *fakeDynamoDB) decode(w http.ResponseWriter, r *http.Request, input interface{}) bool {
+	err := jsonutil.UnmarshalJSON(input, r.Body)
+	if err != nil {
+		fake.t.Errorf("fake DynamoDB could not decode request: %v", err)
+		fake.fail(w, "SerializationException", `"Message":"bad request"`)
+		return false
+	}
+	return true
+}
+
+func (fake *fakeDynamoDB) respond(w http.ResponseWriter, output interface{}) {
+	body, err := jsonutil.BuildJSON(output)
+	if err != nil {
+		fake.t.Errorf("fake DynamoDB could not encode response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.Write(body)
+}
+
+// Answer with a canceled transaction, one reason code per item
+func (fake *fakeDynamoDB) cancelTransaction(w http.ResponseWriter, codes []string) {
+	reasons := make([]string, 0, len(codes))
+	for _, code := range codes {
+		reasons = append(reasons, `{"Code":"`+code+`"}`)
+	}
+	fake.fail(w, "TransactionCanceledException", `"Message":"Transaction cancelled","CancellationReasons":[`+strings.Join(reasons, ",")+`]`)
+}
+
+func (fake *fakeDynamoDB) fail(w http.ResponseWriter, errorType string, fields string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#` + errorType + `",` + fields + `}`))
+}
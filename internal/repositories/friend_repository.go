@@ -0,0 +1,458 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Most keys a BatchGetItem request can read
+const maxBatchGetKeys = 100
+
+// Returned when a friend request is sent to a user the sender already has a relation with
+var ErrFriendshipExists = errors.New("Friendship already exists")
+
+//FRIEND
+// Get all friendships, requests and blocks stored under a user
+func (repo *DynamoDBRepository) GetFriendships(username string) ([]models.Friendship, error) {
+	items, err := repo.queryUserItems("Friendship", username)
+	if err != nil {
+		return nil, err
+	}
+
+	friendships := make([]models.Friendship, 0, len(items))
+	for _, item := range items {
+		var friendship models.Friendship
+		if err := dynamodbattribute.UnmarshalMap(item, &friendship); err != nil {
+			return nil, err
+		}
+		friendships = append(friendships, friendship)
+	}
+
+	return friendships, nil
+}
+
+// Get a user's side of their relation with another user
+// Returns nil if there is none
+func (repo *DynamoDBRepository) GetFriendship(username, friend string) (*models.Friendship, error) {
+	result, err := repo.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("Friendship"),
+		Key:       friendshipKey(username, friend),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil
+	}
+
+	var friendship models.Friendship
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &friendship); err != nil {
+		return nil, err
+	}
+
+	return &friendship, nil
+}
+
+// Count a user's friends and pending friend requests, blocks are not counted
+func (repo *DynamoDBRepository) CountFriendships(username string) (int, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("Friendship"),
+		KeyConditionExpression: aws.String("username = :username"),
+		FilterExpression:       aws.String("#status <> :blocked"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+			":blocked":  {S: aws.String(models.FriendStatusBlocked)},
+		},
+		Select: aws.String(dynamodb.SelectCount),
+	}
+
+	count := 0
+	err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		count += int(aws.Int64Value(page.Count))
+		return true
+	})
+	return count, err
+}
+
+// Send a friend request, storing it on the side of both users
+// Returns ErrFriendshipExists if either user already has a relation with the other, including a block
+func (repo *DynamoDBRepository) CreateFriendRequest(from, to string) error {
+	now := time.Now().UTC()
+	outgoing, err := dynamodbattribute.MarshalMap(models.Friendship{
+		Username: from, Friend: to, Status: models.FriendStatusPendingOut, CreatedAt: now, UpdatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+	incoming, err := dynamodbattribute.MarshalMap(models.Friendship{
+		Username: to, Friend: from, Status: models.FriendStatusPendingIn, CreatedAt: now, UpdatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{
+				TableName:           aws.String("Friendship"),
+				Item:                outgoing,
+				ConditionExpression: aws.String("attribute_not_exists(username)"),
+			}},
+			{Put: &dynamodb.Put{
+				TableName:           aws.String("Friendship"),
+				Item:                incoming,
+				ConditionExpression: aws.String("attribute_not_exists(username)"),
+			}},
+		},
+	})
+	if _, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		return ErrFriendshipExists
+	}
+	return err
+}
+
+// Accept a friend request a user received
+// Returns false if there is no such request
+func (repo *DynamoDBRepository) AcceptFriendRequest(username, friend string) (bool, error) {
+	now, err := dynamodbattribute.Marshal(time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+
+	update := func(key map[string]*dynamodb.AttributeValue, from string) *dynamodb.TransactWriteItem {
+		return &dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+			TableName:        aws.String("Friendship"),
+			Key:              key,
+			UpdateExpression: aws.String("SET #status = :friends, updated_at = :now"),
+			ExpressionAttributeNames: map[string]*string{
+				"#status": aws.String("status"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":friends": {S: aws.String(models.FriendStatusFriends)},
+				":from":    {S: aws.String(from)},
+				":now":     now,
+			},
+			ConditionExpression: aws.String("#status = :from"),
+		}}
+	}
+
+	_, err = repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			update(friendshipKey(username, friend), models.FriendStatusPendingIn),
+			update(friendshipKey(friend, username), models.FriendStatusPendingOut),
+		},
+	})
+	if _, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Remove a friend, or decline or cancel a friend request, on the side of both users
+// Returns false if the users are neither friends nor have a pending request
+func (repo *DynamoDBRepository) DeleteFriendship(username, friend string) (bool, error) {
+	notBlocked := map[string]*dynamodb.AttributeValue{
+		":blocked": {S: aws.String(models.FriendStatusBlocked)},
+	}
+	statusName := map[string]*string{
+		"#status": aws.String("status"),
+	}
+
+	_, err := repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Delete: &dynamodb.Delete{
+				TableName:                 aws.String("Friendship"),
+				Key:                       friendshipKey(username, friend),
+				ConditionExpression:       aws.String("attribute_exists(username) AND #status <> :blocked"),
+				ExpressionAttributeNames:  statusName,
+				ExpressionAttributeValues: notBlocked,
+			}},
+			{Delete: &dynamodb.Delete{
+				TableName:                 aws.String("Friendship"),
+				Key:                       friendshipKey(friend, username),
+				ConditionExpression:       aws.String("attribute_not_exists(username) OR #status <> :blocked"),
+				ExpressionAttributeNames:  statusName,
+				ExpressionAttributeValues: notBlocked,
+			}},
+		},
+	})
+	if _, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Block a user, ending any friendship or request between the two
+// A block the other user placed in return is kept
+func (repo *DynamoDBRepository) BlockUser(username, target string) error {
+	now := time.Now().UTC()
+	block, err := dynamodbattribute.MarshalMap(models.Friendship{
+		Username: username, Friend: target, Status: models.FriendStatusBlocked, CreatedAt: now, UpdatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.client.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String("Friendship"),
+		Item:      block,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.client.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:           aws.String("Friendship"),
+		Key:                 friendshipKey(target, username),
+		ConditionExpression: aws.String("#status <> :blocked"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":blocked": {S: aws.String(models.FriendStatusBlocked)},
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil
+	}
+	return err
+}
+
+// Lift a block a user placed
+// Returns false if the user did not block the target
+func (repo *DynamoDBRepository) UnblockUser(username, target string) (bool, error) {
+	_, err := repo.client.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:           aws.String("Friendship"),
+		Key:                 friendshipKey(username, target),
+		ConditionExpression: aws.String("#status = :blocked"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":blocked": {S: aws.String(models.FriendStatusBlocked)},
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete every friendship, request and block other users keep with a user
+func (repo *DynamoDBRepository) DeleteFriendshipsWith(username string) error {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("Friendship"),
+		IndexName:              aws.String("friend-index"),
+		KeyConditionExpression: aws.String("friend = :friend"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":friend": {S: aws.String(username)},
+		},
+	}
+
+	items := make([]map[string]*dynamodb.AttributeValue, 0)
+	err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		items = append(items, page.Items...)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		_, err := repo.client.DeleteItem(&dynamodb.DeleteItemInput{
+			TableName: aws.String("Friendship"),
+			Key: map[string]*dynamodb.AttributeValue{
+				"username": item["username"],
+				"friend":   item["friend"],
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get the profiles of many users at once, leaving out those that do not exist
+func (repo *DynamoDBRepository) GetUsersByUsernames(usernames []string) ([]models.User, error) {
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(usernames))
+	for _, username := range usernames {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		})
+	}
+
+	// Passwords and the other private fields are not read
+	projection := &dynamodb.KeysAndAttributes{
+		ProjectionExpression: aws.String("username, display_name, avatar_id, frame_id, country, progress_level, moderation_status, suspended_until"),
+	}
+
+	users := make([]models.User, 0, len(usernames))
+	err := repo.batchGet("User", keys, projection, func(item map[string]*dynamodb.AttributeValue) error {
+		var user models.User
+		if err := dynamodbattribute.UnmarshalMap(item, &user); err != nil {
+			return err
+		}
+		users = append(users, user)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// Get the records of many users in a tournament at once, leaving out the users who did not enter it
+func (repo *DynamoDBRepository) GetUsersInTournamentByUsernames(tournamentID string, usernames []string) ([]models.UserInTournament, error) {
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(usernames))
+	for _, username := range usernames {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{
+			"username":      {S: aws.String(username)},
+			"tournament_id": {S: aws.String(tournamentID)},
+		})
+	}
+
+	records := make([]models.UserInTournament, 0, len(usernames))
+	err := repo.batchGet("UserInTournament", keys, &dynamodb.KeysAndAttributes{}, func(item map[string]*dynamodb.AttributeValue) error {
+		var record models.UserInTournament
+		if err := dynamodbattribute.UnmarshalMap(item, &record); err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// Read many rows of a table by key, maxBatchGetKeys at a time
+// The keys that are not processed in a request are retried by the SDK's paging
+func (repo *DynamoDBRepository) batchGet(table string, keys []map[string]*dynamodb.AttributeValue, template *dynamodb.KeysAndAttributes, fn func(item map[string]*dynamodb.AttributeValue) error) error {
+	for start := 0; start < len(keys); start += maxBatchGetKeys {
+		end := start + maxBatchGetKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		request := *template
+		request.Keys = keys[start:end]
+		input := &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]*dynamodb.KeysAndAttributes{
+				table: &request,
+			},
+		}
+
+		var fnErr error
+		err := repo.client.BatchGetItemPages(input, func(page *dynamodb.BatchGetItemOutput, lastPage bool) bool {
+			for _, item := range page.Responses[table] {
+				if fnErr = fn(item); fnErr != nil {
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		if fnErr != nil {
+			return fnErr
+		}
+	}
+	return nil
+}
+
+// Give a user a friend code, unless another user already has it or the user already has one
+// Returns false if the code or the user was taken
+func (repo *DynamoDBRepository) ClaimFriendCode(code, username string) (bool, error) {
+	_, err := repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{
+				TableName: aws.String("FriendCode"),
+				Item: map[string]*dynamodb.AttributeValue{
+					"code":     {S: aws.String(code)},
+					"username": {S: aws.String(username)},
+				},
+				ConditionExpression: aws.String("attribute_not_exists(code)"),
+			}},
+			{Update: &dynamodb.Update{
+				TableName: aws.String("User"),
+				Key: map[string]*dynamodb.AttributeValue{
+					"username": {S: aws.String(username)},
+				},
+				UpdateExpression: aws.String("SET friend_code = :code"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":code": {S: aws.String(code)},
+				},
+				ConditionExpression: aws.String("attribute_exists(username) AND attribute_not_exists(friend_code)"),
+			}},
+		},
+	})
+	if _, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Get the user a friend code belongs to
+// Returns an empty string if no user has the code
+func (repo *DynamoDBRepository) GetFriendCodeOwner(code string) (string, error) {
+	result, err := repo.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("FriendCode"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"code": {S: aws.String(code)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if result == nil || result.Item == nil || result.Item["username"] == nil {
+		return "", nil
+	}
+	return aws.StringValue(result.Item["username"].S), nil
+}
+
+// Delete a friend code, so that it no longer finds its user
+func (repo *DynamoDBRepository) DeleteFriendCode(code string) error {
+	_, err := repo.client.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String("FriendCode"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"code": {S: aws.String(code)},
+		},
+	})
+	return err
+}
+
+// Get the key of a user's side of a relation
+func friendshipKey(username, friend string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"username": {S: aws.String(username)},
+		"friend":   {S: aws.String(friend)},
+	}
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+//HALL OF FAME
+//Create a hall of fame entry for a tournament winner
+//The tournament ID is the key, so recording the same tournament twice overwrites the entry
+func (repo *DynamoDBRepository) CreateHallOfFameEntry(entry *models.HallOfFameEntry) error {
+	av, err := dynamodbattribute.MarshalMap(entry)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String("HallOfFame"),
+		Item:      av,
+	}
+
+	_, err = repo.client.PutItem(input)
+	return err
+}
+
+// Get all hall of fame entries, most recent tournament first
+func (repo *DynamoDBRepository) GetHallOfFame() ([]models.HallOfFameEntry, error) {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String("HallOfFame"),
+	}
+
+	result, err := repo.client.Scan(input)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.HallOfFameEntry, 0)
+	for _, item := range result.Items {
+		var entry models.HallOfFameEntry
+		err := dynamodbattribute.UnmarshalMap(item, &entry)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.After(entries[j].Date)
+	})
+
+	return entries, nil
+}
+
+// Get the ranked players (ranks 1 to 4) of a tournament
+func (repo *DynamoDBRepository) GetRankedPlayersForTournament(tournamentID string) ([]models.UserInTournament, error) {
+	usersInTournament, err := repo.GetUsersInTournament(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	rankedPlayers := make([]models.UserInTournament, 0)
+	for _, user := range usersInTournament {
+		if user.Rank >= 1 && user.Rank <= 4 {
+			rankedPlayers = append(rankedPlayers, user)
+		}
+	}
+
+	sort.Slice(rankedPlayers, func(i, j int) bool {
+		return rankedPlayers[i].Rank < rankedPlayers[j].Rank
+	})
+
+	return rankedPlayers, nil
+}
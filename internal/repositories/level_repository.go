@@ -0,0 +1,385 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+//LEVEL
+// Returned when a level is completed for the first time by a user who is not on that level anymore,
+// e.g. because a concurrent completion advanced them
+var ErrProgressChanged = errors.New("Progress level changed")
+
+// Returned when a play sets a best result that changed since the stars it pays for were counted
+var ErrLevelBestChanged = errors.New("Best level result changed")
+
+// LevelPlay describes the writes of a level played by a user
+type LevelPlay struct {
+	Username string
+	// Progress level the play completes, the user moves on to the next one.
+	// 0 for a replay, which leaves the progress level alone
+	FromLevel int
+	// Reward of the play, nothing is paid if its amount is 0
+	Reward WalletTransaction
+	// Tournament the play earns points in, none if empty
+	TournamentID string
+	Points       int
+	// Best result the play sets, none if nil. It is only written if the stored best still has
+	// PreviousStars stars, so the stars a play is paid for above the best are paid once
+	Best          *models.LevelBest
+	PreviousStars int
+}
+
+// LevelPlayResult is what a level play wrote
+type LevelPlayResult struct {
+	// Lives left after the life the play used
+	Lives models.Lives
+	// Ledger entry of the reward, nil if nothing was paid
+	Entry *models.LedgerEntry
+	// Score in the tournament after the points were added
+	Score int
+	// Whether the play wrote its best result
+	NewBest bool
+}
+
+// Record a level played by a user
+// The life the level uses, the progress level it completes, its reward and its tournament points are
+// written in one transaction with its best result, so a failure leaves none of them and the level can be played again.
+// Returns ErrProgressChanged if the user is not on the level the play completes, ErrLevelBestChanged
+// if the best result does not have the stars the play expects, or ErrNoLives if the user has no life left
+func (repo *DynamoDBRepository) PlayLevel(play LevelPlay) (*LevelPlayResult, error) {
+	result := &LevelPlayResult{}
+	prepare := func() ([]*dynamodb.TransactWriteItem, error) {
+		lifeItem, lives, err := repo.useLifeItem(play.Username)
+		if err != nil {
+			return nil, err
+		}
+		result.Lives = *lives
+		items := []*dynamodb.TransactWriteItem{lifeItem}
+
+		if play.FromLevel > 0 {
+			user, err := repo.GetUserByUsername(play.Username)
+			if err != nil {
+				return nil, err
+			}
+			if user == nil {
+				return nil, ErrUserNotFound
+			}
+			if user.Progress_Level != play.FromLevel {
+				return nil, ErrProgressChanged
+			}
+			items = append(items, progressLevelItem(play.Username, play.FromLevel))
+		}
+
+		result.NewBest = false
+		if play.Best != nil {
+			bestItem, err := repo.levelBestItem(play.Best, play.PreviousStars)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, bestItem)
+			result.NewBest = true
+		}
+
+		if play.TournamentID != "" {
+			scoreItem, score, err := repo.tournamentScoreItem(play.Username, play.TournamentID, play.Points)
+			if err != nil {
+				return nil, err
+			}
+			result.Score = score
+			items = append(items, scoreItem)
+		}
+		return items, nil
+	}
+
+	if play.Reward.Amount != 0 {
+		play.Reward.Username = play.Username
+		play.Reward.prepare = prepare
+		entry, posted, err := repo.postWalletTransaction(play.Reward)
+		if err != nil {
+			return nil, err
+		}
+		if posted {
+			result.Entry = entry
+			return result, nil
+		}
+		// A concurrent play was paid the reward: a first completion lost the level to it,
+		// a replay still uses its life but is not paid again and leaves the best result to it
+		if play.FromLevel > 0 {
+			return nil, ErrProgressChanged
+		}
+		play.Best = nil
+	}
+
+	for attempt := 0; attempt < maxLedgerAttempts; attempt++ {
+		items, err := prepare()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: items})
+		if err == nil {
+			return result, nil
+		}
+		if !isRetryableTransactionError(err) {
+			return nil, err
+		}
+	}
+
+	return nil, errors.New("Lives changed too often, level not recorded")
+}
+
+// Build the conditional write moving a user from a level to the next one
+// The level is only written if the user is still on the level they move from
+func progressLevelItem(username string, fromLevel int) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName: aws.String("User"),
+			Key: map[string]*dynamodb.AttributeValue{
+				"username": {S: aws.String(username)},
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":fromLevel": {N: aws.String(strconv.Itoa(fromLevel))},
+				":nextLevel": {N: aws.String(strconv.Itoa(fromLevel + 1))},
+			},
+			UpdateExpression:    aws.String("SET progress_level = :nextLevel"),
+			ConditionExpression: aws.String("progress_level = :fromLevel"),
+		},
+	}
+}
+
+// Build the conditional write of a new best result of a level
+// The best is only written if the stored one still has the given stars, none for 0.
+// Returns ErrLevelBestChanged if it does not
+func (repo *DynamoDBRepository) levelBestItem(best *models.LevelBest, previousStars int) (*dynamodb.TransactWriteItem, error) {
+	current, err := repo.GetLevelBest(best.Username, best.LevelID)
+	if err != nil {
+		return nil, err
+	}
+	currentStars := 0
+	if current != nil {
+		currentStars = current.Stars
+	}
+	if currentStars != previousStars {
+		return nil, ErrLevelBestChanged
+	}
+
+	item, err := dynamodbattribute.MarshalMap(best)
+	if err != nil {
+		return nil, err
+	}
+	put := &dynamodb.Put{
+		TableName:           aws.String("LevelBest"),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(username)"),
+	}
+	if current != nil {
+		put.ConditionExpression = aws.String("stars = :previousStars")
+		put.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":previousStars": {N: aws.String(strconv.Itoa(previousStars))},
+		}
+	}
+	return &dynamodb.TransactWriteItem{Put: put}, nil
+}
+
+// Complete the level a user is on, which raises their progress level by one
+// The play is retried on the next level when a concurrent completion advanced the user first.
+// Returns the level reached
+func (repo *DynamoDBRepository) levelUp(play LevelPlay) (int, *LevelPlayResult, error) {
+	for attempt := 0; attempt < maxLedgerAttempts; attempt++ {
+		user, err := repo.GetUserByUsername(play.Username)
+		if err != nil {
+			return 0, nil, err
+		}
+		if user == nil {
+			return 0, nil, ErrUserNotFound
+		}
+
+		play.FromLevel = user.Progress_Level
+		play.Reward.TransactionID = LevelRewardTransactionID(play.Username, play.FromLevel+1)
+		result, err := repo.PlayLevel(play)
+		if err == ErrProgressChanged {
+			continue
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		return play.FromLevel + 1, result, nil
+	}
+
+	return 0, nil, errors.New("Progress level changed too often, level not completed")
+}
+
+// Use one of a user's lives to raise their progress level by one and reward the level reached with coins
+// Returns the level reached
+func (repo *DynamoDBRepository) LevelUp(username string, coins int, eventID string) (int, *LevelPlayResult, error) {
+	return repo.levelUp(LevelPlay{
+		Username: username,
+		Reward: WalletTransaction{
+			Counterparty: models.SystemAccountRewards,
+			Type:         models.LedgerLevelReward,
+			Amount:       coins,
+			EventID:      eventID,
+		},
+	})
+}
+
+// Build the conditional write of points added to a user's score in a tournament
+// Returns the write and the new score
+func (repo *DynamoDBRepository) tournamentScoreItem(username, tournamentID string, points int) (*dynamodb.TransactWriteItem, int, error) {
+	result, err := repo.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("UserInTournament"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username":      {S: aws.String(username)},
+			"tournament_id": {S: aws.String(tournamentID)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if result == nil || result.Item == nil {
+		return nil, 0, errors.New("User is not in the tournament")
+	}
+
+	var userInTournament models.UserInTournament
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &userInTournament); err != nil {
+		return nil, 0, err
+	}
+	score := userInTournament.Score + points
+
+	// The score is only written if nobody changed it since it was read
+	return &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName: aws.String("UserInTournament"),
+			Key: map[string]*dynamodb.AttributeValue{
+				"username":      {S: aws.String(username)},
+				"tournament_id": {S: aws.String(tournamentID)},
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":oldScore": {N: aws.String(strconv.Itoa(userInTournament.Score))},
+				":newScore": {N: aws.String(strconv.Itoa(score))},
+			},
+			UpdateExpression:    aws.String("SET score = :newScore"),
+			ConditionExpression: aws.String("score = :oldScore"),
+		},
+	}, score, nil
+}
+
+// Record a level completion
+func (repo *DynamoDBRepository) CreateLevelCompletion(completion *models.LevelCompletion) error {
+	av, err := dynamodbattribute.MarshalMap(completion)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String("LevelCompletion"),
+		Item:      av,
+	}
+
+	_, err = repo.client.PutItem(input)
+	return err
+}
+
+// Get a user's best result of a level
+// Returns nil if the user never completed the level
+func (repo *DynamoDBRepository) GetLevelBest(username string, levelID int) (*models.LevelBest, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String("LevelBest"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+			"level_id": {N: aws.String(strconv.Itoa(levelID))},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	result, err := repo.client.GetItem(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil // Level not completed
+	}
+
+	var best models.LevelBest
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &best); err != nil {
+		return nil, err
+	}
+
+	return &best, nil
+}
+
+// Store a result as the user's best result of a level if it beats the current one
+// More stars are better, and with the same stars fewer moves are better
+// Returns false if the current best result is at least as good
+func (repo *DynamoDBRepository) UpdateLevelBest(best *models.LevelBest) (bool, error) {
+	av, err := dynamodbattribute.MarshalMap(best)
+	if err != nil {
+		return false, err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String("LevelBest"),
+		Item:      av,
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":stars": {N: aws.String(strconv.Itoa(best.Stars))},
+			":moves": {N: aws.String(strconv.Itoa(best.MovesUsed))},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(username) OR stars < :stars OR (stars = :stars AND moves_used > :moves)"),
+	}
+
+	_, err = repo.client.PutItem(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Get a user's best results of all levels, ordered by level
+func (repo *DynamoDBRepository) GetLevelBests(username string) ([]models.LevelBest, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("LevelBest"),
+		KeyConditionExpression: aws.String("username = :username"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+	}
+
+	bests := make([]models.LevelBest, 0)
+	var unmarshalErr error
+	err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var best models.LevelBest
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &best); unmarshalErr != nil {
+				return false
+			}
+			bests = append(bests, best)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+
+	sort.Slice(bests, func(i, j int) bool {
+		return bests[i].LevelID < bests[j].LevelID
+	})
+
+	return bests, nil
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func levelBestItemFor(t *testing.T, stars int) map[string]*dynamodb.AttributeValue {
+	t.Helper()
+	item, err := dynamodbattribute.MarshalMap(models.LevelBest{Username: "alice", LevelID: 2, MovesUsed: 20, Stars: stars})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+// Plan a replay of level 2 with 3 stars, paying 10 coins for each star above the previous best
+func starsReplay(previousStars int) LevelPlay {
+	return LevelPlay{
+		Username: "alice",
+		Reward: WalletTransaction{
+			TransactionID: LevelRewardTransactionID("alice", 2) + ":stars:3",
+			Counterparty:  models.SystemAccountRewards,
+			Type:          models.LedgerLevelReward,
+			Amount:        (3 - previousStars) * 10,
+		},
+		Best:          &models.LevelBest{Username: "alice", LevelID: 2, MovesUsed: 18, Stars: 3, CompletedAt: time.Now().UTC()},
+		PreviousStars: previousStars,
+	}
+}
+
+// Find the write of the best result among the writes of a transaction
+func bestPut(input *dynamodb.TransactWriteItemsInput) *dynamodb.Put {
+	for _, item := range input.TransactItems {
+		if item.Put != nil && *item.Put.TableName == "LevelBest" {
+			return item.Put
+		}
+	}
+	return nil
+}
+
+func TestPlayLevelPaysStarsOnceWhenTheBestChanges(t *testing.T) {
+	fake, repo := newFakeDynamoDB(t)
+	putUser(t, fake, "alice", 0)
+	fake.put("LevelBest", levelBestItemFor(t, 1))
+
+	// A 2-star replay commits first and raises the best result while the 3-star replay is written
+	raised := levelBestItemFor(t, 2)
+	fake.cancel = func(attempt int, input *dynamodb.TransactWriteItemsInput) []string {
+		fake.put("LevelBest", raised)
+		codes := make([]string, 0, len(input.TransactItems))
+		for _, item := range input.TransactItems {
+			if item.Put != nil && *item.Put.TableName == "LevelBest" {
+				codes = append(codes, "ConditionalCheckFailed")
+			} else {
+				codes = append(codes, "None")
+			}
+		}
+		return codes
+	}
+
+	_, err := repo.PlayLevel(starsReplay(1))
+	if err != ErrLevelBestChanged {
+		t.Fatalf("replay from a changed best = %v, want %v", err, ErrLevelBestChanged)
+	}
+	writes := fake.transactWrites()
+	if len(writes) != 1 {
+		t.Fatalf("%d transactions, want 1", len(writes))
+	}
+	put := bestPut(writes[0])
+	if put == nil || *put.ConditionExpression != "stars = :previousStars" || *put.ExpressionAttributeValues[":previousStars"].N != "1" {
+		t.Fatalf("best result write = %+v, want it conditioned on the 1 star it was paid from", put)
+	}
+
+	// Paid again from the new best, only the third star is paid
+	fake.cancel = nil
+	result, err := repo.PlayLevel(starsReplay(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Entry == nil || result.Entry.Amount != 10 || !result.NewBest {
+		t.Errorf("replay paid %+v with new best %v, want 10 coins and a new best", result.Entry, result.NewBest)
+	}
+	writes = fake.transactWrites()
+	if put := bestPut(writes[len(writes)-1]); put == nil || *put.ExpressionAttributeValues[":previousStars"].N != strconv.Itoa(2) {
+		t.Errorf("best result write = %+v, want it conditioned on 2 stars", put)
+	}
+}
+
+func TestPlayLevelWritesTheFirstBestOnlyIfThereIsNone(t *testing.T) {
+	fake, repo := newFakeDynamoDB(t)
+	putUser(t, fake, "alice", 0)
+
+	result, err := repo.PlayLevel(starsReplay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.NewBest || result.Entry.Amount != 30 {
+		t.Errorf("first best paid %d with new best %v, want 30 and a new best", result.Entry.Amount, result.NewBest)
+	}
+	put := bestPut(fake.transactWrites()[0])
+	if put == nil || *put.ConditionExpression != "attribute_not_exists(username)" {
+		t.Errorf("best result write = %+v, want it conditioned on there being no best", put)
+	}
+
+	// A concurrent replay with the same stars was paid first, this one only uses its life
+	result, err = repo.PlayLevel(starsReplay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.NewBest {
+		t.Error("a replay that was not paid wrote its best result")
+	}
+	writes := fake.transactWrites()
+	if last := writes[len(writes)-1]; bestPut(last) != nil || len(ledgerPuts(t, last)) != 0 {
+		t.Errorf("unpaid replay wrote %+v, want only its life", last.TransactItems)
+	}
+	if lives := fake.get("Lives", map[string]*dynamodb.AttributeValue{"username": {S: aws.String("alice")}}); lives == nil {
+		t.Error("the replays did not use lives")
+	}
+}
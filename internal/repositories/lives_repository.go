@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"cloudblast-backend/config"
+	"cloudblast-backend/internal/models"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Returned when a life is used by a player who has none left
+var ErrNoLives = errors.New("No lives left, wait for a refill or buy more lives")
+
+//LIVES
+// Get a user's stored lives
+// Returns nil if the user never used a life
+func (repo *DynamoDBRepository) GetLives(username string) (*models.Lives, error) {
+	result, err := repo.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("Lives"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil
+	}
+
+	var lives models.Lives
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &lives); err != nil {
+		return nil, err
+	}
+
+	return &lives, nil
+}
+
+// Get a user's lives as they are now, refills included
+func (repo *DynamoDBRepository) GetCurrentLives(username string) (models.Lives, error) {
+	stored, err := repo.GetLives(username)
+	if err != nil {
+		return models.Lives{}, err
+	}
+	return models.LivesAt(stored, username, time.Now().UTC(), config.LivesMax, config.LifeRefillInterval), nil
+}
+
+// Build the write using one of a user's lives
+// Returns the write and the lives left, or ErrNoLives if there are none
+func (repo *DynamoDBRepository) useLifeItem(username string) (*dynamodb.TransactWriteItem, *models.Lives, error) {
+	stored, err := repo.GetLives(username)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	lives := models.LivesAt(stored, username, now, config.LivesMax, config.LifeRefillInterval)
+	if lives.Lives <= 0 {
+		return nil, nil, ErrNoLives
+	}
+	lives.Lives--
+	lives.UpdatedAt = now
+
+	livesItem, err := livesPutItem(&lives, stored)
+	if err != nil {
+		return nil, nil, err
+	}
+	return livesItem, &lives, nil
+}
+
+// Buy lives with coins
+// The price is debited and the lives added in one transaction. Posting a purchase ID
+// that was already posted buys nothing and returns the ledger entry of the first purchase.
+func (repo *DynamoDBRepository) PurchaseLives(username string, quantity int, purchaseID string) (*models.LedgerEntry, error) {
+	return repo.PostWalletTransaction(WalletTransaction{
+		TransactionID: models.LedgerLivesPurchase + ":" + username + ":" + purchaseID,
+		Username:      username,
+		Counterparty:  models.SystemAccountStore,
+		Type:          models.LedgerLivesPurchase,
+		Amount:        -quantity * config.LifePrice,
+		prepare: func() ([]*dynamodb.TransactWriteItem, error) {
+			stored, err := repo.GetLives(username)
+			if err != nil {
+				return nil, err
+			}
+
+			now := time.Now().UTC()
+			lives := models.LivesAt(stored, username, now, config.LivesMax, config.LifeRefillInterval)
+			lives.Lives += quantity
+			lives.UpdatedAt = now
+
+			livesItem, err := livesPutItem(&lives, stored)
+			if err != nil {
+				return nil, err
+			}
+			return []*dynamodb.TransactWriteItem{livesItem}, nil
+		},
+	})
+}
+
+// Build the conditional write of a user's lives
+// The lives are only written if nobody changed them since they were read
+func livesPutItem(lives *models.Lives, stored *models.Lives) (*dynamodb.TransactWriteItem, error) {
+	av, err := dynamodbattribute.MarshalMap(lives)
+	if err != nil {
+		return nil, err
+	}
+
+	put := &dynamodb.Put{
+		TableName:           aws.String("Lives"),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(username)"),
+	}
+	if stored != nil {
+		updatedAt, err := dynamodbattribute.Marshal(stored.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		put.ConditionExpression = aws.String("updated_at = :updated_at")
+		put.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":updated_at": updatedAt,
+		}
+	}
+
+	return &dynamodb.TransactWriteItem{Put: put}, nil
+}
+
+// Delete a user's stored lives
+func (repo *DynamoDBRepository) DeleteLives(username string) error {
+	_, err := repo.client.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String("Lives"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+	})
+	return err
+}
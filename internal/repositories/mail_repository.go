@@ -0,0 +1,212 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Returned when a mail is claimed after it was claimed or expired
+var ErrMailNotClaimable = errors.New("Mail was already claimed or has expired")
+
+//MAIL
+// Put a mail in a user's mailbox
+// Returns false if a mail with the same ID was already sent to the user
+func (repo *DynamoDBRepository) SendMail(mail *models.Mail) (bool, error) {
+	item, err := dynamodbattribute.MarshalMap(mail)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = repo.client.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String("Mailbox"),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(mail_id)"),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Get a mail of a user
+// Returns nil if the user has no such mail
+func (repo *DynamoDBRepository) GetMail(username, mailID string) (*models.Mail, error) {
+	result, err := repo.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("Mailbox"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+			"mail_id":  {S: aws.String(mailID)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil
+	}
+
+	var mail models.Mail
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &mail); err != nil {
+		return nil, err
+	}
+
+	return &mail, nil
+}
+
+// Get every mail in a user's mailbox, expired and claimed mail included until it is deleted
+func (repo *DynamoDBRepository) GetMailbox(username string) ([]models.Mail, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("Mailbox"),
+		KeyConditionExpression: aws.String("username = :username"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+	}
+
+	mailbox := make([]models.Mail, 0)
+	var unmarshalErr error
+	err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var mail models.Mail
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &mail); unmarshalErr != nil {
+				return false
+			}
+			mailbox = append(mailbox, mail)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+
+	return mailbox, nil
+}
+
+// Claim a mail, paying each of its rewards
+// Every reward is paid under its own transaction ID, and all of them are paid in one transaction
+// together with the mail being marked claimed, so a claim pays everything or nothing and never pays twice.
+// Returns the ledger entries of the rewards
+func (repo *DynamoDBRepository) ClaimMail(mail *models.Mail, claimedAt time.Time) ([]models.LedgerEntry, error) {
+	markClaimed := func() ([]*dynamodb.TransactWriteItem, error) {
+		current, err := repo.GetMail(mail.Username, mail.MailID)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil || current.ClaimedAt != nil || current.IsExpired(claimedAt) {
+			return nil, ErrMailNotClaimable
+		}
+
+		claimed, err := dynamodbattribute.Marshal(claimedAt.UTC())
+		if err != nil {
+			return nil, err
+		}
+		return []*dynamodb.TransactWriteItem{{
+			Update: &dynamodb.Update{
+				TableName: aws.String("Mailbox"),
+				Key: map[string]*dynamodb.AttributeValue{
+					"username": {S: aws.String(mail.Username)},
+					"mail_id":  {S: aws.String(mail.MailID)},
+				},
+				UpdateExpression:    aws.String("SET claimed_at = :claimedAt"),
+				ConditionExpression: aws.String("attribute_exists(mail_id) AND attribute_not_exists(claimed_at)"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":claimedAt": claimed,
+				},
+			},
+		}}, nil
+	}
+
+	// Mail without rewards is only marked claimed
+	if len(mail.Rewards) == 0 {
+		items, err := markClaimed()
+		if err != nil {
+			return nil, err
+		}
+		_, err = repo.client.UpdateItem(&dynamodb.UpdateItemInput{
+			TableName:                 items[0].Update.TableName,
+			Key:                       items[0].Update.Key,
+			UpdateExpression:          items[0].Update.UpdateExpression,
+			ConditionExpression:       items[0].Update.ConditionExpression,
+			ExpressionAttributeValues: items[0].Update.ExpressionAttributeValues,
+		})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil, ErrMailNotClaimable
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []models.LedgerEntry{}, nil
+	}
+
+	counterparty := models.SystemAccountRewards
+	if mail.Source == models.MailSourceAdmin {
+		counterparty = models.SystemAccountAdmin
+	}
+
+	transactions := make([]WalletTransaction, 0, len(mail.Rewards))
+	for _, reward := range mail.Rewards {
+		transactions = append(transactions, WalletTransaction{
+			TransactionID: MailRewardTransactionID(mail.Username, mail.MailID, reward.Currency),
+			Username:      mail.Username,
+			Currency:      reward.Currency,
+			Counterparty:  counterparty,
+			Type:          models.LedgerMailReward,
+			Amount:        reward.Amount,
+			Reference:     mail.MailID,
+			CreatedBy:     mail.SentBy,
+		})
+	}
+	transactions[len(transactions)-1].prepare = markClaimed
+
+	posted, _, err := repo.postWalletTransactions(transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.LedgerEntry, 0, len(posted))
+	for _, entry := range posted {
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
+
+// Get the ID of the transaction paying a reward of a mail
+func MailRewardTransactionID(username, mailID, currency string) string {
+	return models.LedgerMailReward + ":" + username + ":" + mailID + ":" + currency
+}
+
+// Delete every mail of a user
+func (repo *DynamoDBRepository) DeleteMailbox(username string) error {
+	mailbox, err := repo.GetMailbox(username)
+	if err != nil {
+		return err
+	}
+
+	for _, mail := range mailbox {
+		_, err := repo.client.DeleteItem(&dynamodb.DeleteItemInput{
+			TableName: aws.String("Mailbox"),
+			Key: map[string]*dynamodb.AttributeValue{
+				"username": {S: aws.String(username)},
+				"mail_id":  {S: aws.String(mail.MailID)},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Put a mail with coins and gems in alice's mailbox
+func putRewardMail(t *testing.T, fake *fakeDynamoDB, claimedAt *time.Time) *models.Mail {
+	t.Helper()
+	now := time.Now().UTC()
+	mail := &models.Mail{
+		Username:  "alice",
+		MailID:    "launch-gift",
+		Source:    models.MailSourceAdmin,
+		Title:     "Welcome",
+		Rewards:   []models.MailReward{{Currency: "coins", Amount: 100}, {Currency: "gems", Amount: 5}},
+		SentBy:    "support",
+		SentAt:    now.Add(-time.Hour),
+		ExpiresAt: now.Add(time.Hour),
+		ClaimedAt: claimedAt,
+	}
+	item, err := dynamodbattribute.MarshalMap(mail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.put("Mailbox", item)
+	return mail
+}
+
+// Get the ledger entry alice has for a reward of the mail, nil if it was not paid
+func paidReward(fake *fakeDynamoDB, mail *models.Mail, currency string) map[string]*dynamodb.AttributeValue {
+	return fake.get("WalletLedger", map[string]*dynamodb.AttributeValue{
+		"transaction_id": {S: aws.String(MailRewardTransactionID(mail.Username, mail.MailID, currency))},
+		"account":        {S: aws.String(mail.Username)},
+	})
+}
+
+func TestClaimMailPaysEveryRewardWithTheClaim(t *testing.T) {
+	fake, repo := newFakeDynamoDB(t)
+	putUser(t, fake, "alice", 20)
+	mail := putRewardMail(t, fake, nil)
+
+	entries, err := repo.ClaimMail(mail, time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Currency != "coins" || entries[0].Balance != 120 || entries[1].Currency != "gems" || entries[1].Balance != 5 {
+		t.Errorf("entries = %+v, want 120 coins and 5 gems", entries)
+	}
+	for _, entry := range entries {
+		if entry.Counterparty != models.SystemAccountAdmin || entry.Type != models.LedgerMailReward {
+			t.Errorf("%s paid by %s as %s, want %s as %s", entry.Currency, entry.Counterparty, entry.Type, models.SystemAccountAdmin, models.LedgerMailReward)
+		}
+	}
+
+	writes := fake.transactWrites()
+	if len(writes) != 1 {
+		t.Fatalf("claim made %d transactions, want 1", len(writes))
+	}
+	claims := findUpdate(writes[0], "Mailbox")
+	if len(claims) != 1 || *claims[0].ConditionExpression != "attribute_exists(mail_id) AND attribute_not_exists(claimed_at)" {
+		t.Fatalf("claim updates = %+v, want the mail marked claimed on the condition it was not", claims)
+	}
+	if len(findUpdate(writes[0], "User")) != 1 || len(findUpdate(writes[0], "WalletBalance")) != 1 {
+		t.Error("claim does not update both balances")
+	}
+	if len(ledgerPuts(t, writes[0])) != 4 {
+		t.Errorf("claim writes %d ledger entries, want both sides of both rewards", len(ledgerPuts(t, writes[0])))
+	}
+}
+
+func TestClaimMailPaysNothingWhenClaimedConcurrently(t *testing.T) {
+	fake, repo := newFakeDynamoDB(t)
+	putUser(t, fake, "alice", 20)
+	mail := putRewardMail(t, fake, nil)
+
+	// The other claim commits first, this one fails on the mail and finds it claimed when it retries
+	claimedAt := time.Now().UTC()
+	claimed := *mail
+	claimed.ClaimedAt = &claimedAt
+	claimedItem, err := dynamodbattribute.MarshalMap(claimed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.cancel = func(attempt int, input *dynamodb.TransactWriteItemsInput) []string {
+		fake.put("Mailbox", claimedItem)
+		codes := make([]string, 0, len(input.TransactItems))
+		for _, item := range input.TransactItems {
+			if item.Update != nil && *item.Update.TableName == "Mailbox" {
+				codes = append(codes, "ConditionalCheckFailed")
+			} else {
+				codes = append(codes, "None")
+			}
+		}
+		return codes
+	}
+
+	_, err = repo.ClaimMail(mail, time.Now().UTC())
+	if err != ErrMailNotClaimable {
+		t.Fatalf("claim = %v, want %v", err, ErrMailNotClaimable)
+	}
+	if attempts := len(fake.transactWrites()); attempts != 1 {
+		t.Errorf("claim made %d attempts, want 1", attempts)
+	}
+	for _, currency := range []string{"coins", "gems"} {
+		if paidReward(fake, mail, currency) != nil {
+			t.Errorf("%s were paid for a mail claimed elsewhere", currency)
+		}
+	}
+}
+
+func TestClaimMailRejectsClaimedAndExpiredMail(t *testing.T) {
+	claimedAt := time.Now().UTC().Add(-time.Minute)
+
+	t.Run("claimed", func(t *testing.T) {
+		fake, repo := newFakeDynamoDB(t)
+		putUser(t, fake, "alice", 20)
+		mail := putRewardMail(t, fake, &claimedAt)
+
+		if _, err := repo.ClaimMail(mail, time.Now().UTC()); err != ErrMailNotClaimable {
+			t.Errorf("claim = %v, want %v", err, ErrMailNotClaimable)
+		}
+		if len(fake.transactWrites()) != 0 {
+			t.Error("claiming a claimed mail wrote a transaction")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		fake, repo := newFakeDynamoDB(t)
+		putUser(t, fake, "alice", 20)
+		mail := putRewardMail(t, fake, nil)
+
+		if _, err := repo.ClaimMail(mail, mail.ExpiresAt); err != ErrMailNotClaimable {
+			t.Errorf("claim = %v, want %v", err, ErrMailNotClaimable)
+		}
+		if len(fake.transactWrites()) != 0 {
+			t.Error("claiming an expired mail wrote a transaction")
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+//MODERATION
+// Set a user's moderation state, suspendedUntil is only kept for suspensions
+func (repo *DynamoDBRepository) UpdateUserModeration(username string, status string, suspendedUntil *time.Time) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String("User"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":status": {S: aws.String(status)},
+		},
+		UpdateExpression:    aws.String("SET moderation_status = :status REMOVE suspended_until"),
+		ConditionExpression: aws.String("attribute_exists(username)"),
+	}
+	if suspendedUntil != nil {
+		until, err := dynamodbattribute.Marshal(suspendedUntil.UTC())
+		if err != nil {
+			return err
+		}
+		input.ExpressionAttributeValues[":until"] = until
+		input.UpdateExpression = aws.String("SET moderation_status = :status, suspended_until = :until")
+	}
+
+	_, err := repo.client.UpdateItem(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// Get the users that are currently banned, shadow-banned or suspended
+func (repo *DynamoDBRepository) GetSanctionedUsernames() (map[string]bool, error) {
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String("User"),
+		FilterExpression:     aws.String("attribute_exists(moderation_status) AND moderation_status <> :active"),
+		ProjectionExpression: aws.String("username, moderation_status, suspended_until"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":active": {S: aws.String(models.ModerationActive)},
+		},
+	}
+
+	now := time.Now()
+	sanctioned := make(map[string]bool)
+	err := repo.client.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var user models.User
+			if err := dynamodbattribute.UnmarshalMap(item, &user); err != nil {
+				continue
+			}
+			if user.IsSanctionedAt(now) {
+				sanctioned[user.Username] = true
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sanctioned, nil
+}
+
+// Add an entry to the moderation audit trail
+func (repo *DynamoDBRepository) CreateModerationAction(action *models.ModerationAction) error {
+	av, err := dynamodbattribute.MarshalMap(action)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName:           aws.String("ModerationAction"),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(action_id)"),
+	}
+
+	_, err = repo.client.PutItem(input)
+	return err
+}
+
+// Get the moderation actions taken on a user, newest first
+func (repo *DynamoDBRepository) GetModerationActions(username string, limit int64) ([]models.ModerationAction, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("ModerationAction"),
+		KeyConditionExpression: aws.String("username = :username"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int64(limit),
+	}
+
+	result, err := repo.client.Query(input)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]models.ModerationAction, 0, len(result.Items))
+	for _, item := range result.Items {
+		var action models.ModerationAction
+		if err := dynamodbattribute.UnmarshalMap(item, &action); err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
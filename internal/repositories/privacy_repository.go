@@ -0,0 +1,513 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Tables keyed by username and a sort key, with the name of the sort key
+// Rows of the first group are deleted with the account, those of the second are kept under the deleted user's alias
+var (
+	erasedUserTables = map[string]string{
+		"Inventory":       "item_id",
+		"LevelCompletion": "completion_id",
+		"LevelBest":       "level_id",
+		"SecurityEvent":   "event_id",
+		"WalletBalance":   "currency",
+		"Session":         "session_id",
+		"Friendship":      "friend",
+	}
+	anonymizedUserTables = map[string]string{
+		"Quarantine":       "tournament_id",
+		"ModerationAction": "action_id",
+	}
+)
+
+//PRIVACY
+// Record a login session
+func (repo *DynamoDBRepository) CreateSession(session *models.Session) error {
+	av, err := dynamodbattribute.MarshalMap(session)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String("Session"),
+		Item:      av,
+	}
+
+	_, err = repo.client.PutItem(input)
+	return err
+}
+
+// Get a user's login sessions, oldest first
+func (repo *DynamoDBRepository) GetSessions(username string) ([]models.Session, error) {
+	items, err := repo.queryUserItems("Session", username)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]models.Session, 0, len(items))
+	for _, item := range items {
+		var session models.Session
+		if err := dynamodbattribute.UnmarshalMap(item, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+
+	return sessions, nil
+}
+
+// Get a user's records of the given tournaments, in the order of the tournaments
+// Tournaments the user did not take part in are left out
+func (repo *DynamoDBRepository) GetUserTournamentHistory(username string, tournamentIDs []string) ([]models.UserInTournament, error) {
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(tournamentIDs))
+	for _, tournamentID := range tournamentIDs {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{
+			"username":      {S: aws.String(username)},
+			"tournament_id": {S: aws.String(tournamentID)},
+		})
+	}
+
+	found := make(map[string]models.UserInTournament)
+	err := repo.batchGet("UserInTournament", keys, &dynamodb.KeysAndAttributes{}, func(item map[string]*dynamodb.AttributeValue) error {
+		var userInTournament models.UserInTournament
+		if err := dynamodbattribute.UnmarshalMap(item, &userInTournament); err != nil {
+			return err
+		}
+		found[userInTournament.TournamentID] = userInTournament
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]models.UserInTournament, 0, len(found))
+	for _, tournamentID := range tournamentIDs {
+		if userInTournament, ok := found[tournamentID]; ok {
+			history = append(history, userInTournament)
+		}
+	}
+
+	return history, nil
+}
+
+// Schedule a user's account to be erased, replacing the user with an alias in the results that outlive it
+// Returns ErrUserNotFound if the user does not exist
+func (repo *DynamoDBRepository) ScheduleUserDeletion(username string, at time.Time, alias string) error {
+	scheduledAt, err := dynamodbattribute.Marshal(at.UTC())
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String("User"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":at":    scheduledAt,
+			":alias": {S: aws.String(alias)},
+		},
+		UpdateExpression:    aws.String("SET deletion_scheduled_at = :at, deletion_alias = :alias"),
+		ConditionExpression: aws.String("attribute_exists(username)"),
+	}
+
+	_, err = repo.client.UpdateItem(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// Cancel a scheduled account deletion
+// Returns false if no deletion was scheduled
+func (repo *DynamoDBRepository) CancelUserDeletion(username string) (bool, error) {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String("User"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+		UpdateExpression:    aws.String("REMOVE deletion_scheduled_at"),
+		ConditionExpression: aws.String("attribute_exists(deletion_scheduled_at)"),
+	}
+
+	_, err := repo.client.UpdateItem(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Get the users whose deletion grace period ended
+func (repo *DynamoDBRepository) GetUsersDueForDeletion(now time.Time) ([]models.User, error) {
+	cutoff, err := dynamodbattribute.Marshal(now.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String("User"),
+		FilterExpression: aws.String("deletion_scheduled_at <= :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": cutoff,
+		},
+	}
+
+	users := make([]models.User, 0)
+	var unmarshalErr error
+	err = repo.client.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var user models.User
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &user); unmarshalErr != nil {
+				return false
+			}
+			users = append(users, user)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+
+	return users, nil
+}
+
+// Delete a user's record of a tournament
+func (repo *DynamoDBRepository) DeleteUserInTournament(username, tournamentID string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String("UserInTournament"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username":      {S: aws.String(username)},
+			"tournament_id": {S: aws.String(tournamentID)},
+		},
+	}
+
+	_, err := repo.client.DeleteItem(input)
+	return err
+}
+
+// Replace a user with an alias in their records of the given tournaments
+func (repo *DynamoDBRepository) AnonymizeTournamentHistory(username, alias string, tournamentIDs []string) error {
+	history, err := repo.GetUserTournamentHistory(username, tournamentIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, userInTournament := range history {
+		item, err := dynamodbattribute.MarshalMap(userInTournament)
+		if err != nil {
+			return err
+		}
+		err = repo.rekeyItem("UserInTournament", item, map[string]string{"username": alias})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replace a user with an alias in their archived season standings
+func (repo *DynamoDBRepository) AnonymizeSeasonStandings(username, alias string) error {
+	seasons, err := repo.GetAllSeasons()
+	if err != nil {
+		return err
+	}
+
+	for _, season := range seasons {
+		standing, err := repo.GetSeasonStanding(season.SeasonID, username)
+		if err != nil {
+			return err
+		}
+		if standing == nil {
+			continue
+		}
+		item, err := dynamodbattribute.MarshalMap(standing)
+		if err != nil {
+			return err
+		}
+		err = repo.rekeyItem("SeasonStanding", item, map[string]string{"username": alias})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replace a user with an alias in the hall of fame
+func (repo *DynamoDBRepository) AnonymizeHallOfFame(username, alias string) error {
+	entries, err := repo.GetHallOfFame()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Username != username {
+			continue
+		}
+		entry.Username = alias
+		err := repo.CreateHallOfFameEntry(&entry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replace a user with an alias in both sides of their wallet transactions
+// The ledger keeps summing to zero, only the name of the account changes
+func (repo *DynamoDBRepository) AnonymizeLedger(username, alias string) error {
+	// Read the whole history first, the index changes as entries are moved
+	entries := make([]map[string]*dynamodb.AttributeValue, 0)
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("WalletLedger"),
+		IndexName:              aws.String("account-created_at-index"),
+		KeyConditionExpression: aws.String("account = :account"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":account": {S: aws.String(username)},
+		},
+	}
+	err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		entries = append(entries, page.Items...)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range entries {
+		var entry models.LedgerEntry
+		if err := dynamodbattribute.UnmarshalMap(item, &entry); err != nil {
+			return err
+		}
+		transactionID := anonymizeTransactionID(entry.TransactionID, username, alias)
+
+		writes := make([]*dynamodb.TransactWriteItem, 0, 4)
+		playerWrites, err := rekeyWrites("WalletLedger", item, map[string]string{
+			"transaction_id": transactionID,
+			"account":        alias,
+		})
+		if err != nil {
+			return err
+		}
+		writes = append(writes, playerWrites...)
+
+		counterparty, err := repo.getLedgerItem(entry.TransactionID, entry.Counterparty)
+		if err != nil {
+			return err
+		}
+		if counterparty != nil {
+			counterpartyWrites, err := rekeyWrites("WalletLedger", counterparty, map[string]string{
+				"transaction_id": transactionID,
+				"counterparty":   alias,
+			})
+			if err != nil {
+				return err
+			}
+			writes = append(writes, counterpartyWrites...)
+		}
+
+		_, err = repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: writes})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get one side of a ledger transaction as it is stored
+func (repo *DynamoDBRepository) getLedgerItem(transactionID, account string) (map[string]*dynamodb.AttributeValue, error) {
+	result, err := repo.client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("WalletLedger"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"transaction_id": {S: aws.String(transactionID)},
+			"account":        {S: aws.String(account)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Item, nil
+}
+
+// Replace a username with an alias in the ":" separated parts of a transaction ID
+// IDs of requests made by support staff do not hold the username and are kept as they are
+func anonymizeTransactionID(transactionID, username, alias string) string {
+	parts := strings.Split(transactionID, ":")
+	for i, part := range parts {
+		if part == username {
+			parts[i] = alias
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// Delete a user's rows from the tables that do not outlive the account,
+// and move the audit records to the user's alias
+func (repo *DynamoDBRepository) EraseUserRecords(username, alias string) error {
+	for table, sortKey := range erasedUserTables {
+		items, err := repo.queryUserItems(table, username)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			_, err := repo.client.DeleteItem(&dynamodb.DeleteItemInput{
+				TableName: aws.String(table),
+				Key: map[string]*dynamodb.AttributeValue{
+					"username": item["username"],
+					sortKey:    item[sortKey],
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for table := range anonymizedUserTables {
+		items, err := repo.queryUserItems(table, username)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			err := repo.rekeyItem(table, item, map[string]string{"username": alias})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Delete a user
+func (repo *DynamoDBRepository) DeleteUser(username string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String("User"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+	}
+
+	_, err := repo.client.DeleteItem(input)
+	return err
+}
+
+// Get all rows of a table keyed by username that belong to a user
+func (repo *DynamoDBRepository) queryUserItems(table, username string) ([]map[string]*dynamodb.AttributeValue, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(table),
+		KeyConditionExpression: aws.String("username = :username"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+	}
+
+	items := make([]map[string]*dynamodb.AttributeValue, 0)
+	err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		items = append(items, page.Items...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Move a row to a new key by writing the changed row and deleting the old one together
+func (repo *DynamoDBRepository) rekeyItem(table string, item map[string]*dynamodb.AttributeValue, changes map[string]string) error {
+	writes, err := rekeyWrites(table, item, changes)
+	if err != nil {
+		return err
+	}
+	_, err = repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: writes})
+	return err
+}
+
+// Build the writes that change string attributes of a row, deleting the old row if a key attribute changed
+func rekeyWrites(table string, item map[string]*dynamodb.AttributeValue, changes map[string]string) ([]*dynamodb.TransactWriteItem, error) {
+	oldKey, err := tableKey(table, item)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, value := range item {
+		changed[name] = value
+	}
+	for name, value := range changes {
+		changed[name] = &dynamodb.AttributeValue{S: aws.String(value)}
+	}
+	newKey, err := tableKey(table, changed)
+	if err != nil {
+		return nil, err
+	}
+
+	writes := []*dynamodb.TransactWriteItem{
+		{Put: &dynamodb.Put{TableName: aws.String(table), Item: changed}},
+	}
+	if !sameKey(oldKey, newKey) {
+		writes = append(writes, &dynamodb.TransactWriteItem{
+			Delete: &dynamodb.Delete{TableName: aws.String(table), Key: oldKey},
+		})
+	}
+	return writes, nil
+}
+
+// Get the key attributes of a row
+func tableKey(table string, item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	var names []string
+	switch table {
+	case "WalletLedger":
+		names = []string{"transaction_id", "account"}
+	case "UserInTournament":
+		names = []string{"username", "tournament_id"}
+	case "SeasonStanding":
+		names = []string{"season_id", "username"}
+	default:
+		sortKey, ok := anonymizedUserTables[table]
+		if !ok {
+			sortKey, ok = erasedUserTables[table]
+		}
+		if !ok {
+			return nil, errors.New("no key is known for table " + table)
+		}
+		names = []string{"username", sortKey}
+	}
+
+	key := make(map[string]*dynamodb.AttributeValue, len(names))
+	for _, name := range names {
+		key[name] = item[name]
+	}
+	return key, nil
+}
+
+// Check whether two keys name the same row
+func sameKey(a, b map[string]*dynamodb.AttributeValue) bool {
+	for name, value := range a {
+		if value.String() != b[name].String() {
+			return false
+		}
+	}
+	return true
+}
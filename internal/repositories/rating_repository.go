@@ -0,0 +1,202 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+//RATING
+// Get the skill ratings of many users at once
+// Users who were never rated, or do not exist, are left out
+func (repo *DynamoDBRepository) GetSkillRatings(usernames []string) (map[string]*models.SkillRating, error) {
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(usernames))
+	for _, username := range usernames {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		})
+	}
+
+	projection := &dynamodb.KeysAndAttributes{
+		ProjectionExpression: aws.String("username, skill_rating"),
+	}
+
+	ratings := make(map[string]*models.SkillRating, len(usernames))
+	err := repo.batchGet("User", keys, projection, func(item map[string]*dynamodb.AttributeValue) error {
+		var user models.User
+		if err := dynamodbattribute.UnmarshalMap(item, &user); err != nil {
+			return err
+		}
+		if user.Skill_Rating != nil {
+			ratings[user.Username] = user.Skill_Rating
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ratings, nil
+}
+
+// Set a user's skill rating
+// Returns false if the user does not exist
+func (repo *DynamoDBRepository) SetSkillRating(username string, rating *models.SkillRating) (bool, error) {
+	value, err := dynamodbattribute.Marshal(rating)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = repo.client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String("User"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+		UpdateExpression:    aws.String("SET skill_rating = :rating"),
+		ConditionExpression: aws.String("attribute_exists(username)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":rating": value,
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Store the ratings of a group's players after a tournament together with their history entries, in one transaction
+// Users that do not exist anymore, like erased accounts, are left out. Returns false if the group was already rated
+func (repo *DynamoDBRepository) RecordGroupRatings(entries []models.RatingHistoryEntry, ratings map[string]*models.SkillRating) (bool, error) {
+	for len(entries) > 0 {
+		items := make([]*dynamodb.TransactWriteItem, 0, 2*len(entries))
+		for i := range entries {
+			rating, err := dynamodbattribute.Marshal(ratings[entries[i].Username])
+			if err != nil {
+				return false, err
+			}
+			historyItem, err := dynamodbattribute.MarshalMap(entries[i])
+			if err != nil {
+				return false, err
+			}
+
+			items = append(items, &dynamodb.TransactWriteItem{
+				Update: &dynamodb.Update{
+					TableName: aws.String("User"),
+					Key: map[string]*dynamodb.AttributeValue{
+						"username": {S: aws.String(entries[i].Username)},
+					},
+					UpdateExpression:    aws.String("SET skill_rating = :rating"),
+					ConditionExpression: aws.String("attribute_exists(username)"),
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+						":rating": rating,
+					},
+				},
+			}, &dynamodb.TransactWriteItem{
+				Put: &dynamodb.Put{
+					TableName:           aws.String("RatingHistory"),
+					Item:                historyItem,
+					ConditionExpression: aws.String("attribute_not_exists(username)"),
+				},
+			})
+		}
+
+		_, err := repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: items})
+		canceled, ok := err.(*dynamodb.TransactionCanceledException)
+		if !ok {
+			if err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+
+		// Stop if the group was rated meanwhile, otherwise try again without the users that do not exist
+		remaining := make([]models.RatingHistoryEntry, 0, len(entries))
+		for i, entry := range entries {
+			if conditionFailed(canceled, 2*i+1) {
+				return false, nil
+			}
+			if !conditionFailed(canceled, 2*i) {
+				remaining = append(remaining, entry)
+			}
+		}
+		if len(remaining) == len(entries) {
+			return false, err
+		}
+		entries = remaining
+	}
+	return true, nil
+}
+
+// Store a user's rating after a tournament, replacing the one stored before for the same tournament
+func (repo *DynamoDBRepository) PutRatingHistoryEntry(entry *models.RatingHistoryEntry) error {
+	item, err := dynamodbattribute.MarshalMap(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.client.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String("RatingHistory"),
+		Item:      item,
+	})
+	return err
+}
+
+// Get a user's rating after each of their rated tournaments
+func (repo *DynamoDBRepository) GetRatingHistory(username string) ([]models.RatingHistoryEntry, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("RatingHistory"),
+		KeyConditionExpression: aws.String("username = :username"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+	}
+
+	entries := make([]models.RatingHistoryEntry, 0)
+	var unmarshalErr error
+	err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var entry models.RatingHistoryEntry
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &entry); unmarshalErr != nil {
+				return false
+			}
+			entries = append(entries, entry)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+
+	return entries, nil
+}
+
+// Delete a user's rating history
+func (repo *DynamoDBRepository) DeleteRatingHistory(username string) error {
+	entries, err := repo.GetRatingHistory(username)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		_, err := repo.client.DeleteItem(&dynamodb.DeleteItemInput{
+			TableName: aws.String("RatingHistory"),
+			Key: map[string]*dynamodb.AttributeValue{
+				"username":      {S: aws.String(username)},
+				"tournament_id": {S: aws.String(entry.TournamentID)},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package repositories
 import (
 	"context"
 	"log"
+	"strconv"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 )
@@ -34,15 +36,17 @@ func (rr *RedisRepo) Close() error {
 	return rr.client.Close()
 }
 
-// Clear all keys in the database
+// Clear all group leaderboards of a tournament
+// Only the "<tournament_id>:<group_id>" keys are removed so that long-lived leaderboards survive
 func (rr *RedisRepo) DeleteLeaderboards(leaderboardName string) error {
-
-	status := rr.client.FlushAll(rr.ctx)
-	if status.Err() != nil {
-		return status.Err()
+	iter := rr.client.Scan(rr.ctx, 0, leaderboardName+":*", 100).Iterator()
+	for iter.Next(rr.ctx) {
+		if err := rr.client.Del(rr.ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return iter.Err()
 }
 
 // Add a user's score to the leaderboard
@@ -111,23 +115,281 @@ func (rr *RedisRepo) GetGroupUserRank(leaderboardName string, username string) (
 	return rank + 1, nil
 }
 
-// Get the full leaderboard with scores and ranks
+// Get the full leaderboard of a group with scores and ranks
 func (rr *RedisRepo) GetGroupLeaderboardWithRanks(leaderboardName string, start, stop int64) ([]map[string]interface{}, error) {
-	leaderboardKey := leaderboardName
-	zRange, err := rr.client.ZRevRangeWithScores(rr.ctx, leaderboardKey, start, stop).Result()
+	return rr.GetLeaderboardWithRanks(leaderboardName, start, stop)
+}
+
+// Increment a member's score in a leaderboard by a given amount
+func (rr *RedisRepo) IncrementLeaderboardScore(leaderboardKey string, member string, amount int) error {
+	return rr.client.ZIncrBy(rr.ctx, leaderboardKey, float64(amount), member).Err()
+}
+
+// Increment a member's score in several leaderboards and add the member to a set, all in one transaction
+func (rr *RedisRepo) IncrementLeaderboardScoresAndAddToSet(increments map[string]int, member string, setKey string) error {
+	_, err := rr.client.TxPipelined(rr.ctx, func(pipe redis.Pipeliner) error {
+		for leaderboardKey, amount := range increments {
+			pipe.ZIncrBy(rr.ctx, leaderboardKey, float64(amount), member)
+		}
+		pipe.SAdd(rr.ctx, setKey, member)
+		return nil
+	})
+	return err
+}
+
+// Add a member to a set
+// Returns true if the member was not in the set before
+func (rr *RedisRepo) AddToSet(setKey string, member string) (bool, error) {
+	added, err := rr.client.SAdd(rr.ctx, setKey, member).Result()
 	if err != nil {
-		return nil, err
+		return false, err
 	}
+	return added == 1, nil
+}
 
-	// Convert the leaderboard to a slice of maps
-	leaderboard := make([]map[string]interface{}, len(zRange))
-	for i, z := range zRange {
-		leaderboard[i] = map[string]interface{}{
-			"rank":   i + 1,
-			"member": z.Member,
-			"score":  int(z.Score),
+// Check whether a key exists
+func (rr *RedisRepo) KeyExists(key string) (bool, error) {
+	count, err := rr.client.Exists(rr.ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Set a key without expiration
+func (rr *RedisRepo) SetFlag(key string) error {
+	return rr.client.Set(rr.ctx, key, "1", 0).Err()
+}
+
+// Get a member's score and rank in a leaderboard
+// Returns a rank of -1 if the member is not in the leaderboard
+func (rr *RedisRepo) GetMemberScoreAndRank(leaderboardKey string, member string) (int, int64, error) {
+	score, err := rr.client.ZScore(rr.ctx, leaderboardKey, member).Result()
+	if err == redis.Nil {
+		return 0, -1, nil
+	}
+	if err != nil {
+		return 0, -1, err
+	}
+
+	rank, err := rr.client.ZRevRank(rr.ctx, leaderboardKey, member).Result()
+	if err != nil {
+		return 0, -1, err
+	}
+
+	return int(score), rank + 1, nil
+}
+
+// Acquire a lock shared by all backend instances
+// Returns false if another instance holds the lock
+func (rr *RedisRepo) AcquireLock(lockKey string, ttl time.Duration) (bool, error) {
+	return rr.client.SetNX(rr.ctx, lockKey, "1", ttl).Result()
+}
+
+// Set a value that expires after the given TTL, unless the key already exists
+// Returns false if the key already exists
+func (rr *RedisRepo) SetValueIfNotExists(key string, value []byte, ttl time.Duration) (bool, error) {
+	return rr.client.SetNX(rr.ctx, key, value, ttl).Result()
+}
+
+// Set a value that expires after the given TTL
+func (rr *RedisRepo) SetValue(key string, value []byte, ttl time.Duration) error {
+	return rr.client.Set(rr.ctx, key, value, ttl).Err()
+}
+
+// Get a value
+// Returns nil if the key does not exist
+func (rr *RedisRepo) GetValue(key string) ([]byte, error) {
+	value, err := rr.client.Get(rr.ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return value, err
+}
+
+// Delete the given keys
+func (rr *RedisRepo) DeleteKeys(keys ...string) error {
+	return rr.client.Del(rr.ctx, keys...).Err()
+}
+
+// Check whether a member is in a set
+func (rr *RedisRepo) IsSetMember(setKey string, member string) (bool, error) {
+	return rr.client.SIsMember(rr.ctx, setKey, member).Result()
+}
+
+// Remove a member from a set
+func (rr *RedisRepo) RemoveFromSet(setKey string, member string) error {
+	return rr.client.SRem(rr.ctx, setKey, member).Err()
+}
+
+// Remove a member from a leaderboard
+func (rr *RedisRepo) RemoveLeaderboardMember(leaderboardKey string, member string) error {
+	return rr.client.ZRem(rr.ctx, leaderboardKey, member).Err()
+}
+
+// Move a member and its score from one leaderboard to another
+// Scores the member already has in the destination leaderboard are added up
+func (rr *RedisRepo) MoveLeaderboardMember(fromKey string, toKey string, member string) error {
+	score, err := rr.client.ZScore(rr.ctx, fromKey, member).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = rr.client.TxPipelined(rr.ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZIncrBy(rr.ctx, toKey, score, member)
+		pipe.ZRem(rr.ctx, fromKey, member)
+		return nil
+	})
+	return err
+}
+
+// Count the members of a leaderboard with a higher score than the given one
+func (rr *RedisRepo) CountLeaderboardMembersAbove(leaderboardKey string, score int) (int64, error) {
+	return rr.client.ZCount(rr.ctx, leaderboardKey, "("+strconv.Itoa(score), "+inf").Result()
+}
+
+// Add an event to a sliding window, dropping the events older than the window
+func (rr *RedisRepo) AddToSlidingWindow(windowKey string, member string, at time.Time, window time.Duration) error {
+	_, err := rr.client.TxPipelined(rr.ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(rr.ctx, windowKey, "-inf", "("+strconv.FormatInt(at.Add(-window).UnixMilli(), 10))
+		pipe.ZAdd(rr.ctx, windowKey, &redis.Z{
+			Score:  float64(at.UnixMilli()),
+			Member: member,
+		})
+		pipe.Expire(rr.ctx, windowKey, window)
+		return nil
+	})
+	return err
+}
+
+// Remove an event from a sliding window
+func (rr *RedisRepo) RemoveFromSlidingWindow(windowKey string, member string) error {
+	return rr.client.ZRem(rr.ctx, windowKey, member).Err()
+}
+
+// Get the events of several sliding windows that happened since the given time, oldest first
+func (rr *RedisRepo) GetSlidingWindows(windowKeys []string, since time.Time) ([][]string, error) {
+	cmds := make([]*redis.StringSliceCmd, len(windowKeys))
+	_, err := rr.client.Pipelined(rr.ctx, func(pipe redis.Pipeliner) error {
+		for i, windowKey := range windowKeys {
+			cmds[i] = pipe.ZRangeByScore(rr.ctx, windowKey, &redis.ZRangeBy{
+				Min: strconv.FormatInt(since.UnixMilli(), 10),
+				Max: "+inf",
+			})
 		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
 	}
 
-	return leaderboard, nil
-}
\ No newline at end of file
+	windows := make([][]string, len(windowKeys))
+	for i, cmd := range cmds {
+		windows[i] = cmd.Val()
+	}
+	return windows, nil
+}
+
+// StreamEntry is an entry of a Redis stream holding a single JSON payload
+type StreamEntry struct {
+	ID   string
+	Data []byte
+}
+
+// Append a JSON payload to a stream, keeping roughly the latest maxLen entries
+// Returns the ID of the new entry
+func (rr *RedisRepo) AppendToStream(streamKey string, maxLen int64, data []byte) (string, error) {
+	return rr.client.XAdd(rr.ctx, &redis.XAddArgs{
+		Stream: streamKey,
+		MaxLen: maxLen,
+		Approx: true,
+		ID:     "*",
+		Values: map[string]interface{}{"data": data},
+	}).Result()
+}
+
+// Read the entries added to a stream after the given ID, waiting up to block for new ones
+// Returns no entries and no error if nothing was added in time
+func (rr *RedisRepo) ReadStream(streamKey string, afterID string, count int64, block time.Duration) ([]StreamEntry, error) {
+	streams, err := rr.client.XRead(rr.ctx, &redis.XReadArgs{
+		Streams: []string{streamKey, afterID},
+		Count:   count,
+		Block:   block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]StreamEntry, 0)
+	for _, stream := range streams {
+		entries = append(entries, toStreamEntries(stream.Messages)...)
+	}
+	return entries, nil
+}
+
+// Get up to count entries of a stream starting at the given ID, oldest first
+func (rr *RedisRepo) GetStreamRange(streamKey string, fromID string, count int64) ([]StreamEntry, error) {
+	messages, err := rr.client.XRangeN(rr.ctx, streamKey, fromID, "+", count).Result()
+	if err != nil {
+		return nil, err
+	}
+	return toStreamEntries(messages), nil
+}
+
+// Get the ID of the newest entry in a stream
+// Returns "0" if the stream is empty
+func (rr *RedisRepo) GetLastStreamID(streamKey string) (string, error) {
+	messages, err := rr.client.XRevRangeN(rr.ctx, streamKey, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return "0", nil
+	}
+	return messages[0].ID, nil
+}
+
+// Convert Redis stream messages to stream entries
+func toStreamEntries(messages []redis.XMessage) []StreamEntry {
+	entries := make([]StreamEntry, 0, len(messages))
+	for _, message := range messages {
+		data, _ := message.Values["data"].(string)
+		entries = append(entries, StreamEntry{ID: message.ID, Data: []byte(data)})
+	}
+	return entries
+}
+
+// Rename a member of a leaderboard, keeping its score
+func (rr *RedisRepo) RenameLeaderboardMember(leaderboardKey string, member string, newMember string) error {
+	score, err := rr.client.ZScore(rr.ctx, leaderboardKey, member).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = rr.client.TxPipelined(rr.ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZIncrBy(rr.ctx, leaderboardKey, score, newMember)
+		pipe.ZRem(rr.ctx, leaderboardKey, member)
+		return nil
+	})
+	return err
+}
+
+// Make the given keys expire after the given TTL
+func (rr *RedisRepo) ExpireKeys(ttl time.Duration, keys ...string) error {
+	_, err := rr.client.TxPipelined(rr.ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Expire(rr.ctx, key, ttl)
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+//SEASON
+//Create or overwrite a season given a season struct
+func (repo *DynamoDBRepository) CreateSeason(season *models.Season) error {
+	av, err := dynamodbattribute.MarshalMap(season)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String("Season"),
+		Item:      av,
+	}
+
+	_, err = repo.client.PutItem(input)
+	return err
+}
+
+// Get a season by season ID
+// Returns nil if the season has not been archived
+func (repo *DynamoDBRepository) GetSeasonByID(seasonID string) (*models.Season, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String("Season"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"season_id": {S: aws.String(seasonID)},
+		},
+	}
+
+	result, err := repo.client.GetItem(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil // Season not found
+	}
+
+	var season models.Season
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &season); err != nil {
+		return nil, err
+	}
+
+	return &season, nil
+}
+
+// Get all archived seasons, most recent first
+func (repo *DynamoDBRepository) GetAllSeasons() ([]models.Season, error) {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String("Season"),
+	}
+
+	result, err := repo.client.Scan(input)
+	if err != nil {
+		return nil, err
+	}
+
+	seasons := make([]models.Season, 0)
+	for _, item := range result.Items {
+		var season models.Season
+		err := dynamodbattribute.UnmarshalMap(item, &season)
+		if err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, season)
+	}
+
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].Number > seasons[j].Number
+	})
+
+	return seasons, nil
+}
+
+// Archive a user's final standing in a season
+func (repo *DynamoDBRepository) CreateSeasonStanding(standing *models.SeasonStanding) error {
+	av, err := dynamodbattribute.MarshalMap(standing)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String("SeasonStanding"),
+		Item:      av,
+	}
+
+	_, err = repo.client.PutItem(input)
+	return err
+}
+
+// Get a user's archived standing in a season
+// Returns nil if the user did not take part in the season
+func (repo *DynamoDBRepository) GetSeasonStanding(seasonID, username string) (*models.SeasonStanding, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String("SeasonStanding"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"season_id": {S: aws.String(seasonID)},
+			"username":  {S: aws.String(username)},
+		},
+	}
+
+	result, err := repo.client.GetItem(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil
+	}
+
+	var standing models.SeasonStanding
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &standing); err != nil {
+		return nil, err
+	}
+
+	return &standing, nil
+}
+
+// Get all archived standings of a season
+func (repo *DynamoDBRepository) GetSeasonStandings(seasonID string) ([]models.SeasonStanding, error) {
+	standings := make([]models.SeasonStanding, 0)
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("SeasonStanding"),
+		KeyConditionExpression: aws.String("season_id = :season_id"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":season_id": {S: aws.String(seasonID)},
+		},
+	}
+
+	var unmarshalErr error
+	err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var standing models.SeasonStanding
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &standing); unmarshalErr != nil {
+				return false
+			}
+			standings = append(standings, standing)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+
+	return standings, nil
+}
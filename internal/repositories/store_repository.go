@@ -0,0 +1,283 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Returned when the stock of an item is sold out
+var ErrOutOfStock = errors.New("Item is out of stock")
+
+// Returned when a unique item is bought again
+var ErrAlreadyOwned = errors.New("Item is already owned")
+
+// Returned when more units of an item are consumed than the user holds
+var ErrNotEnoughItems = errors.New("Not enough items in inventory")
+
+//STORE
+// Buy a store item
+// The price is debited and the item granted in one transaction. Posting a purchase ID
+// that was already posted buys nothing and returns the ledger entry of the first purchase.
+func (repo *DynamoDBRepository) PurchaseStoreItem(username string, item models.StoreItem, purchaseID string) (*models.LedgerEntry, *models.InventoryItem, error) {
+	entry, err := repo.PostWalletTransaction(WalletTransaction{
+		TransactionID: models.LedgerStorePurchase + ":" + username + ":" + purchaseID,
+		Username:      username,
+		Currency:      item.Currency,
+		Counterparty:  models.SystemAccountStore,
+		Type:          models.LedgerStorePurchase,
+		Amount:        -item.Price,
+		Reference:     item.ItemID,
+		prepare: func() ([]*dynamodb.TransactWriteItem, error) {
+			now := time.Now().UTC()
+			items := make([]*dynamodb.TransactWriteItem, 0, 2)
+
+			// Take one from the stock of limited items
+			if item.Stock > 0 {
+				sold, err := repo.getStoreItemSold(item.ItemID)
+				if err != nil {
+					return nil, err
+				}
+				if sold >= item.Stock {
+					return nil, ErrOutOfStock
+				}
+				items = append(items, &dynamodb.TransactWriteItem{
+					Update: &dynamodb.Update{
+						TableName: aws.String("StoreStock"),
+						Key: map[string]*dynamodb.AttributeValue{
+							"item_id": {S: aws.String(item.ItemID)},
+						},
+						ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+							":zero":  {N: aws.String("0")},
+							":one":   {N: aws.String("1")},
+							":stock": {N: aws.String(strconv.Itoa(item.Stock))},
+						},
+						UpdateExpression:    aws.String("SET sold = if_not_exists(sold, :zero) + :one"),
+						ConditionExpression: aws.String("attribute_not_exists(sold) OR sold < :stock"),
+					},
+				})
+			}
+
+			// Add the units to the inventory, replacing expired ones
+			existing, err := repo.GetInventoryItem(username, item.ItemID)
+			if err != nil {
+				return nil, err
+			}
+			held := 0
+			var expiresAt *time.Time
+			if existing != nil && !inventoryItemExpired(existing, now) {
+				held = existing.Quantity
+				expiresAt = existing.ExpiresAt
+			}
+			if item.Unique && held > 0 {
+				return nil, ErrAlreadyOwned
+			}
+			if item.ExpiresAfterHours > 0 {
+				newExpiresAt := now.Add(time.Duration(item.ExpiresAfterHours) * time.Hour)
+				if expiresAt == nil || newExpiresAt.After(*expiresAt) {
+					expiresAt = &newExpiresAt
+				}
+			}
+
+			granted := &models.InventoryItem{
+				Username:  username,
+				ItemID:    item.ItemID,
+				Type:      item.Type,
+				Quantity:  held + item.Quantity,
+				ExpiresAt: expiresAt,
+				UpdatedAt: now,
+			}
+			inventoryItem, err := inventoryPutItem(granted, existing)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, inventoryItem)
+
+			return items, nil
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Report the holding as it is now, also when the purchase was posted before
+	inventoryItem, err := repo.GetInventoryItem(username, item.ItemID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return entry, inventoryItem, nil
+}
+
+// Use up units of an inventory item
+// Returns the remaining holding
+func (repo *DynamoDBRepository) ConsumeInventoryItem(username, itemID string, quantity int) (*models.InventoryItem, error) {
+	for attempt := 0; attempt < maxLedgerAttempts; attempt++ {
+		existing, err := repo.GetInventoryItem(username, itemID)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		if existing == nil || inventoryItemExpired(existing, now) || existing.Quantity < quantity {
+			return nil, ErrNotEnoughItems
+		}
+
+		remaining := *existing
+		remaining.Quantity -= quantity
+		remaining.UpdatedAt = now
+
+		inventoryItem, err := inventoryPutItem(&remaining, existing)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = repo.client.PutItem(&dynamodb.PutItemInput{
+			TableName:                 inventoryItem.Put.TableName,
+			Item:                      inventoryItem.Put.Item,
+			ConditionExpression:       inventoryItem.Put.ConditionExpression,
+			ExpressionAttributeValues: inventoryItem.Put.ExpressionAttributeValues,
+		})
+		if err == nil {
+			return &remaining, nil
+		}
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil, err
+		}
+	}
+
+	return nil, errors.New("Inventory changed too often, item not consumed")
+}
+
+// Build the conditional write of an inventory holding
+// The holding is only written if nobody changed it since it was read
+func inventoryPutItem(item *models.InventoryItem, existing *models.InventoryItem) (*dynamodb.TransactWriteItem, error) {
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return nil, err
+	}
+
+	put := &dynamodb.Put{
+		TableName:           aws.String("Inventory"),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(item_id)"),
+	}
+	if existing != nil {
+		updatedAt, err := dynamodbattribute.Marshal(existing.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		put.ConditionExpression = aws.String("updated_at = :updated_at")
+		put.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":updated_at": updatedAt,
+		}
+	}
+
+	return &dynamodb.TransactWriteItem{Put: put}, nil
+}
+
+// Check whether the units of an inventory holding have expired
+func inventoryItemExpired(item *models.InventoryItem, now time.Time) bool {
+	return item.ExpiresAt != nil && !now.Before(*item.ExpiresAt)
+}
+
+// Get a user's holding of an item
+// Returns nil if the user never had the item
+func (repo *DynamoDBRepository) GetInventoryItem(username, itemID string) (*models.InventoryItem, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String("Inventory"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+			"item_id":  {S: aws.String(itemID)},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	result, err := repo.client.GetItem(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil // Item not in inventory
+	}
+
+	var item models.InventoryItem
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// Get the items a user holds, leaving out used up and expired ones
+func (repo *DynamoDBRepository) GetInventory(username string) ([]models.InventoryItem, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("Inventory"),
+		KeyConditionExpression: aws.String("username = :username"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+	}
+
+	now := time.Now().UTC()
+	inventory := make([]models.InventoryItem, 0)
+	err := repo.client.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, av := range page.Items {
+			var item models.InventoryItem
+			if err := dynamodbattribute.UnmarshalMap(av, &item); err != nil {
+				continue
+			}
+			if item.Quantity > 0 && !inventoryItemExpired(&item, now) {
+				inventory = append(inventory, item)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return inventory, nil
+}
+
+// Get the number of units of a limited item sold so far
+func (repo *DynamoDBRepository) getStoreItemSold(itemID string) (int, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String("StoreStock"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"item_id": {S: aws.String(itemID)},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	result, err := repo.client.GetItem(input)
+	if err != nil {
+		return 0, err
+	}
+
+	if result == nil || result.Item == nil || result.Item["sold"] == nil || result.Item["sold"].N == nil {
+		return 0, nil
+	}
+
+	return strconv.Atoi(*result.Item["sold"].N)
+}
+
+// Get the remaining stock of a limited item
+func (repo *DynamoDBRepository) GetStoreItemRemainingStock(item models.StoreItem) (int, error) {
+	sold, err := repo.getStoreItemSold(item.ItemID)
+	if err != nil {
+		return 0, err
+	}
+
+	if sold >= item.Stock {
+		return 0, nil
+	}
+	return item.Stock - sold, nil
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+//USERNAME
+// Claim the skeleton of a username, so that no other username that looks the same can be taken
+// Returns false if the skeleton is already claimed
+func (repo *DynamoDBRepository) ClaimUsernameSkeleton(skeleton string, username string) (bool, error) {
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String("UsernameClaim"),
+		Item: map[string]*dynamodb.AttributeValue{
+			"skeleton":   {S: aws.String(skeleton)},
+			"username":   {S: aws.String(username)},
+			"claimed_at": {S: aws.String(time.Now().UTC().Format(time.RFC3339Nano))},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(skeleton)"),
+	}
+
+	_, err := repo.client.PutItem(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Get the username that claimed a skeleton
+// Returns an empty string if the skeleton is not claimed
+func (repo *DynamoDBRepository) GetUsernameSkeletonOwner(skeleton string) (string, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String("UsernameClaim"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"skeleton": {S: aws.String(skeleton)},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	result, err := repo.client.GetItem(input)
+	if err != nil {
+		return "", err
+	}
+
+	if result.Item == nil || result.Item["username"] == nil || result.Item["username"].S == nil {
+		return "", nil
+	}
+	return *result.Item["username"].S, nil
+}
+
+// Release the skeleton a username claimed
+func (repo *DynamoDBRepository) ReleaseUsernameSkeleton(skeleton string, username string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String("UsernameClaim"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"skeleton": {S: aws.String(skeleton)},
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+		ConditionExpression: aws.String("username = :username"),
+	}
+
+	_, err := repo.client.DeleteItem(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil
+	}
+	return err
+}
+
+// Call a function for every user, reading the table a page at a time
+// Stops at the first error the function returns
+func (repo *DynamoDBRepository) ForEachUser(fn func(user models.User) error) error {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String("User"),
+	}
+
+	var fnErr error
+	err := repo.client.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var user models.User
+			if fnErr = dynamodbattribute.UnmarshalMap(item, &user); fnErr != nil {
+				return false
+			}
+			if fnErr = fn(user); fnErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// A change to a user's profile, fields that are nil are kept
+// The country and timezone can each change at most once per cooldown period.
+type ProfileUpdate struct {
+	DisplayName      *string
+	AvatarID         *string
+	FrameID          *string
+	Country          *string
+	CountryCooldown  time.Duration
+	Timezone         *string
+	TimezoneCooldown time.Duration
+}
+
+// Write a change to a user's profile in one update, so that it is either made in full or not at all
+// The country and timezone change from the ones of the given user. Returns false if either changed
+// since, or changed within its cooldown.
+func (repo *DynamoDBRepository) UpdateUserProfile(user *models.User, update ProfileUpdate) (bool, error) {
+	now := time.Now().UTC()
+	changedAt, err := dynamodbattribute.Marshal(now)
+	if err != nil {
+		return false, err
+	}
+
+	sets := []string{}
+	conditions := []string{"attribute_exists(username)"}
+	names := map[string]*string{}
+	values := map[string]*dynamodb.AttributeValue{}
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"display_name", update.DisplayName},
+		{"avatar_id", update.AvatarID},
+		{"frame_id", update.FrameID},
+	}
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		sets = append(sets, f.name+" = :"+f.name)
+		values[":"+f.name] = &dynamodb.AttributeValue{S: aws.String(*f.value)}
+	}
+
+	// Timezone is a reserved word, so every attribute that changes with a cooldown goes by a name
+	cooldowns := []struct {
+		name     string
+		from     string
+		to       *string
+		cooldown time.Duration
+	}{
+		{"country", user.Country, update.Country, update.CountryCooldown},
+		{"timezone", user.Timezone, update.Timezone, update.TimezoneCooldown},
+	}
+	for _, c := range cooldowns {
+		if c.to == nil {
+			continue
+		}
+		cutoff, err := dynamodbattribute.Marshal(now.Add(-c.cooldown))
+		if err != nil {
+			return false, err
+		}
+		names["#"+c.name] = aws.String(c.name)
+		names["#"+c.name+"_changed_at"] = aws.String(c.name + "_changed_at")
+		values[":"+c.name] = &dynamodb.AttributeValue{S: aws.String(*c.to)}
+		values[":"+c.name+"_from"] = &dynamodb.AttributeValue{S: aws.String(c.from)}
+		values[":"+c.name+"_cutoff"] = cutoff
+		values[":changed_at"] = changedAt
+		sets = append(sets, "#"+c.name+" = :"+c.name, "#"+c.name+"_changed_at = :changed_at")
+		conditions = append(conditions,
+			"(#"+c.name+" = :"+c.name+"_from OR attribute_not_exists(#"+c.name+"))",
+			"(attribute_not_exists(#"+c.name+"_changed_at) OR #"+c.name+"_changed_at <= :"+c.name+"_cutoff)")
+	}
+	if len(sets) == 0 {
+		return true, nil
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String("User"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(user.Username)},
+		},
+		ExpressionAttributeValues: values,
+		UpdateExpression:          aws.String("SET " + strings.Join(sets, ", ")),
+		ConditionExpression:       aws.String(strings.Join(conditions, " AND ")),
+	}
+	if len(names) > 0 {
+		input.ExpressionAttributeNames = names
+	}
+
+	_, err = repo.client.UpdateItem(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
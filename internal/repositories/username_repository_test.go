@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestUpdateUserProfileWritesEveryChangeInOneUpdate(t *testing.T) {
+	fake, repo := newFakeDynamoDB(t)
+	user := &models.User{Username: "alice", Country: "DE", Timezone: "Europe/Berlin"}
+	displayName, country, timezone, frame := "Alice", "FR", "Europe/Paris", ""
+
+	updated, err := repo.UpdateUserProfile(user, ProfileUpdate{
+		DisplayName:      &displayName,
+		Country:          &country,
+		CountryCooldown:  30 * 24 * time.Hour,
+		Timezone:         &timezone,
+		TimezoneCooldown: 7 * 24 * time.Hour,
+		FrameID:          &frame,
+	})
+	if err != nil || !updated {
+		t.Fatalf("UpdateUserProfile() = %v, %v, want the profile updated", updated, err)
+	}
+
+	updates := fake.updateItems()
+	if len(updates) != 1 {
+		t.Fatalf("%d updates, want 1", len(updates))
+	}
+	update := updates[0]
+	for _, set := range []string{"display_name = :display_name", "#country = :country", "#country_changed_at = :changed_at", "#timezone = :timezone", "#timezone_changed_at = :changed_at", "frame_id = :frame_id"} {
+		if !strings.Contains(*update.UpdateExpression, set) {
+			t.Errorf("update %q does not set %q", *update.UpdateExpression, set)
+		}
+	}
+	if strings.Contains(*update.UpdateExpression, "avatar_id") {
+		t.Errorf("update %q changes the avatar that was kept", *update.UpdateExpression)
+	}
+	for _, condition := range []string{"attribute_exists(username)", "#country = :country_from", "#country_changed_at <= :country_cutoff", "#timezone = :timezone_from", "#timezone_changed_at <= :timezone_cutoff"} {
+		if !strings.Contains(*update.ConditionExpression, condition) {
+			t.Errorf("condition %q does not check %q", *update.ConditionExpression, condition)
+		}
+	}
+	if *update.ExpressionAttributeValues[":country_from"].S != "DE" || *update.ExpressionAttributeValues[":timezone_from"].S != "Europe/Berlin" {
+		t.Error("the country and timezone are not changed from the ones that were read")
+	}
+}
+
+func TestUpdateUserProfileChangesNothingWhenTheConditionFails(t *testing.T) {
+	fake, repo := newFakeDynamoDB(t)
+	user := &models.User{Username: "alice", Country: "DE"}
+	displayName, country := "Alice", "FR"
+
+	// The country changed within its cooldown, so the display name is not written either
+	fake.failUpdate = func(input *dynamodb.UpdateItemInput) bool { return true }
+	updated, err := repo.UpdateUserProfile(user, ProfileUpdate{DisplayName: &displayName, Country: &country, CountryCooldown: time.Hour})
+	if err != nil || updated {
+		t.Errorf("UpdateUserProfile() = %v, %v, want nothing updated", updated, err)
+	}
+
+	// A profile without changes is not written
+	updated, err = repo.UpdateUserProfile(user, ProfileUpdate{})
+	if err != nil || !updated {
+		t.Errorf("UpdateUserProfile() without changes = %v, %v, want true", updated, err)
+	}
+	if len(fake.updateItems()) != 1 {
+		t.Errorf("%d updates, want only the one that failed", len(fake.updateItems()))
+	}
+}
@@ -0,0 +1,562 @@
+package repositories
+
+import (
+	"cloudblast-backend/config"
+	"cloudblast-backend/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Returned when a debit would make a balance that cannot go negative negative
+var ErrInsufficientFunds = errors.New("Not enough funds")
+
+// Returned when a transaction is posted for a user that does not exist
+var ErrUserNotFound = errors.New("User not found")
+
+// Returned when a transaction is posted in a currency that does not exist
+var ErrUnknownCurrency = errors.New("Unknown currency")
+
+// Returned when the rules of a currency do not allow a transaction
+var ErrCurrencyNotAllowed = errors.New("Currency cannot be used for this transaction")
+
+// Returned when an expiring currency is credited without an expiry time
+var ErrMissingExpiry = errors.New("Expiring currency credited without an expiry time")
+
+// Number of times a transaction is retried when the balance changes concurrently
+const maxLedgerAttempts = 5
+
+// WalletTransaction describes a change to a player's balance in a currency
+type WalletTransaction struct {
+	// Unique ID of the transaction, posting the same ID again has no effect
+	TransactionID string
+	Username      string
+	// Defaults to coins
+	Currency     string
+	Counterparty string
+	Type         string
+	Amount       int
+	// Time the credited balance expires, for expiring currencies
+	ExpiresAt time.Time
+	Reference string
+	Reason    string
+	CreatedBy string
+	// Live event that multiplied the amount
+	EventID string
+	// Builds writes to other tables that must happen together with the transaction,
+	// called again on every attempt so that it can re-read what it depends on
+	prepare func() ([]*dynamodb.TransactWriteItem, error)
+}
+
+//WALLET
+// Post a wallet transaction
+// The player's balance is updated atomically together with both sides of the ledger entry.
+// Posting a transaction ID that was already posted returns the entry recorded the first time.
+func (repo *DynamoDBRepository) PostWalletTransaction(transaction WalletTransaction) (*models.LedgerEntry, error) {
+	entry, _, err := repo.postWalletTransaction(transaction)
+	return entry, err
+}
+
+// Post a wallet transaction
+// Also returns whether this call posted it, false if it was already posted
+func (repo *DynamoDBRepository) postWalletTransaction(transaction WalletTransaction) (*models.LedgerEntry, bool, error) {
+	entries, posted, err := repo.postWalletTransactions([]WalletTransaction{transaction})
+	if err != nil {
+		return nil, false, err
+	}
+	return entries[0], posted, nil
+}
+
+// Post wallet transactions of one player together, either all of them are posted or none
+// Each transaction must be in a different currency. Transactions that were already posted are left out,
+// with their prepare. Returns the ledger entries in the order of the transactions, and whether this call
+// posted any of them
+func (repo *DynamoDBRepository) postWalletTransactions(transactions []WalletTransaction) ([]*models.LedgerEntry, bool, error) {
+	currencies := make([]config.Currency, len(transactions))
+	for i := range transactions {
+		transaction := &transactions[i]
+		if transaction.Currency == "" {
+			transaction.Currency = config.DefaultCurrency
+		}
+		currency, ok := config.CurrencyByName(transaction.Currency)
+		if !ok {
+			return nil, false, ErrUnknownCurrency
+		}
+		if !currency.Allows(transaction.Type, transaction.Amount) {
+			return nil, false, ErrCurrencyNotAllowed
+		}
+		if currency.Expires && transaction.Amount > 0 && transaction.ExpiresAt.IsZero() {
+			if currency.Lifetime <= 0 {
+				return nil, false, ErrMissingExpiry
+			}
+			transaction.ExpiresAt = time.Now().UTC().Add(currency.Lifetime)
+		}
+		currencies[i] = currency
+	}
+
+	for attempt := 0; attempt < maxLedgerAttempts; attempt++ {
+		entries := make([]*models.LedgerEntry, len(transactions))
+		items := make([]*dynamodb.TransactWriteItem, 0, 5*len(transactions))
+		balanceItems := make([]*dynamodb.TransactWriteItem, 0, len(transactions))
+		extraItems := make([]*dynamodb.TransactWriteItem, 0)
+		for i, transaction := range transactions {
+			existingEntry, err := repo.GetLedgerEntry(transaction.TransactionID, transaction.Username)
+			if err != nil {
+				return nil, false, err
+			}
+			if existingEntry != nil {
+				entries[i] = existingEntry
+				continue
+			}
+
+			balanceItem, transactionItems, entry, err := repo.walletTransactionItems(transaction, currencies[i])
+			if err != nil {
+				return nil, false, err
+			}
+			entries[i] = entry
+			items = append(items, transactionItems...)
+			balanceItems = append(balanceItems, balanceItem)
+
+			if transaction.prepare != nil {
+				prepared, err := transaction.prepare()
+				if err != nil {
+					return nil, false, err
+				}
+				extraItems = append(extraItems, prepared...)
+			}
+		}
+		if len(balanceItems) == 0 {
+			return entries, false, nil
+		}
+
+		// A transaction writes each item once, so updates of the user are made together with their coins
+		for _, balanceItem := range balanceItems {
+			var err error
+			extraItems, err = foldUserUpdates(balanceItem, extraItems)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		items = append(items, extraItems...)
+
+		_, err := repo.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: items})
+		if err == nil {
+			return entries, true, nil
+		}
+		if !isRetryableTransactionError(err) {
+			return nil, false, err
+		}
+	}
+
+	return nil, false, errors.New("Balance changed too often, transaction not posted")
+}
+
+// Build the writes of a wallet transaction that was not posted yet
+// Returns the write of the new balance, every write of the transaction with it, and the player's ledger entry
+func (repo *DynamoDBRepository) walletTransactionItems(transaction WalletTransaction, currency config.Currency) (*dynamodb.TransactWriteItem, []*dynamodb.TransactWriteItem, *models.LedgerEntry, error) {
+	balance, err := repo.getStoredBalance(transaction.Username, currency.Name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	items := make([]*dynamodb.TransactWriteItem, 0, 5)
+
+	// An expired balance is written off before the transaction is applied
+	currentBalance := balance.Balance
+	var expiresAt time.Time
+	if balance.ExpiresAt != nil {
+		expiresAt = *balance.ExpiresAt
+	}
+	expired := currency.Expires && !expiresAt.IsZero() && !now.Before(expiresAt)
+	if expired && currentBalance != 0 {
+		expiryItems, err := ledgerEntryItems(models.LedgerEntry{
+			TransactionID: models.LedgerExpiry + ":" + currency.Name + ":" + transaction.Username + ":" + strconv.FormatInt(expiresAt.Unix(), 10),
+			Account:       transaction.Username,
+			Currency:      currency.Name,
+			Counterparty:  models.SystemAccountExpiry,
+			Type:          models.LedgerExpiry,
+			Amount:        -currentBalance,
+			Balance:       0,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		items = append(items, expiryItems...)
+		currentBalance = 0
+	}
+
+	newBalance := currentBalance + transaction.Amount
+	if newBalance < 0 && transaction.Amount < 0 && !currency.AllowNegative {
+		return nil, nil, nil, ErrInsufficientFunds
+	}
+
+	// Credits keep the balance alive until the latest expiry they carry
+	newExpiresAt := expiresAt
+	if currency.Expires && (expired || currentBalance == 0 || transaction.ExpiresAt.After(expiresAt)) && !transaction.ExpiresAt.IsZero() {
+		newExpiresAt = transaction.ExpiresAt.UTC()
+	}
+
+	playerEntry := models.LedgerEntry{
+		TransactionID: transaction.TransactionID,
+		Account:       transaction.Username,
+		Currency:      currency.Name,
+		Counterparty:  transaction.Counterparty,
+		Type:          transaction.Type,
+		Amount:        transaction.Amount,
+		Balance:       newBalance,
+		Reference:     transaction.Reference,
+		Reason:        transaction.Reason,
+		CreatedBy:     transaction.CreatedBy,
+		EventID:       transaction.EventID,
+		CreatedAt:     now,
+	}
+	entryItems, err := ledgerEntryItems(playerEntry)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The balance is only written if nobody changed it since it was read,
+	// and the entries only if the transaction was not posted in the meantime
+	balanceItem, err := balanceUpdateItem(balance, newBalance, newExpiresAt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	items = append([]*dynamodb.TransactWriteItem{balanceItem}, append(items, entryItems...)...)
+
+	return balanceItem, items, &playerEntry, nil
+}
+
+// Build the writes of both sides of a ledger entry
+func ledgerEntryItems(playerEntry models.LedgerEntry) ([]*dynamodb.TransactWriteItem, error) {
+	systemEntry := playerEntry
+	systemEntry.Account = playerEntry.Counterparty
+	systemEntry.Counterparty = playerEntry.Account
+	systemEntry.Amount = -playerEntry.Amount
+	systemEntry.Balance = 0
+
+	items := make([]*dynamodb.TransactWriteItem, 0, 2)
+	for _, entry := range []models.LedgerEntry{playerEntry, systemEntry} {
+		item, err := dynamodbattribute.MarshalMap(entry)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{
+				TableName:           aws.String("WalletLedger"),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(transaction_id)"),
+			},
+		})
+	}
+
+	return items, nil
+}
+
+// Build the conditional write of a new balance
+// Coins are kept on the user so that the existing coin endpoints keep working,
+// every other currency has its own item in the WalletBalance table
+func balanceUpdateItem(balance *models.WalletBalance, newBalance int, expiresAt time.Time) (*dynamodb.TransactWriteItem, error) {
+	if balance.Currency == config.DefaultCurrency {
+		return &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				TableName: aws.String("User"),
+				Key: map[string]*dynamodb.AttributeValue{
+					"username": {S: aws.String(balance.Username)},
+				},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":old": {N: aws.String(strconv.Itoa(balance.Balance))},
+					":new": {N: aws.String(strconv.Itoa(newBalance))},
+				},
+				UpdateExpression:    aws.String("SET coins = :new"),
+				ConditionExpression: aws.String("coins = :old"),
+			},
+		}, nil
+	}
+
+	values := map[string]*dynamodb.AttributeValue{
+		":new": {N: aws.String(strconv.Itoa(newBalance))},
+	}
+	updateExpression := "SET balance = :new"
+	if !expiresAt.IsZero() {
+		newExpiry, err := dynamodbattribute.Marshal(expiresAt)
+		if err != nil {
+			return nil, err
+		}
+		values[":expires_at"] = newExpiry
+		updateExpression += ", expires_at = :expires_at"
+	}
+
+	// Nobody may have created, changed or extended the balance since it was read
+	values[":old"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(balance.Balance))}
+	conditionExpression := "(attribute_not_exists(balance) OR balance = :old)"
+	if balance.ExpiresAt == nil {
+		conditionExpression += " AND attribute_not_exists(expires_at)"
+	} else {
+		oldExpiry, err := dynamodbattribute.Marshal(*balance.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		values[":old_expires_at"] = oldExpiry
+		conditionExpression += " AND expires_at = :old_expires_at"
+	}
+
+	return &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName: aws.String("WalletBalance"),
+			Key: map[string]*dynamodb.AttributeValue{
+				"username": {S: aws.String(balance.Username)},
+				"currency": {S: aws.String(balance.Currency)},
+			},
+			ExpressionAttributeValues: values,
+			UpdateExpression:          aws.String(updateExpression),
+			ConditionExpression:       aws.String(conditionExpression),
+		},
+	}, nil
+}
+
+// Move the updates of the user's item among the extra writes of a transaction into the update of their coins
+// Only SET updates can be moved, the writes left are returned
+func foldUserUpdates(balanceItem *dynamodb.TransactWriteItem, items []*dynamodb.TransactWriteItem) ([]*dynamodb.TransactWriteItem, error) {
+	balanceUpdate := balanceItem.Update
+	if balanceUpdate == nil || *balanceUpdate.TableName != "User" {
+		return items, nil
+	}
+
+	remaining := make([]*dynamodb.TransactWriteItem, 0, len(items))
+	for _, item := range items {
+		update := item.Update
+		if update == nil || *update.TableName != "User" || *update.Key["username"].S != *balanceUpdate.Key["username"].S {
+			remaining = append(remaining, item)
+			continue
+		}
+		if !strings.HasPrefix(*update.UpdateExpression, "SET ") {
+			return nil, errors.New("Only SET updates of a user can be written with their coins")
+		}
+
+		balanceUpdate.UpdateExpression = aws.String(*balanceUpdate.UpdateExpression + ", " + strings.TrimPrefix(*update.UpdateExpression, "SET "))
+		if update.ConditionExpression != nil {
+			balanceUpdate.ConditionExpression = aws.String("(" + *balanceUpdate.ConditionExpression + ") AND (" + *update.ConditionExpression + ")")
+		}
+		for name, value := range update.ExpressionAttributeValues {
+			if _, taken := balanceUpdate.ExpressionAttributeValues[name]; taken {
+				return nil, errors.New("Attribute value " + name + " is already used by the coins update")
+			}
+			balanceUpdate.ExpressionAttributeValues[name] = value
+		}
+		for name, attribute := range update.ExpressionAttributeNames {
+			if balanceUpdate.ExpressionAttributeNames == nil {
+				balanceUpdate.ExpressionAttributeNames = make(map[string]*string)
+			}
+			if _, taken := balanceUpdate.ExpressionAttributeNames[name]; taken {
+				return nil, errors.New("Attribute name " + name + " is already used by the coins update")
+			}
+			balanceUpdate.ExpressionAttributeNames[name] = attribute
+		}
+	}
+
+	return remaining, nil
+}
+
+// Get a user's balance in a currency as it is stored, ignoring expiry
+func (repo *DynamoDBRepository) getStoredBalance(username, currency string) (*models.WalletBalance, error) {
+	user, err := repo.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if currency == config.DefaultCurrency {
+		return &models.WalletBalance{Username: username, Currency: currency, Balance: user.Coins}, nil
+	}
+
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String("WalletBalance"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+			"currency": {S: aws.String(currency)},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	result, err := repo.client.GetItem(input)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := models.WalletBalance{Username: username, Currency: currency}
+	if result != nil && result.Item != nil {
+		if err := dynamodbattribute.UnmarshalMap(result.Item, &balance); err != nil {
+			return nil, err
+		}
+	}
+
+	return &balance, nil
+}
+
+// Get a user's balances in every currency
+// Expired balances are reported as zero
+func (repo *DynamoDBRepository) GetWalletBalances(username string) ([]models.WalletBalance, error) {
+	now := time.Now().UTC()
+	balances := make([]models.WalletBalance, 0, len(config.Currencies))
+	for _, currency := range config.Currencies {
+		balance, err := repo.getStoredBalance(username, currency.Name)
+		if err != nil {
+			return nil, err
+		}
+		if currency.Expires && balance.ExpiresAt != nil && !now.Before(*balance.ExpiresAt) {
+			balance.Balance = 0
+			balance.ExpiresAt = nil
+		}
+		balances = append(balances, *balance)
+	}
+
+	return balances, nil
+}
+
+// Check whether a transaction failed because of a concurrent change
+// The next attempt re-reads the balance, or returns the entry if the transaction was posted meanwhile
+func isRetryableTransactionError(err error) bool {
+	if canceled, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		for _, reason := range canceled.CancellationReasons {
+			if reason.Code == nil || *reason.Code == "None" {
+				continue
+			}
+			if *reason.Code != "ConditionalCheckFailed" && *reason.Code != "TransactionConflict" {
+				return false
+			}
+		}
+		return true
+	}
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeTransactionConflictException {
+		return true
+	}
+	return false
+}
+
+// Get one side of a ledger transaction
+// Returns nil if the transaction was not posted
+func (repo *DynamoDBRepository) GetLedgerEntry(transactionID, account string) (*models.LedgerEntry, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String("WalletLedger"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"transaction_id": {S: aws.String(transactionID)},
+			"account":        {S: aws.String(account)},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	result, err := repo.client.GetItem(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Item == nil {
+		return nil, nil // Transaction not posted
+	}
+
+	var entry models.LedgerEntry
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Currency == "" {
+		entry.Currency = config.DefaultCurrency // Posted before there were other currencies
+	}
+
+	return &entry, nil
+}
+
+// Get a page of an account's ledger entries, most recent first
+// The cursor returned with a page is passed to get the next one, it is empty after the last page
+func (repo *DynamoDBRepository) GetLedgerEntries(account string, limit int64, cursor string) ([]models.LedgerEntry, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("WalletLedger"),
+		IndexName:              aws.String("account-created_at-index"),
+		KeyConditionExpression: aws.String("account = :account"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":account": {S: aws.String(account)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int64(limit),
+	}
+
+	if cursor != "" {
+		startKey, err := decodeLedgerCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = startKey
+	}
+
+	result, err := repo.client.Query(input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries := make([]models.LedgerEntry, 0, len(result.Items))
+	for _, item := range result.Items {
+		var entry models.LedgerEntry
+		err := dynamodbattribute.UnmarshalMap(item, &entry)
+		if err != nil {
+			return nil, "", err
+		}
+		if entry.Currency == "" {
+			entry.Currency = config.DefaultCurrency
+		}
+		entries = append(entries, entry)
+	}
+
+	nextCursor, err := encodeLedgerCursor(result.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return entries, nextCursor, nil
+}
+
+// Encode the key a ledger query stopped at as an opaque cursor
+func encodeLedgerCursor(lastEvaluatedKey map[string]*dynamodb.AttributeValue) (string, error) {
+	if len(lastEvaluatedKey) == 0 {
+		return "", nil
+	}
+
+	var key map[string]string
+	err := dynamodbattribute.UnmarshalMap(lastEvaluatedKey, &key)
+	if err != nil {
+		return "", err
+	}
+
+	keyJSON, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(keyJSON), nil
+}
+
+// Decode a cursor back into the key a ledger query continues from
+func decodeLedgerCursor(cursor string) (map[string]*dynamodb.AttributeValue, error) {
+	keyJSON, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("Invalid cursor")
+	}
+
+	var key map[string]string
+	err = json.Unmarshal(keyJSON, &key)
+	if err != nil {
+		return nil, errors.New("Invalid cursor")
+	}
+
+	return dynamodbattribute.MarshalMap(key)
+}
@@ -0,0 +1,348 @@
+package repositories
+
+import (
+	"cloudblast-backend/internal/models"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func userUpdate(username, expression, condition string, values map[string]*dynamodb.AttributeValue) *dynamodb.TransactWriteItem {
+	update := &dynamodb.Update{
+		TableName:                 aws.String("User"),
+		Key:                       map[string]*dynamodb.AttributeValue{"username": {S: aws.String(username)}},
+		UpdateExpression:          aws.String(expression),
+		ExpressionAttributeValues: values,
+	}
+	if condition != "" {
+		update.ConditionExpression = aws.String(condition)
+	}
+	return &dynamodb.TransactWriteItem{Update: update}
+}
+
+func coinsUpdate(username string) *dynamodb.TransactWriteItem {
+	item, err := balanceUpdateItem(&models.WalletBalance{Username: username, Currency: "coins", Balance: 100}, 150, time.Time{})
+	if err != nil {
+		panic(err)
+	}
+	return item
+}
+
+func number(n int) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(n))}
+}
+
+func TestFoldUserUpdates(t *testing.T) {
+	t.Run("SET updates of the user join the coins update", func(t *testing.T) {
+		balance := coinsUpdate("alice")
+		level := userUpdate("alice", "SET progress_level = :level", "progress_level = :from", map[string]*dynamodb.AttributeValue{
+			":level": number(4),
+			":from":  number(3),
+		})
+		other := userUpdate("bob", "SET progress_level = :level", "", map[string]*dynamodb.AttributeValue{":level": number(9)})
+		ledger := &dynamodb.TransactWriteItem{Put: &dynamodb.Put{TableName: aws.String("WalletLedger")}}
+
+		remaining, err := foldUserUpdates(balance, []*dynamodb.TransactWriteItem{level, other, ledger})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(remaining) != 2 || remaining[0] != other || remaining[1] != ledger {
+			t.Errorf("remaining writes = %v, want bob's update and the ledger entry", remaining)
+		}
+
+		update := balance.Update
+		if got, want := *update.UpdateExpression, "SET coins = :new, progress_level = :level"; got != want {
+			t.Errorf("update = %q, want %q", got, want)
+		}
+		if got, want := *update.ConditionExpression, "(coins = :old) AND (progress_level = :from)"; got != want {
+			t.Errorf("condition = %q, want %q", got, want)
+		}
+		for _, name := range []string{":old", ":new", ":level", ":from"} {
+			if update.ExpressionAttributeValues[name] == nil {
+				t.Errorf("value %s is missing", name)
+			}
+		}
+	})
+
+	t.Run("updates of other currencies are not folded", func(t *testing.T) {
+		balance, err := balanceUpdateItem(&models.WalletBalance{Username: "alice", Currency: "gems"}, 5, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		level := userUpdate("alice", "SET progress_level = :level", "", map[string]*dynamodb.AttributeValue{":level": number(4)})
+
+		remaining, err := foldUserUpdates(balance, []*dynamodb.TransactWriteItem{level})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(remaining) != 1 || remaining[0] != level {
+			t.Errorf("remaining writes = %v, want the user update", remaining)
+		}
+	})
+
+	t.Run("updates that cannot be folded are rejected", func(t *testing.T) {
+		for name, item := range map[string]*dynamodb.TransactWriteItem{
+			"REMOVE":           userUpdate("alice", "REMOVE banned_at", "", nil),
+			"clashing value":   userUpdate("alice", "SET best = :new", "", map[string]*dynamodb.AttributeValue{":new": number(1)}),
+			"ADD to a counter": userUpdate("alice", "ADD plays :one", "", map[string]*dynamodb.AttributeValue{":one": number(1)}),
+		} {
+			if _, err := foldUserUpdates(coinsUpdate("alice"), []*dynamodb.TransactWriteItem{item}); err == nil {
+				t.Errorf("%s: folded without an error", name)
+			}
+		}
+	})
+}
+
+// Store a user with a coin balance
+func putUser(t *testing.T, fake *fakeDynamoDB, username string, coins int) {
+	t.Helper()
+	item, err := dynamodbattribute.MarshalMap(models.User{Username: username, Coins: coins})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.put("User", item)
+}
+
+// Find the update of a table among the writes of a transaction
+func findUpdate(input *dynamodb.TransactWriteItemsInput, table string) []*dynamodb.Update {
+	var updates []*dynamodb.Update
+	for _, item := range input.TransactItems {
+		if item.Update != nil && *item.Update.TableName == table {
+			updates = append(updates, item.Update)
+		}
+	}
+	return updates
+}
+
+// Find the ledger entries written by a transaction
+func ledgerPuts(t *testing.T, input *dynamodb.TransactWriteItemsInput) []models.LedgerEntry {
+	t.Helper()
+	var entries []models.LedgerEntry
+	for _, item := range input.TransactItems {
+		if item.Put == nil || *item.Put.TableName != "WalletLedger" {
+			continue
+		}
+		var entry models.LedgerEntry
+		if err := dynamodbattribute.UnmarshalMap(item.Put.Item, &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func levelReward(prepare func() ([]*dynamodb.TransactWriteItem, error)) WalletTransaction {
+	return WalletTransaction{
+		TransactionID: "level_reward:alice:4",
+		Username:      "alice",
+		Counterparty:  models.SystemAccountRewards,
+		Type:          "level_reward",
+		Amount:        50,
+		prepare:       prepare,
+	}
+}
+
+func TestPostWalletTransactionWritesEverythingInOneTransaction(t *testing.T) {
+	fake, repo := newFakeDynamoDB(t)
+	putUser(t, fake, "alice", 100)
+
+	prepared := 0
+	entry, posted, err := repo.postWalletTransaction(levelReward(func() ([]*dynamodb.TransactWriteItem, error) {
+		prepared++
+		return []*dynamodb.TransactWriteItem{
+			userUpdate("alice", "SET progress_level = :level", "progress_level = :from", map[string]*dynamodb.AttributeValue{
+				":level": number(4),
+				":from":  number(3),
+			}),
+		}, nil
+	}))
+	if err != nil || !posted {
+		t.Fatalf("posted = %v, %v, want posted", posted, err)
+	}
+	if entry.Balance != 150 || entry.Currency != "coins" {
+		t.Errorf("entry balance = %d %s, want 150 coins", entry.Balance, entry.Currency)
+	}
+
+	writes := fake.transactWrites()
+	if len(writes) != 1 || prepared != 1 {
+		t.Fatalf("%d transactions with %d prepares, want 1 and 1", len(writes), prepared)
+	}
+	users := findUpdate(writes[0], "User")
+	if len(users) != 1 {
+		t.Fatalf("transaction updates the user %d times, want once", len(users))
+	}
+	if got, want := *users[0].UpdateExpression, "SET coins = :new, progress_level = :level"; got != want {
+		t.Errorf("user update = %q, want %q", got, want)
+	}
+
+	entries := ledgerPuts(t, writes[0])
+	if len(entries) != 2 {
+		t.Fatalf("%d ledger entries, want both sides", len(entries))
+	}
+	if entries[0].Account != "alice" || entries[0].Amount != 50 || entries[1].Account != models.SystemAccountRewards || entries[1].Amount != -50 {
+		t.Errorf("ledger entries = %+v, want alice +50 and the rewards account -50", entries)
+	}
+
+	// Posting the transaction again returns the first entry and writes nothing
+	again, posted, err := repo.postWalletTransaction(levelReward(func() ([]*dynamodb.TransactWriteItem, error) {
+		prepared++
+		return nil, nil
+	}))
+	if err != nil || posted {
+		t.Fatalf("posted again = %v, %v, want not posted", posted, err)
+	}
+	if again.TransactionID != entry.TransactionID || again.Balance != 150 {
+		t.Errorf("entry posted again = %+v, want the first one", again)
+	}
+	if len(fake.transactWrites()) != 1 || prepared != 1 {
+		t.Errorf("posting again made %d transactions and %d prepares, want none", len(fake.transactWrites())-1, prepared-1)
+	}
+}
+
+func TestPostWalletTransactionRetriesConcurrentChanges(t *testing.T) {
+	fake, repo := newFakeDynamoDB(t)
+	putUser(t, fake, "alice", 100)
+
+	// Another purchase changes the balance before the first attempt commits
+	spent, err := dynamodbattribute.MarshalMap(models.User{Username: "alice", Coins: 80})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.cancel = func(attempt int, input *dynamodb.TransactWriteItemsInput) []string {
+		if attempt > 1 {
+			return nil
+		}
+		fake.put("User", spent)
+		codes := make([]string, len(input.TransactItems))
+		for i := range codes {
+			codes[i] = "None"
+		}
+		codes[0] = "ConditionalCheckFailed"
+		return codes
+	}
+
+	prepared := 0
+	entry, err := repo.PostWalletTransaction(levelReward(func() ([]*dynamodb.TransactWriteItem, error) {
+		prepared++
+		return nil, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writes := fake.transactWrites()
+	if len(writes) != 2 || prepared != 2 {
+		t.Fatalf("%d attempts with %d prepares, want 2 and 2", len(writes), prepared)
+	}
+	coins := findUpdate(writes[1], "User")[0]
+	if *coins.ExpressionAttributeValues[":old"].N != "80" || *coins.ExpressionAttributeValues[":new"].N != "130" {
+		t.Errorf("retry writes %s over %s, want 130 over 80", *coins.ExpressionAttributeValues[":new"].N, *coins.ExpressionAttributeValues[":old"].N)
+	}
+	if entry.Balance != 130 {
+		t.Errorf("entry balance = %d, want 130", entry.Balance)
+	}
+}
+
+func TestPostWalletTransactionStopsOnOtherFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		codes    []string
+		attempts int
+	}{
+		{"a validation error is not retried", []string{"ValidationError", "None", "None"}, 1},
+		{"a balance that keeps changing is given up on", []string{"ConditionalCheckFailed", "None", "None"}, maxLedgerAttempts},
+		{"conflicting transactions are retried", []string{"TransactionConflict", "None", "None"}, maxLedgerAttempts},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake, repo := newFakeDynamoDB(t)
+			putUser(t, fake, "alice", 100)
+			fake.cancel = func(attempt int, input *dynamodb.TransactWriteItemsInput) []string {
+				return test.codes
+			}
+
+			_, err := repo.PostWalletTransaction(levelReward(nil))
+			if err == nil {
+				t.Fatal("posted, want an error")
+			}
+			if attempts := len(fake.transactWrites()); attempts != test.attempts {
+				t.Errorf("%d attempts, want %d", attempts, test.attempts)
+			}
+		})
+	}
+}
+
+func TestPostWalletTransactionWritesOffExpiredBalances(t *testing.T) {
+	fake, repo := newFakeDynamoDB(t)
+	putUser(t, fake, "alice", 0)
+
+	expiredAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	balance, err := dynamodbattribute.MarshalMap(models.WalletBalance{Username: "alice", Currency: "event_tokens", Balance: 30, ExpiresAt: &expiredAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.put("WalletBalance", balance)
+
+	// Spending expired tokens fails before anything is written
+	_, err = repo.PostWalletTransaction(WalletTransaction{
+		TransactionID: "store_purchase:alice:1",
+		Username:      "alice",
+		Currency:      "event_tokens",
+		Counterparty:  models.SystemAccountStore,
+		Type:          "store_purchase",
+		Amount:        -10,
+	})
+	if err != ErrInsufficientFunds {
+		t.Fatalf("spending expired tokens: %v, want %v", err, ErrInsufficientFunds)
+	}
+	if len(fake.transactWrites()) != 0 {
+		t.Fatal("spending expired tokens wrote a transaction")
+	}
+
+	// A credit starts from zero and writes the expired tokens off in the same transaction
+	newExpiry := time.Now().UTC().Add(24 * time.Hour)
+	entry, err := repo.PostWalletTransaction(WalletTransaction{
+		TransactionID: "tournament_prize:alice:1",
+		Username:      "alice",
+		Currency:      "event_tokens",
+		Counterparty:  models.SystemAccountRewards,
+		Type:          "tournament_prize",
+		Amount:        10,
+		ExpiresAt:     newExpiry,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Balance != 10 {
+		t.Errorf("balance after the credit = %d, want 10", entry.Balance)
+	}
+
+	writes := fake.transactWrites()
+	if len(writes) != 1 {
+		t.Fatalf("%d transactions, want 1", len(writes))
+	}
+	update := findUpdate(writes[0], "WalletBalance")[0]
+	if *update.ExpressionAttributeValues[":old"].N != "30" || *update.ExpressionAttributeValues[":new"].N != "10" {
+		t.Errorf("balance update writes %s over %s, want 10 over 30", *update.ExpressionAttributeValues[":new"].N, *update.ExpressionAttributeValues[":old"].N)
+	}
+	if !strings.Contains(*update.ConditionExpression, "expires_at = :old_expires_at") {
+		t.Errorf("balance update condition %q does not check the old expiry", *update.ConditionExpression)
+	}
+
+	var expiry []models.LedgerEntry
+	for _, entry := range ledgerPuts(t, writes[0]) {
+		if entry.Type == models.LedgerExpiry {
+			expiry = append(expiry, entry)
+		}
+	}
+	wantID := models.LedgerExpiry + ":event_tokens:alice:" + strconv.FormatInt(expiredAt.Unix(), 10)
+	if len(expiry) != 2 || expiry[0].TransactionID != wantID || expiry[0].Amount != -30 || expiry[1].Account != models.SystemAccountExpiry {
+		t.Errorf("expiry entries = %+v, want -30 for alice under %s and +30 for the expiry account", expiry, wantID)
+	}
+}
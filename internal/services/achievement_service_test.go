@@ -0,0 +1,136 @@
+package services
+
+import (
+	"cloudblast-backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestApplyAchievementEvent(t *testing.T) {
+	counter := models.AchievementDefinition{Kind: models.AchievementKindCounter, Threshold: 5}
+	streak := models.AchievementDefinition{Kind: models.AchievementKindStreak, Threshold: 3}
+	once := models.AchievementDefinition{Kind: models.AchievementKindOnce, MinAmount: 10}
+	at := time.Date(2024, time.March, 10, 22, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		achievement models.AchievementDefinition
+		progress    models.AchievementProgress
+		amount      int
+		at          time.Time
+		changed     bool
+		want        models.AchievementProgress
+		completed   bool
+	}{
+		{
+			name:        "counter adds the amount",
+			achievement: counter,
+			progress:    models.AchievementProgress{Progress: 1},
+			amount:      2,
+			at:          at,
+			changed:     true,
+			want:        models.AchievementProgress{Progress: 3},
+		},
+		{
+			name:        "counter completes at its threshold",
+			achievement: counter,
+			progress:    models.AchievementProgress{Progress: 3},
+			amount:      2,
+			at:          at,
+			changed:     true,
+			want:        models.AchievementProgress{Progress: 5},
+			completed:   true,
+		},
+		{
+			name:        "counter ignores amounts that are not positive",
+			achievement: counter,
+			progress:    models.AchievementProgress{Progress: 3},
+			amount:      0,
+			at:          at,
+			want:        models.AchievementProgress{Progress: 3},
+		},
+		{
+			name:        "streak starts on the first day",
+			achievement: streak,
+			amount:      1,
+			at:          at,
+			changed:     true,
+			want:        models.AchievementProgress{Progress: 1, StreakDay: "2024-03-10"},
+		},
+		{
+			name:        "streak counts a day once",
+			achievement: streak,
+			progress:    models.AchievementProgress{Progress: 1, StreakDay: "2024-03-10"},
+			amount:      1,
+			at:          at,
+			want:        models.AchievementProgress{Progress: 1, StreakDay: "2024-03-10"},
+		},
+		{
+			name:        "streak grows on the next day and completes",
+			achievement: streak,
+			progress:    models.AchievementProgress{Progress: 2, StreakDay: "2024-03-09"},
+			amount:      1,
+			at:          at,
+			changed:     true,
+			want:        models.AchievementProgress{Progress: 3, StreakDay: "2024-03-10"},
+			completed:   true,
+		},
+		{
+			name:        "streak starts over after a missed day",
+			achievement: streak,
+			progress:    models.AchievementProgress{Progress: 2, StreakDay: "2024-03-08"},
+			amount:      1,
+			at:          at,
+			changed:     true,
+			want:        models.AchievementProgress{Progress: 1, StreakDay: "2024-03-10"},
+		},
+		{
+			name:        "streak days are counted in UTC",
+			achievement: streak,
+			progress:    models.AchievementProgress{Progress: 1, StreakDay: "2024-03-09"},
+			amount:      1,
+			at:          at.In(time.FixedZone("UTC+3", 3*60*60)),
+			changed:     true,
+			want:        models.AchievementProgress{Progress: 2, StreakDay: "2024-03-10"},
+		},
+		{
+			name:        "one-time achievement completes with a large enough amount",
+			achievement: once,
+			amount:      10,
+			at:          at,
+			changed:     true,
+			want:        models.AchievementProgress{Progress: 1},
+			completed:   true,
+		},
+		{
+			name:        "one-time achievement ignores smaller amounts",
+			achievement: once,
+			amount:      9,
+			at:          at,
+			want:        models.AchievementProgress{},
+		},
+		{
+			name:        "unknown kinds change nothing",
+			achievement: models.AchievementDefinition{Kind: "unknown", Threshold: 1},
+			amount:      1,
+			at:          at,
+			want:        models.AchievementProgress{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			progress := test.progress
+			changed := applyAchievementEvent(test.achievement, &progress, test.amount, test.at)
+			if changed != test.changed {
+				t.Errorf("changed = %v, want %v", changed, test.changed)
+			}
+			if progress.Progress != test.want.Progress || progress.StreakDay != test.want.StreakDay {
+				t.Errorf("progress = %d on %q, want %d on %q", progress.Progress, progress.StreakDay, test.want.Progress, test.want.StreakDay)
+			}
+			if completed := progress.CompletedAt != nil; completed != test.completed {
+				t.Errorf("completed = %v, want %v", completed, test.completed)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// catalogReloader holds a catalog loaded from a JSON file and replaces it whenever the version in the file changes
+// A file that cannot be read or is not valid keeps the catalog in use.
+type catalogReloader[T any] struct {
+	// Name of the catalog in logs, such as "store"
+	name     string
+	path     string
+	validate func(catalog *T) error
+	mu       sync.RWMutex
+	catalog  T
+	version  int
+}
+
+// Load a catalog file to reload from then on
+// validate checks the catalog and can fill in what its file leaves out.
+func newCatalogReloader[T any](name string, path string, validate func(catalog *T) error) (*catalogReloader[T], error) {
+	reloader := &catalogReloader[T]{name: name, path: path, validate: validate}
+	catalog, version, err := reloader.load()
+	if err != nil {
+		return nil, err
+	}
+	reloader.catalog = catalog
+	reloader.version = version
+	return reloader, nil
+}
+
+// Load and validate the catalog file
+func (cr *catalogReloader[T]) load() (T, int, error) {
+	var catalog T
+	var versioned struct {
+		Version int `json:"version"`
+	}
+
+	data, err := os.ReadFile(cr.path)
+	if err != nil {
+		return catalog, 0, err
+	}
+	err = json.Unmarshal(data, &catalog)
+	if err != nil {
+		return catalog, 0, err
+	}
+	err = json.Unmarshal(data, &versioned)
+	if err != nil {
+		return catalog, 0, err
+	}
+
+	err = cr.validate(&catalog)
+	if err != nil {
+		return catalog, 0, err
+	}
+	return catalog, versioned.Version, nil
+}
+
+// Load the catalog file again and use it if its version changed
+func (cr *catalogReloader[T]) reload() {
+	catalog, version, err := cr.load()
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if err != nil {
+		log.Printf("Error loading %s catalog, keeping version %d: %v", cr.name, cr.version, err)
+		return
+	}
+	if version != cr.version {
+		log.Printf("Loaded %s catalog version %d, replacing version %d", cr.name, version, cr.version)
+		cr.catalog = catalog
+		cr.version = version
+	}
+}
+
+// Reload the catalog every period until stop is closed
+// onTick, if given, runs after every reload with the catalog then in use.
+func (cr *catalogReloader[T]) run(period time.Duration, stop <-chan struct{}, onTick func(catalog T)) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			cr.reload()
+			if onTick != nil {
+				onTick(cr.get())
+			}
+		}
+	}
+}
+
+// Get the catalog currently in use
+func (cr *catalogReloader[T]) get() T {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.catalog
+}
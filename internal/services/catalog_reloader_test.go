@@ -0,0 +1,54 @@
+package services
+
+import (
+	"cloudblast-backend/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeStoreCatalog(t *testing.T, path string, contents string) {
+	t.Helper()
+	err := os.WriteFile(path, []byte(contents), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCatalogReloaderReplacesTheCatalogOnlyWithNewValidVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store_catalog.json")
+	writeStoreCatalog(t, path, `{"version": 1, "items": [{"item_id": "hammer", "price": 100, "currency": "coins", "quantity": 1}]}`)
+
+	reloader, err := newCatalogReloader("store", path, validateStoreCatalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name     string
+		contents string
+		// Price of the hammer in the catalog in use after the reload
+		price int
+	}{
+		{"same version", `{"version": 1, "items": [{"item_id": "hammer", "price": 50, "currency": "coins", "quantity": 1}]}`, 100},
+		{"new version", `{"version": 2, "items": [{"item_id": "hammer", "price": 150, "currency": "coins", "quantity": 1}]}`, 150},
+		{"invalid catalog", `{"version": 3, "items": [{"item_id": "hammer", "price": 200, "currency": "stars", "quantity": 1}]}`, 150},
+		{"unreadable file", `{"version": 4,`, 150},
+		{"older version", `{"version": 1, "items": [{"item_id": "hammer", "price": 100, "currency": "coins", "quantity": 1}]}`, 100},
+	}
+	for _, step := range steps {
+		writeStoreCatalog(t, path, step.contents)
+		reloader.reload()
+
+		catalog := reloader.get()
+		if len(catalog.Items) != 1 || catalog.Items[0].Price != step.price {
+			t.Errorf("after %s: catalog %+v, want the hammer at %d", step.name, catalog, step.price)
+		}
+	}
+
+	// A catalog that is not valid when the service starts stops it
+	writeStoreCatalog(t, path, `{"version": 5, "items": [{"item_id": "", "price": 100, "currency": "coins", "quantity": 1}]}`)
+	if _, err := newCatalogReloader[models.StoreCatalog]("store", path, validateStoreCatalog); err == nil {
+		t.Error("an invalid catalog was loaded")
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitTeamReward(t *testing.T) {
+	tests := []struct {
+		name         string
+		pool         int
+		contributors []teamRewardShare
+		rule         string
+		want         []teamRewardShare
+	}{
+		{
+			name:         "no contributors",
+			pool:         1000,
+			contributors: nil,
+			rule:         "proportional",
+			want:         nil,
+		},
+		{
+			name:         "proportional to contributions",
+			pool:         1000,
+			contributors: []teamRewardShare{{"alice", 30}, {"bob", 10}},
+			rule:         "proportional",
+			want:         []teamRewardShare{{"alice", 750}, {"bob", 250}},
+		},
+		{
+			name:         "proportional rounding leftovers go to the biggest contributors",
+			pool:         100,
+			contributors: []teamRewardShare{{"alice", 1}, {"bob", 1}, {"carol", 1}},
+			rule:         "proportional",
+			want:         []teamRewardShare{{"alice", 34}, {"bob", 33}, {"carol", 33}},
+		},
+		{
+			name:         "contributors whose share rounds to nothing are left out",
+			pool:         10,
+			contributors: []teamRewardShare{{"alice", 1000}, {"bob", 1}},
+			rule:         "proportional",
+			want:         []teamRewardShare{{"alice", 10}},
+		},
+		{
+			name:         "equal shares ignore contributions",
+			pool:         10,
+			contributors: []teamRewardShare{{"alice", 50}, {"bob", 5}, {"carol", 1}},
+			rule:         "equal",
+			want:         []teamRewardShare{{"alice", 4}, {"bob", 3}, {"carol", 3}},
+		},
+		{
+			name:         "equal pool smaller than the team",
+			pool:         2,
+			contributors: []teamRewardShare{{"alice", 50}, {"bob", 5}, {"carol", 1}},
+			rule:         "equal",
+			want:         []teamRewardShare{{"alice", 1}, {"bob", 1}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := splitTeamReward(test.pool, test.contributors, test.rule)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("splitTeamReward() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
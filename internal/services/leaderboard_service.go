@@ -4,12 +4,32 @@ import (
 	"encoding/json"
 	"log"
 	"strconv"
+	"strings"
+	"time"
 
+	"cloudblast-backend/config"
+	"cloudblast-backend/internal/models"
 	"cloudblast-backend/internal/repositories"
 
 	"github.com/streadway/amqp"
 )
 
+// Redis keys of the hall of fame leaderboards
+const (
+	hallOfFameSettledKey    = "halloffame:settled"
+	hallOfFameBackfilledKey = "halloffame:backfilled"
+)
+
+// How long recording a tournament in the hall of fame may take before another instance can take over
+const hallOfFameLockTTL = 10 * time.Minute
+
+// Categories of the hall of fame leaderboards
+var hallOfFameCategories = map[string]bool{
+	"wins":    true,
+	"podiums": true,
+	"coins":   true,
+}
+
 type LeaderboardService struct {
 	conn        *amqp.Connection
 	channel     *amqp.Channel
@@ -36,6 +56,12 @@ func NewLeaderboardService(conn *amqp.Connection, redisAddr string) (*Leaderboar
 		return nil, err
 	}
 
+	// Declare the exchange used to push score changes to connected clients
+	err = declareLiveEventsExchange(channel)
+	if err != nil {
+		return nil, err
+	}
+
 	return &LeaderboardService{
 		conn:        conn,
 		channel:     channel,
@@ -90,6 +116,12 @@ func (ls *LeaderboardService) Start() {
 		log.Fatalf("Failed to register a consumer: %v", err)
 	}
 
+	// Fill the hall of fame with the results of tournaments that finished before it existed
+	err = ls.BackfillHallOfFame()
+	if err != nil {
+		log.Printf("Failed to backfill hall of fame: %v", err)
+	}
+
 	// Handle messages received on the leaderboard queue
 	for msg := range msgs {
 		action, ok := msg.Headers["action"].(string)
@@ -110,6 +142,12 @@ func (ls *LeaderboardService) Start() {
 			ls.HandleGetGroupUserRank(msg.Body, msg.ReplyTo, msg.CorrelationId)
 		case "GetGroupLeaderboardWithRanks":
 			ls.HandleGetGroupLeaderboardWithRanks(msg.Body, msg.ReplyTo, msg.CorrelationId)
+		case "RecordTournamentResults":
+			ls.HandleRecordTournamentResults(msg.Body, msg.ReplyTo, msg.CorrelationId)
+		case "GetHallOfFameLeaderboard":
+			ls.HandleGetHallOfFameLeaderboard(msg.Body, msg.ReplyTo, msg.CorrelationId)
+		case "GetHallOfFame":
+			ls.HandleGetHallOfFame(msg.Body, msg.ReplyTo, msg.CorrelationId)
 		default:
 			log.Printf("Unknown action: %s", action)
 		}
@@ -162,8 +200,20 @@ func (ls *LeaderboardService) HandleEnterLeaderboardGroup(data []byte, replyTo s
 		return
 	}
 
+	// Hidden players enter the group's quarantined leaderboard
+	leaderboardName := requestData.LeaderboardName
+	tournamentID := strings.TrimSuffix(leaderboardName, ":"+requestData.GroupID)
+	hidden, err := isHiddenInTournament(ls.redisRepo, tournamentID, requestData.Username)
+	if err != nil {
+		log.Printf("Error checking whether user is hidden: %v", err)
+		return
+	}
+	if hidden {
+		leaderboardName = quarantinedLeaderboardKey(leaderboardName)
+	}
+
 	// Enter the leaderboard group for the specified user with an initial score
-	err = ls.redisRepo.EnterLeaderboardGroup(requestData.LeaderboardName, requestData.Username, requestData.InitialScore)
+	err = ls.redisRepo.EnterLeaderboardGroup(leaderboardName, requestData.Username, requestData.InitialScore)
 	if err != nil {
 		log.Printf("Error entering leaderboard group: %v", err)
 		return
@@ -178,6 +228,7 @@ func (ls *LeaderboardService) HandleIncrementGroupScore(data []byte, replyTo str
 		GroupID        int `json:"group_id"`
 		LeaderboardName string `json:"leaderboard_name"`
 		Username       string `json:"username"`
+		Amount         int `json:"amount"`
 	}
 
 	err := json.Unmarshal(data, &requestData)
@@ -186,14 +237,86 @@ func (ls *LeaderboardService) HandleIncrementGroupScore(data []byte, replyTo str
 		return
 	}
 
+	// Level completions carry the points they earned, plain score updates count one
+	amount := requestData.Amount
+	if amount <= 0 {
+		amount = 1
+	}
+
+	// Quarantined and sanctioned players keep scoring, but only they can see their scores
+	tournamentID := strings.TrimSuffix(requestData.LeaderboardName, ":"+strconv.Itoa(requestData.GroupID))
+	hidden, err := isHiddenInTournament(ls.redisRepo, tournamentID, requestData.Username)
+	if err != nil {
+		log.Printf("Error checking whether user is hidden: %v", err)
+		return
+	}
+	if hidden {
+		err = ls.redisRepo.IncrementLeaderboardScore(quarantinedLeaderboardKey(requestData.LeaderboardName), requestData.Username, amount)
+		if err != nil {
+			log.Printf("Error incrementing quarantined score: %v", err)
+		}
+		return
+	}
+
+	// Scores hidden during a suspension that has ended return to the group
+	err = ls.redisRepo.MoveLeaderboardMember(quarantinedLeaderboardKey(requestData.LeaderboardName), requestData.LeaderboardName, requestData.Username)
+	if err != nil {
+		log.Printf("Error restoring hidden score: %v", err)
+		return
+	}
+
+	// Remember the user's rank to find the players they overtake
+	_, previousRank, err := ls.redisRepo.GetMemberScoreAndRank(requestData.LeaderboardName, requestData.Username)
+	if err != nil {
+		log.Printf("Error getting user's rank: %v", err)
+		return
+	}
+
 	// Increment the user's score in the specified leaderboard
-	err = ls.redisRepo.IncrementGroupScore(requestData.LeaderboardName, requestData.Username)
+	err = ls.redisRepo.IncrementLeaderboardScore(requestData.LeaderboardName, requestData.Username, amount)
 	if err != nil {
 		log.Printf("Error incrementing user's score: %v", err)
 		return
 	}
 
 	log.Printf("Incremented score for user %s in leaderboard %s", requestData.Username, requestData.LeaderboardName)
+
+	// Push the new group standings to the clients watching the group
+	leaderboard, err := ls.redisRepo.GetGroupLeaderboardWithRanks(requestData.LeaderboardName, 0, 34)
+	if err != nil {
+		log.Printf("Error getting group leaderboard: %v", err)
+		return
+	}
+	publishLiveEvent(ls.channel, models.LiveEvent{
+		Type:         "leaderboard_update",
+		TournamentID: tournamentID,
+		GroupID:      requestData.GroupID,
+		Username:     requestData.Username,
+		Data:         leaderboard,
+	})
+
+	// Notify the players the user moved past
+	ls.notifyOvertakenPlayers(tournamentID, requestData.Username, int(previousRank), leaderboard)
+
+	// Count the points in the user's scoring stats and check the group for outliers
+	action := "RecordGroupScore"
+	publishToRabbitMQ(ls.channel, "antiCheatQueue", action, map[string]interface{}{
+		"action":           action,
+		"tournament_id":    tournamentID,
+		"group_id":         requestData.GroupID,
+		"leaderboard_name": requestData.LeaderboardName,
+		"username":         requestData.Username,
+		"amount":           amount,
+	}, "", "")
+
+	// Add the points to the team total of the user's clan
+	action = "RecordTeamScore"
+	publishToRabbitMQ(ls.channel, "clanQueue", action, map[string]interface{}{
+		"action":        action,
+		"tournament_id": tournamentID,
+		"username":      requestData.Username,
+		"amount":        amount,
+	}, "", "")
 }
 
 // Get a user's rank in a leaderboard
@@ -238,11 +361,32 @@ func (ls *LeaderboardService) HandleGetGroupUserRank(data []byte, replyTo string
 	// Create the leaderboard name
 	newLeaderboardName := latestTournamentID + ":" + strconv.Itoa(latestGroupID)
 	// Get the user's rank in the specified leaderboard
-	rank, err := ls.redisRepo.GetGroupUserRank(newLeaderboardName, requestData.Username)
+	// Hidden users see the rank their score would have in the group
+	hidden, err := isHiddenInTournament(ls.redisRepo, latestTournamentID, requestData.Username)
 	if err != nil {
-		log.Printf("Error getting user's rank: %v", err)
+		log.Printf("Error checking whether user is hidden: %v", err)
 		return
 	}
+	var rank int64
+	if hidden {
+		score, _, err := ls.redisRepo.GetMemberScoreAndRank(quarantinedLeaderboardKey(newLeaderboardName), requestData.Username)
+		if err != nil {
+			log.Printf("Error getting user's score: %v", err)
+			return
+		}
+		rank, err = ls.redisRepo.CountLeaderboardMembersAbove(newLeaderboardName, score)
+		if err != nil {
+			log.Printf("Error getting user's rank: %v", err)
+			return
+		}
+		rank++
+	} else {
+		rank, err = ls.redisRepo.GetGroupUserRank(newLeaderboardName, requestData.Username)
+		if err != nil {
+			log.Printf("Error getting user's rank: %v", err)
+			return
+		}
+	}
 
 	// Publish the user's rank as a response
 	sendResponse(ls.channel, replyTo, correlationID,"GetGroupUserRankResponse", struct {
@@ -299,6 +443,346 @@ func (ls *LeaderboardService) HandleGetGroupLeaderboardWithRanks(data []byte, re
 		return
 	}
 
+	// Hidden users still see themselves in their group
+	hidden, err := isHiddenInTournament(ls.redisRepo, latestTournamentID, requestData.Username)
+	if err != nil {
+		log.Printf("Error checking whether user is hidden: %v", err)
+		return
+	}
+	if hidden {
+		score, _, err := ls.redisRepo.GetMemberScoreAndRank(quarantinedLeaderboardKey(newLeaderboardName), requestData.Username)
+		if err != nil {
+			log.Printf("Error getting user's score: %v", err)
+			return
+		}
+		leaderboard = withLeaderboardMember(leaderboard, requestData.Username, score)
+	}
+
 	sendResponse(ls.channel, replyTo, correlationID,"GetGroupLeaderboardWithRanksResponse", leaderboard)
 
 }
+
+// Insert a member into a leaderboard at the place of its score and rank the entries again
+func withLeaderboardMember(leaderboard []map[string]interface{}, member string, score int) []map[string]interface{} {
+	position := len(leaderboard)
+	for i, entry := range leaderboard {
+		if toInt(entry["score"]) < score {
+			position = i
+			break
+		}
+	}
+
+	ranked := make([]map[string]interface{}, 0, len(leaderboard)+1)
+	ranked = append(ranked, leaderboard[:position]...)
+	ranked = append(ranked, map[string]interface{}{
+		"member": member,
+		"score":  score,
+	})
+	ranked = append(ranked, leaderboard[position:]...)
+	for i, entry := range ranked {
+		entry["rank"] = i + 1
+	}
+	return ranked
+}
+
+// Get the Redis key of a hall of fame leaderboard, optionally scoped to a country
+func hallOfFameKey(category string, country string) string {
+	if country == "" {
+		return "halloffame:" + category
+	}
+	return "halloffame:" + category + ":" + country
+}
+
+// Move a player's hall of fame scores from one country's leaderboards to another's
+func moveHallOfFameCountry(redisRepo *repositories.RedisRepo, username string, fromCountry string, toCountry string) error {
+	if fromCountry == "" {
+		return nil
+	}
+	for category := range hallOfFameCategories {
+		err := redisRepo.MoveLeaderboardMember(hallOfFameKey(category, fromCountry), hallOfFameKey(category, toCountry), username)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update the hall of fame leaderboards with the ranked players of a finished tournament
+// Each player is only counted once, so settlement and backfill can safely overlap, recording again
+// after a failure picks up where it stopped, and players ranked after the settlement can be added.
+// The tournament is marked settled once all the players it was settled with are recorded.
+func (ls *LeaderboardService) recordTournamentResults(tournamentID string, rankedPlayers []models.UserInTournament) error {
+	lockKey := "lock:halloffame:" + tournamentID
+	acquired, err := ls.redisRepo.AcquireLock(lockKey, hallOfFameLockTTL)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		log.Printf("Tournament %s is already being recorded in the hall of fame", tournamentID)
+		return nil
+	}
+	defer ls.redisRepo.DeleteKeys(lockKey)
+
+	tournament, err := ls.dynamoDBRepo.GetTournamentByID(tournamentID)
+	if err != nil {
+		return err
+	}
+
+	// Players whose scores are already counted
+	recordedKey := hallOfFameSettledKey + ":" + tournamentID
+	for _, player := range rankedPlayers {
+		recorded, err := ls.redisRepo.IsSetMember(recordedKey, player.Username)
+		if err != nil {
+			return err
+		}
+		if recorded {
+			continue
+		}
+
+		country, err := ls.dynamoDBRepo.GetCountryForUser(player.Username)
+		if err != nil {
+			return err
+		}
+
+		// Update both the global and the country leaderboards
+		scopes := []string{""}
+		if country != "" {
+			scopes = append(scopes, country)
+		}
+
+		// Only coin rewards count towards the coins leaderboard
+		reward := RewardForPlacement(player.League, player.Rank)
+		if _, league := config.LeagueByName(player.League); league.RewardCurrency != config.DefaultCurrency {
+			reward = 0
+		}
+		increments := make(map[string]int)
+		for _, scope := range scopes {
+			if player.Rank == 1 {
+				increments[hallOfFameKey("wins", scope)] = 1
+			}
+			if player.Rank >= 1 && player.Rank <= 3 {
+				increments[hallOfFameKey("podiums", scope)] = 1
+			}
+			if reward > 0 {
+				increments[hallOfFameKey("coins", scope)] = reward
+			}
+		}
+
+		// Add the winner to the hall of fame, writing the entry again replaces it
+		if player.Rank == 1 && tournament != nil {
+			err = ls.dynamoDBRepo.CreateHallOfFameEntry(&models.HallOfFameEntry{
+				TournamentID: tournamentID,
+				Username:     player.Username,
+				Country:      country,
+				Score:        player.Score,
+				Reward:       reward,
+				Date:         tournament.EndTime,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// The scores are counted together with the player being marked as recorded
+		err = ls.redisRepo.IncrementLeaderboardScoresAndAddToSet(increments, player.Username, recordedKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = ls.redisRepo.AddToSet(hallOfFameSettledKey, tournamentID)
+	return err
+}
+
+// Record the results of every finished tournament in the hall of fame
+// Runs only once, the first time the hall of fame is deployed
+func (ls *LeaderboardService) BackfillHallOfFame() error {
+	backfilled, err := ls.redisRepo.KeyExists(hallOfFameBackfilledKey)
+	if err != nil {
+		return err
+	}
+	if backfilled {
+		return nil
+	}
+
+	tournaments, err := ls.dynamoDBRepo.GetAllTournaments()
+	if err != nil {
+		return err
+	}
+
+	for _, tournament := range tournaments {
+		if !tournament.Finished {
+			continue
+		}
+		settled, err := ls.redisRepo.IsSetMember(hallOfFameSettledKey, tournament.TournamentID)
+		if err != nil {
+			return err
+		}
+		if settled {
+			continue
+		}
+
+		rankedPlayers, err := ls.dynamoDBRepo.GetRankedPlayersForTournament(tournament.TournamentID)
+		if err != nil {
+			return err
+		}
+
+		err = ls.recordTournamentResults(tournament.TournamentID, rankedPlayers)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Backfilled hall of fame with %d tournaments", len(tournaments))
+	return ls.redisRepo.SetFlag(hallOfFameBackfilledKey)
+}
+
+// Record the results of a finished tournament in the hall of fame
+func (ls *LeaderboardService) HandleRecordTournamentResults(data []byte, replyTo string, correlationID string) {
+	var requestData struct {
+		Action        string                    `json:"action"`
+		TournamentID  string                    `json:"tournament_id"`
+		RankedPlayers []models.UserInTournament `json:"ranked_players"`
+	}
+
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		log.Printf("Failed to unmarshal data: %v", err)
+		return
+	}
+
+	err = ls.recordTournamentResults(requestData.TournamentID, requestData.RankedPlayers)
+	if err != nil {
+		log.Printf("Error recording tournament results: %v", err)
+		return
+	}
+	log.Printf("Recorded results of tournament %s in the hall of fame", requestData.TournamentID)
+}
+
+// Get an all-time hall of fame leaderboard, globally or for the user's country
+func (ls *LeaderboardService) HandleGetHallOfFameLeaderboard(data []byte, replyTo string, correlationID string) {
+	var requestData struct {
+		Action   string `json:"action"`
+		Username string `json:"username"`
+		Category string `json:"category"`
+		Scope    string `json:"scope"`
+	}
+
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		log.Printf("Failed to unmarshal data: %v", err)
+		return
+	}
+
+	if !hallOfFameCategories[requestData.Category] {
+		sendResponse(ls.channel, replyTo, correlationID, "GetHallOfFameLeaderboardResponse", struct {
+			Error string `json:"error"`
+		}{
+			Error: "Category must be one of wins, podiums or coins",
+		})
+		return
+	}
+
+	// Use the user's country for country leaderboards
+	country := ""
+	if requestData.Scope == "country" {
+		country, err = ls.dynamoDBRepo.GetCountryForUser(requestData.Username)
+		if err != nil || country == "" {
+			log.Printf("Error getting country for user: %v", err)
+			sendResponse(ls.channel, replyTo, correlationID, "GetHallOfFameLeaderboardResponse", struct {
+				Error string `json:"error"`
+			}{
+				Error: "Failed to get country for user",
+			})
+			return
+		}
+	}
+
+	leaderboard, err := ls.redisRepo.GetLeaderboardWithRanks(hallOfFameKey(requestData.Category, country), 0, 999)
+	if err != nil {
+		log.Printf("Error getting hall of fame leaderboard: %v", err)
+		sendResponse(ls.channel, replyTo, correlationID, "GetHallOfFameLeaderboardResponse", struct {
+			Error string `json:"error"`
+		}{
+			Error: "Failed to get hall of fame leaderboard",
+		})
+		return
+	}
+
+	sendResponse(ls.channel, replyTo, correlationID, "GetHallOfFameLeaderboardResponse", struct {
+		Category    string                   `json:"category"`
+		Country     string                   `json:"country"`
+		Leaderboard []map[string]interface{} `json:"leaderboard"`
+	}{
+		Category:    requestData.Category,
+		Country:     country,
+		Leaderboard: leaderboard,
+	})
+}
+
+// Get the past tournament winners, most recent first
+func (ls *LeaderboardService) HandleGetHallOfFame(data []byte, replyTo string, correlationID string) {
+	var requestData struct {
+		Action string `json:"action"`
+	}
+
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		log.Printf("Failed to unmarshal data: %v", err)
+		return
+	}
+
+	entries, err := ls.dynamoDBRepo.GetHallOfFame()
+	if err != nil {
+		log.Printf("Error getting hall of fame: %v", err)
+		sendResponse(ls.channel, replyTo, correlationID, "GetHallOfFameResponse", struct {
+			Error string `json:"error"`
+		}{
+			Error: "Failed to get hall of fame",
+		})
+		return
+	}
+
+	sendResponse(ls.channel, replyTo, correlationID, "GetHallOfFameResponse", struct {
+		Winners []models.HallOfFameEntry `json:"winners"`
+	}{
+		Winners: entries,
+	})
+}
+
+// Notify the players of a group that dropped a place because a user moved past them
+func (ls *LeaderboardService) notifyOvertakenPlayers(tournamentID string, username string, previousRank int, leaderboard []map[string]interface{}) {
+	if previousRank < 1 {
+		return
+	}
+
+	newRank, newScore := 0, 0
+	for _, entry := range leaderboard {
+		if entry["member"] == username {
+			newRank = entry["rank"].(int)
+			newScore = entry["score"].(int)
+		}
+	}
+	if newRank == 0 || newRank >= previousRank {
+		return
+	}
+
+	// The players now ranked right below the user, down to the user's old rank, were overtaken
+	for _, entry := range leaderboard {
+		rank := entry["rank"].(int)
+		if rank <= newRank || rank > previousRank || entry["score"].(int) >= newScore {
+			continue
+		}
+
+		member, _ := entry["member"].(string)
+		publishNotification(ls.channel, models.Notification{
+			Type:         "overtaken_in_group",
+			TournamentID: tournamentID,
+			Username:     member,
+			Data: map[string]interface{}{
+				"overtaken_by": username,
+				"rank":         rank,
+			},
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"cloudblast-backend/config"
+	"cloudblast-backend/internal/models"
+	"cloudblast-backend/internal/repositories"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+// Number of messages buffered for a client before further messages are dropped
+const liveSubscriberBuffer = 32
+
+// LiveSubscriber is a client connected to the live leaderboard of their group
+type LiveSubscriber struct {
+	Username     string
+	TournamentID string
+	GroupID      int
+	Messages     chan []byte
+}
+
+type LiveService struct {
+	conn         *amqp.Connection
+	channel      *amqp.Channel
+	dynamoDBRepo *repositories.DynamoDBRepository
+	redisRepo    *repositories.RedisRepo
+	mu           sync.Mutex
+	subscribers  map[*LiveSubscriber]bool
+}
+
+// Create a new live service
+func NewLiveService(conn *amqp.Connection, redisAddr string) (*LiveService, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	dynamoDBRepo, err := repositories.NewDynamoDBRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	redisRepo, err := repositories.NewRedisRepo(redisAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LiveService{
+		conn:         conn,
+		channel:      channel,
+		dynamoDBRepo: dynamoDBRepo,
+		redisRepo:    redisRepo,
+		subscribers:  make(map[*LiveSubscriber]bool),
+	}, nil
+}
+
+// Initialize the live service
+// Every backend instance binds its own queue to the live events exchange, so each
+// instance receives every event and forwards it to the clients connected to it
+func (lv *LiveService) Start() {
+	err := declareLiveEventsExchange(lv.channel)
+	if err != nil {
+		log.Fatalf("Failed to declare an exchange: %v", err)
+	}
+
+	q, err := lv.channel.QueueDeclare(
+		"",
+		false,
+		true,
+		true,
+		false,
+		nil,
+	)
+	if err != nil {
+		log.Fatalf("Failed to declare a queue: %v", err)
+	}
+
+	err = lv.channel.QueueBind(q.Name, "", liveEventsExchange, false, nil)
+	if err != nil {
+		log.Fatalf("Failed to bind a queue: %v", err)
+	}
+
+	msgs, err := lv.channel.Consume(
+		q.Name,
+		"",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		log.Fatalf("Failed to register a consumer: %v", err)
+	}
+
+	for msg := range msgs {
+		var event models.LiveEvent
+		err := json.Unmarshal(msg.Body, &event)
+		if err != nil {
+			log.Printf("Failed to unmarshal live event: %v", err)
+			continue
+		}
+		lv.dispatch(event)
+	}
+}
+
+// Stop the live service
+func (lv *LiveService) Stop() {
+	log.Println("Stopping live service...")
+	if err := lv.channel.Close(); err != nil {
+		log.Printf("Error closing channel: %v", err)
+	}
+
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+	for subscriber := range lv.subscribers {
+		close(subscriber.Messages)
+		delete(lv.subscribers, subscriber)
+	}
+}
+
+// Subscribe a user to the live leaderboard of their group in the active tournament
+// Returns the subscriber and a snapshot of the current group standings
+func (lv *LiveService) Subscribe(username string) (*LiveSubscriber, []byte, error) {
+	tournamentID, err := lv.dynamoDBRepo.GetLatestTournamentForUser(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tournamentID == "" {
+		return nil, nil, errors.New("User is not registered to an active tournament")
+	}
+
+	isTournamentActive, err := lv.dynamoDBRepo.IsTournamentActive(tournamentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isTournamentActive {
+		return nil, nil, errors.New("User is not registered to an active tournament")
+	}
+
+	groupID, err := lv.dynamoDBRepo.GetLatestGroupIdForUser(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	if groupID < 0 {
+		return nil, nil, errors.New("User is not assigned to any group")
+	}
+
+	leaderboard, err := lv.redisRepo.GetGroupLeaderboardWithRanks(tournamentID+":"+strconv.Itoa(groupID), 0, 34)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subscriber := &LiveSubscriber{
+		Username:     username,
+		TournamentID: tournamentID,
+		GroupID:      groupID,
+		Messages:     make(chan []byte, liveSubscriberBuffer),
+	}
+
+	snapshot, err := buildLeaderboardMessage("leaderboard_snapshot", subscriber, leaderboard)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lv.mu.Lock()
+	lv.subscribers[subscriber] = true
+	lv.mu.Unlock()
+
+	return subscriber, snapshot, nil
+}
+
+// Unsubscribe a client when its connection closes
+func (lv *LiveService) Unsubscribe(subscriber *LiveSubscriber) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	if lv.subscribers[subscriber] {
+		close(subscriber.Messages)
+		delete(lv.subscribers, subscriber)
+	}
+}
+
+// Forward an event to the subscribers it concerns
+func (lv *LiveService) dispatch(event models.LiveEvent) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	for subscriber := range lv.subscribers {
+		if subscriber.TournamentID != event.TournamentID {
+			continue
+		}
+		if event.GroupID != 0 && subscriber.GroupID != event.GroupID {
+			continue
+		}
+
+		message, err := buildLiveMessage(subscriber, event)
+		if err != nil {
+			log.Printf("Failed to build live message: %v", err)
+			continue
+		}
+		if message == nil {
+			continue
+		}
+
+		// Never block the dispatcher on a slow client
+		select {
+		case subscriber.Messages <- message:
+		default:
+			log.Printf("Dropped live message for slow client %s", subscriber.Username)
+		}
+	}
+}
+
+// Build the message sent to a subscriber for an event, personalised with their own standing
+func buildLiveMessage(subscriber *LiveSubscriber, event models.LiveEvent) ([]byte, error) {
+	switch event.Type {
+	case "leaderboard_update":
+		// Events crossed the exchange as JSON, so the leaderboard has to be decoded again
+		eventData, err := json.Marshal(event.Data)
+		if err != nil {
+			return nil, err
+		}
+		var leaderboard []map[string]interface{}
+		err = json.Unmarshal(eventData, &leaderboard)
+		if err != nil {
+			return nil, err
+		}
+		return buildLeaderboardMessage(event.Type, subscriber, leaderboard)
+
+	case "rewards_ready":
+		eventData, err := json.Marshal(event.Data)
+		if err != nil {
+			return nil, err
+		}
+		var rankedPlayers []models.UserInTournament
+		err = json.Unmarshal(eventData, &rankedPlayers)
+		if err != nil {
+			return nil, err
+		}
+
+		rank, reward, rewardCurrency := 0, 0, ""
+		for _, player := range rankedPlayers {
+			if player.Username == subscriber.Username {
+				rank = player.Rank
+				reward = RewardForPlacement(player.League, player.Rank)
+				_, league := config.LeagueByName(player.League)
+				rewardCurrency = league.RewardCurrency
+			}
+		}
+		return json.Marshal(map[string]interface{}{
+			"type":            event.Type,
+			"tournament_id":   event.TournamentID,
+			"rank":            rank,
+			"reward":          reward,
+			"reward_currency": rewardCurrency,
+			"time":            event.Time,
+		})
+
+	default:
+		return json.Marshal(map[string]interface{}{
+			"type":          event.Type,
+			"tournament_id": event.TournamentID,
+			"data":          event.Data,
+			"time":          event.Time,
+		})
+	}
+}
+
+// Build a group leaderboard message with the subscriber's own rank and score
+func buildLeaderboardMessage(messageType string, subscriber *LiveSubscriber, leaderboard []map[string]interface{}) ([]byte, error) {
+	rank, score := 0, 0
+	for _, entry := range leaderboard {
+		if member, ok := entry["member"].(string); ok && member == subscriber.Username {
+			rank = toInt(entry["rank"])
+			score = toInt(entry["score"])
+		}
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"type":          messageType,
+		"tournament_id": subscriber.TournamentID,
+		"group_id":      subscriber.GroupID,
+		"rank":          rank,
+		"score":         score,
+		"leaderboard":   leaderboard,
+	})
+}
+
+// Convert a number read from Redis or decoded from JSON to an int
+func toInt(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"cloudblast-backend/config"
+	"cloudblast-backend/internal/models"
+	"cloudblast-backend/internal/repositories"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrepareMailRejectsInvalidRewards(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		rewards []models.MailReward
+		err     string
+	}{
+		{"same currency twice", []models.MailReward{{Currency: "gems", Amount: 5}, {Currency: "gems", Amount: 5}}, "more than once"},
+		{"coins twice, once by default", []models.MailReward{{Amount: 50}, {Currency: "coins", Amount: 50}}, "more than once"},
+		{"unknown currency", []models.MailReward{{Currency: "stars", Amount: 5}}, repositories.ErrUnknownCurrency.Error()},
+		{"nothing to pay", []models.MailReward{{Currency: "gems", Amount: 0}}, "cannot carry"},
+		{"a debit", []models.MailReward{{Currency: "coins", Amount: -10}}, "cannot carry"},
+		{"too many rewards", make([]models.MailReward, config.MaxMailRewards+1), "at most"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mail := &models.Mail{Username: "alice", MailID: "gift", Title: "Gift", Rewards: test.rewards}
+			err := prepareMail(mail, now)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("prepareMail() = %v, want an error containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestPrepareMailFillsDefaults(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	claimed := now.Add(-time.Hour)
+	mail := &models.Mail{
+		Username:  "alice",
+		MailID:    "gift",
+		Title:     "Gift",
+		Rewards:   []models.MailReward{{Amount: 50}, {Currency: "gems", Amount: 5}},
+		ClaimedAt: &claimed,
+	}
+
+	if err := prepareMail(mail, now); err != nil {
+		t.Fatal(err)
+	}
+	if mail.Rewards[0].Currency != config.DefaultCurrency {
+		t.Errorf("reward without a currency pays %q, want %q", mail.Rewards[0].Currency, config.DefaultCurrency)
+	}
+	if mail.ClaimedAt != nil {
+		t.Error("a sent mail is already claimed")
+	}
+	if !mail.SentAt.Equal(now) || !mail.ExpiresAt.Equal(now.Add(config.MailLifetime)) {
+		t.Errorf("sent at %v expiring at %v, want %v and %v", mail.SentAt, mail.ExpiresAt, now, now.Add(config.MailLifetime))
+	}
+	if want := now.Add(config.MailLifetime + config.MailRetention).Unix(); mail.DeleteAt != want {
+		t.Errorf("deleted at %d, want %d", mail.DeleteAt, want)
+	}
+
+	// A mail may not be sent already expired
+	expired := &models.Mail{Username: "alice", MailID: "late", Title: "Late", ExpiresAt: now}
+	if err := prepareMail(expired, now); err == nil {
+		t.Error("an expired mail was accepted")
+	}
+}
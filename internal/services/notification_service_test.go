@@ -0,0 +1,29 @@
+package services
+
+import "testing"
+
+func TestCompareNotificationIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want int
+	}{
+		{"same ID", "1700000000000-3", "1700000000000-3", 0},
+		{"older time", "1700000000000-9", "1700000000001-0", -1},
+		{"newer time", "1700000000001-0", "1700000000000-9", 1},
+		{"same time, lower sequence", "1700000000000-1", "1700000000000-2", -1},
+		{"same time, higher sequence", "1700000000000-2", "1700000000000-1", 1},
+		{"times are compared as numbers", "999-0", "1000-0", -1},
+		{"sequences are compared as numbers", "1000-10", "1000-9", 1},
+		{"a missing sequence is 0", "1000", "1000-0", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := CompareNotificationIDs(test.a, test.b); got != test.want {
+				t.Errorf("CompareNotificationIDs(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"cloudblast-backend/config"
+	"cloudblast-backend/internal/models"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGlickoUpdate(t *testing.T) {
+	tests := []struct {
+		name       string
+		player     models.SkillRating
+		opponents  []models.SkillRating
+		scores     []float64
+		rating     float64
+		deviation  float64
+		volatility float64
+	}{
+		{
+			// The worked example of Glickman's Glicko-2 paper
+			name:      "rating period of the Glicko-2 paper",
+			player:    models.SkillRating{Rating: 1500, Deviation: 200, Volatility: 0.06},
+			opponents: []models.SkillRating{{Rating: 1400, Deviation: 30}, {Rating: 1550, Deviation: 100}, {Rating: 1700, Deviation: 300}},
+			scores:    []float64{1, 0, 0},
+			rating:    1464.06, deviation: 151.52, volatility: 0.05999,
+		},
+		{
+			name:      "win against an equal opponent",
+			player:    models.SkillRating{Rating: 1500, Deviation: 350, Volatility: 0.06},
+			opponents: []models.SkillRating{{Rating: 1500, Deviation: 350}},
+			scores:    []float64{1},
+			rating:    1662.31, deviation: 290.32, volatility: 0.06,
+		},
+		{
+			name:      "draw against an equal opponent",
+			player:    models.SkillRating{Rating: 1500, Deviation: 350, Volatility: 0.06},
+			opponents: []models.SkillRating{{Rating: 1500, Deviation: 350}},
+			scores:    []float64{0.5},
+			rating:    1500, deviation: 290.32, volatility: 0.06,
+		},
+		{
+			name:   "no opponents only grows the deviation",
+			player: models.SkillRating{Rating: 1600, Deviation: 50, Volatility: 0.06},
+			rating: 1600, deviation: 51.07, volatility: 0.06,
+		},
+		{
+			name:   "no opponents never grows the deviation past a new player's",
+			player: models.SkillRating{Rating: 1600, Deviation: config.RatingInitialDeviation, Volatility: 0.06},
+			rating: 1600, deviation: config.RatingInitialDeviation, volatility: 0.06,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			updated := glickoUpdate(test.player, test.opponents, test.scores)
+			if math.Abs(updated.Rating-test.rating) > 0.01 {
+				t.Errorf("rating = %.4f, want %.2f", updated.Rating, test.rating)
+			}
+			if math.Abs(updated.Deviation-test.deviation) > 0.01 {
+				t.Errorf("deviation = %.4f, want %.2f", updated.Deviation, test.deviation)
+			}
+			if math.Abs(updated.Volatility-test.volatility) > 0.00001 {
+				t.Errorf("volatility = %.6f, want %.5f", updated.Volatility, test.volatility)
+			}
+		})
+	}
+}
+
+func TestRateTournamentGroupsUsesScores(t *testing.T) {
+	endTime := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+	rate := func(group []models.UserInTournament) map[string]models.RatingHistoryEntry {
+		entries := rateTournamentGroups("t1", endTime, map[int][]models.UserInTournament{0: group}, map[string]*models.SkillRating{})
+		byUser := make(map[string]models.RatingHistoryEntry, len(entries))
+		for _, entry := range entries {
+			byUser[entry.Username] = entry
+		}
+		return byUser
+	}
+
+	// Players with the same score draw, so new players with the same rating keep it
+	tied := rate([]models.UserInTournament{
+		{Username: "alice", Score: 7, GroupRank: 1},
+		{Username: "bob", Score: 7, GroupRank: 2},
+		{Username: "carol", Score: 7, GroupRank: 3},
+	})
+	for username, entry := range tied {
+		if math.Abs(entry.Change) > 1e-9 {
+			t.Errorf("%s changed by %.4f with a tied score, want 0", username, entry.Change)
+		}
+	}
+
+	// Group ranks that disagree with the scores, e.g. ranks written before a score was cleared, are ignored
+	reordered := rate([]models.UserInTournament{
+		{Username: "carol", Score: 10, GroupRank: 1},
+		{Username: "alice", Score: 20, GroupRank: 2},
+		{Username: "bob", Score: 30, GroupRank: 3},
+	})
+	if !(reordered["bob"].Change > 0 && math.Abs(reordered["alice"].Change) < 1e-9 && reordered["carol"].Change < 0) {
+		t.Errorf("changes = bob %.2f, alice %.2f, carol %.2f, want bob up, alice unchanged, carol down",
+			reordered["bob"].Change, reordered["alice"].Change, reordered["carol"].Change)
+	}
+	if math.Abs(reordered["bob"].Change+reordered["carol"].Change) > 1e-6 {
+		t.Errorf("bob gained %.4f but carol lost %.4f, want the same amount", reordered["bob"].Change, -reordered["carol"].Change)
+	}
+
+	// The order the group is listed in does not change the outcome
+	listed := rate([]models.UserInTournament{
+		{Username: "bob", Score: 30, GroupRank: 3},
+		{Username: "carol", Score: 10, GroupRank: 1},
+		{Username: "alice", Score: 20, GroupRank: 2},
+	})
+	for username, entry := range reordered {
+		if math.Abs(listed[username].Rating-entry.Rating) > 1e-9 {
+			t.Errorf("%s rated %.4f when listed in another order, want %.4f", username, listed[username].Rating, entry.Rating)
+		}
+	}
+
+	// A head-to-head win between new players matches the Glicko-2 update of a single win
+	won := rate([]models.UserInTournament{
+		{Username: "alice", Score: 1, GroupRank: 2},
+		{Username: "bob", Score: 0, GroupRank: 1},
+	})
+	if math.Abs(won["alice"].Rating-1662.31) > 0.01 || math.Abs(won["bob"].Rating-1337.69) > 0.01 {
+		t.Errorf("ratings = alice %.2f, bob %.2f, want 1662.31 and 1337.69", won["alice"].Rating, won["bob"].Rating)
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"cloudblast-backend/config"
+	"testing"
+	"time"
+)
+
+// Run a test with a season period and epoch, restoring the configured ones afterwards
+func withSeasonConfig(t *testing.T, period string, epoch time.Time) {
+	previousPeriod, previousEpoch := config.SeasonPeriod, config.SeasonEpoch
+	config.SeasonPeriod, config.SeasonEpoch = period, epoch
+	t.Cleanup(func() {
+		config.SeasonPeriod, config.SeasonEpoch = previousPeriod, previousEpoch
+	})
+}
+
+func TestSeasonNumberForTime(t *testing.T) {
+	epoch := time.Date(2023, time.September, 4, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		period string
+		at     time.Time
+		want   int
+	}{
+		{"weekly before the epoch", "weekly", epoch.Add(-time.Hour), 1},
+		{"weekly at the epoch", "weekly", epoch, 1},
+		{"weekly end of the first season", "weekly", epoch.Add(7*24*time.Hour - time.Nanosecond), 1},
+		{"weekly start of the second season", "weekly", epoch.Add(7 * 24 * time.Hour), 2},
+		{"weekly a year later", "weekly", epoch.AddDate(0, 0, 364), 53},
+		{"monthly before the epoch", "monthly", epoch.AddDate(0, -2, 0), 1},
+		{"monthly in the epoch's month", "monthly", time.Date(2023, time.September, 30, 23, 59, 59, 0, time.UTC), 1},
+		{"monthly next month", "monthly", time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC), 2},
+		{"monthly across a new year", "monthly", time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC), 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withSeasonConfig(t, test.period, epoch)
+			if got := seasonNumberForTime(test.at); got != test.want {
+				t.Errorf("seasonNumberForTime(%v) = %d, want %d", test.at, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSeasonBounds(t *testing.T) {
+	epoch := time.Date(2023, time.September, 4, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		period string
+		number int
+		start  time.Time
+		end    time.Time
+	}{
+		{"weekly first season", "weekly", 1, epoch, epoch.AddDate(0, 0, 7)},
+		{"weekly third season", "weekly", 3, epoch.AddDate(0, 0, 14), epoch.AddDate(0, 0, 21)},
+		{"monthly first season starts on the first of the month", "monthly", 1,
+			time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC)},
+		{"monthly across a new year", "monthly", 5,
+			time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withSeasonConfig(t, test.period, epoch)
+			start, end := seasonBounds(test.number)
+			if !start.Equal(test.start) || !end.Equal(test.end) {
+				t.Errorf("seasonBounds(%d) = %v, %v, want %v, %v", test.number, start, end, test.start, test.end)
+			}
+
+			// Every time within a season belongs to that season
+			for _, at := range []time.Time{start, end.Add(-time.Nanosecond)} {
+				if got := seasonNumberForTime(at); got != test.number {
+					t.Errorf("seasonNumberForTime(%v) = %d, want %d", at, got, test.number)
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package services
 
 import (
+	"cloudblast-backend/internal/models"
 	"encoding/json"
 	"log"
+	"time"
 
 	"github.com/streadway/amqp"
 )
@@ -67,4 +69,51 @@ func publishToRabbitMQ(ch *amqp.Channel, queueName, action string, data interfac
 	if err != nil {
 		log.Fatalf("Failed to publish a message: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+// Fanout exchange that delivers live tournament events to every backend instance
+const liveEventsExchange = "liveEvents"
+
+// Declare the live events exchange on a channel
+func declareLiveEventsExchange(ch *amqp.Channel) error {
+	return ch.ExchangeDeclare(
+		liveEventsExchange,
+		"fanout",
+		false,
+		false,
+		false,
+		false,
+		nil,
+	)
+}
+
+// Publish a live tournament event to all backend instances
+func publishLiveEvent(ch *amqp.Channel, event models.LiveEvent) {
+	event.Time = time.Now().UTC()
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode live event to JSON: %v", err)
+		return
+	}
+
+	err = ch.Publish(
+		liveEventsExchange,
+		"",
+		false,
+		false,
+		amqp.Publishing{
+			ContentType: "application/json",
+			Body:        eventJSON,
+		},
+	)
+	if err != nil {
+		log.Printf("Failed to publish live event: %v", err)
+	}
+}
+
+// Publish a notification to the notification service, which stores it and streams it to players
+func publishNotification(ch *amqp.Channel, notification models.Notification) {
+	notification.Time = time.Now().UTC()
+	publishToRabbitMQ(ch, "notificationQueue", "RecordNotification", notification, "", "")
+}
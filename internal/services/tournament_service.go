@@ -2,9 +2,11 @@ package services
 
 import (
 	"encoding/json"
+	"cloudblast-backend/config"
 	"cloudblast-backend/internal/models"
 	"cloudblast-backend/internal/repositories"
 	"log"
+	"sort"
 	"strconv"
 	"time"
 
@@ -16,10 +18,12 @@ type TournamentService struct {
 	conn        *amqp.Connection
 	channel     *amqp.Channel
 	dynamoDBRepo *repositories.DynamoDBRepository
+	redisRepo   *repositories.RedisRepo
+	stopChan    chan struct{}
 }
 
 // Create a new tournament service
-func NewTournamentService(conn *amqp.Connection) (*TournamentService, error) {
+func NewTournamentService(conn *amqp.Connection, redisAddr string) (*TournamentService, error) {
 	channel, err := conn.Channel()
 	if err != nil {
 		return nil, err
@@ -30,10 +34,23 @@ func NewTournamentService(conn *amqp.Connection) (*TournamentService, error) {
 		return nil, err
 	}
 
+	redisRepo, err := repositories.NewRedisRepo(redisAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Declare the exchange used to push tournament lifecycle events to connected clients
+	err = declareLiveEventsExchange(channel)
+	if err != nil {
+		return nil, err
+	}
+
 	return &TournamentService{
 		conn:        conn,
 		channel:     channel,
 		dynamoDBRepo: dynamoDBRepo,
+		redisRepo:   redisRepo,
+		stopChan:    make(chan struct{}),
 	}, nil
 }
 
@@ -83,6 +100,9 @@ func (ts *TournamentService) Start() {
 		log.Fatalf("Failed to register a consumer: %v", err)
 	}
 
+    // Tell players when the current tournament is about to end
+	go ts.runEndingSoonNotifier()
+
     // Handle messages received on the "tournamentQueue" queue
 	for msg := range msgs {
 		action, ok := msg.Headers["action"].(string)
@@ -103,6 +123,8 @@ func (ts *TournamentService) Start() {
 			ts.HandleClaimReward(msg.Body, msg.ReplyTo, msg.CorrelationId)
 		case "EndTournament":
 			ts.EndTournament(msg.Body, msg.ReplyTo, msg.CorrelationId)
+		case "GetLeague":
+			ts.HandleGetLeague(msg.Body, msg.ReplyTo, msg.CorrelationId)
 		default:
 			log.Printf("Unknown action: %s", action)
 		}
@@ -112,6 +134,7 @@ func (ts *TournamentService) Start() {
 // Stop the tournament service
 func (ts *TournamentService) Stop() {
 	log.Println("Stopping tournament service...")
+	close(ts.stopChan)
 	if err := ts.channel.Close(); err != nil {
 		log.Printf("Error closing channel: %v", err)
 	}
@@ -169,6 +192,15 @@ func (ts *TournamentService) HandleStartTournament(data []byte, replyTo string,
 		Finished: tournament.Finished,
 	})
 
+	publishNotification(ts.channel, models.Notification{
+		Type:         "tournament_started",
+		TournamentID: tournamentID,
+		Data: map[string]interface{}{
+			"start_time": tournament.StartTime,
+			"end_time":   tournament.EndTime,
+		},
+	})
+
 	log.Printf("Tournament started: %+v", tournament)
 }
 
@@ -285,6 +317,17 @@ func (ts *TournamentService) HandleEnterTournament(data []byte, replyTo string,
     // Update the user's latest group ID
     ts.dynamoDBRepo.UpdateUserField(requestData.Username, "latest_group_id", groupID)
 
+    recordAchievementEvent(ts.channel, requestData.Username, models.AchievementEventTournamentEntered, latestTournamentID, 1)
+
+    publishNotification(ts.channel, models.Notification{
+        Type:         "tournament_entered",
+        TournamentID: latestTournamentID,
+        Username:     requestData.Username,
+        Data: map[string]interface{}{
+            "group_id": groupID,
+        },
+    })
+
 	sendResponse(ts.channel, replyTo, correlationID, "EnterTournamentResponse", struct {
 		GroupID int `json:"group_id"`
 	}{
@@ -332,13 +375,55 @@ func (ts *TournamentService) HandleUpdateScore(data []byte, replyTo string, corr
 
     // If the tournament is active, increment the user's score in the tournament
     if isTournamentActive {
-        progressLevel, coins, score, err := ts.dynamoDBRepo.IncrementUserScoreInTournament(requestData.Username, latestTournamentID)
+        // Reject scores submitted faster than levels can be played
+        err = checkScoreSubmission(ts.redisRepo, requestData.Username, 0)
+        if err == ErrScoreTooFast || err == ErrTooManyScores {
+            sendResponse(ts.channel, replyTo, correlationID, "UpdateScoreResponse", struct {
+                Error string `json:"error"`
+            }{
+                Error: err.Error(),
+            })
+            return
+        }
+        if err != nil {
+            log.Printf("Failed to check score submission: %v", err)
+            sendResponse(ts.channel, replyTo, correlationID, "UpdateScoreResponse", struct {
+                Error string `json:"error"`
+            }{
+                Error: "Failed to check score submission",
+            })
+            return
+        }
+
+        user, err := ts.dynamoDBRepo.GetUserByUsername(requestData.Username)
+        if err != nil || user == nil {
+            sendResponse(ts.channel, replyTo, correlationID, "UpdateScoreResponse", struct {
+                Error string `json:"error"`
+            }{
+                Error: "User not found",
+            })
+            return
+        }
+
+        // Live events can multiply the level's coins and tournament point
+        events := activeEvents(ts.redisRepo, time.Now().UTC())
+        coinsBoost := eventBoostFor(events, user, models.EventEffectLevelCoins)
+        pointsBoost := eventBoostFor(events, user, models.EventEffectTournamentPoints)
+        coinsEarned := coinsBoost.apply(100)
+        pointsEarned := pointsBoost.apply(1)
+
+        // Every level played uses a life, in the same transaction as the level, its points and its coins
+        progressLevel, result, err := ts.dynamoDBRepo.IncrementUserScoreInTournament(requestData.Username, latestTournamentID, pointsEarned, coinsEarned, coinsBoost.EventID)
         if err != nil {
-			log.Printf("Failed to increment user score in tournament: %v", err)
+            message := err.Error()
+            if err != repositories.ErrNoLives {
+                log.Printf("Failed to increment user score in tournament: %v", err)
+                message = "Failed to increment user score in tournament"
+            }
             sendResponse(ts.channel, replyTo, correlationID, "UpdateScoreResponse", struct {
                 Error string `json:"error"`
             }{
-                Error: "Failed to increment user score in tournament",
+                Error: message,
             })
             return
         }
@@ -376,21 +461,46 @@ func (ts *TournamentService) HandleUpdateScore(data []byte, replyTo string, corr
             "group_id": latestGroupID,
             "leaderboard_name": newLeaderboardName,
             "username": requestData.Username,
+            "amount": pointsEarned,
         }
         // Publish the message to the "leaderboardQueue" with the publishToRabbitMQ function
         log.Printf("messageData: %v", messageData)
         publishToRabbitMQ(ts.channel, "leaderboardQueue", action, messageData, replyTo, correlationID)
 
         log.Printf("Sent incrementGroupScore action to LeaderboardService")
+
+        // Count the level, coins and tournament point in the current season, in running challenges and towards achievements
+        recordSeasonProgress(ts.channel, requestData.Username, 1, coinsEarned, pointsEarned)
+        recordChallengeScore(ts.channel, requestData.Username, 1, time.Now().UTC())
+        recordAchievementEvent(ts.channel, requestData.Username, models.AchievementEventLevelUp, strconv.Itoa(progressLevel), 1)
+        recordAchievementEvent(ts.channel, requestData.Username, models.AchievementEventCoinsEarned, repositories.LevelRewardTransactionID(requestData.Username, progressLevel), coinsEarned)
+
+        publishNotification(ts.channel, models.Notification{
+            Type:     "level_up",
+            Username: requestData.Username,
+            Data: map[string]interface{}{
+                "progress_level": progressLevel,
+            },
+        })
         
         sendResponse(ts.channel, replyTo, correlationID, "UpdateScoreResponse", struct {
             Progress_Level int `json:"progress_level"`
             Coins         int `json:"coins"`
             Score         int `json:"score"`
+            Lives         int `json:"lives"`
+            CoinsEarned   int `json:"coins_earned"`
+            PointsEarned  int `json:"points_earned"`
+            CoinsEventID  string `json:"coins_event_id,omitempty"`
+            PointsEventID string `json:"points_event_id,omitempty"`
         }{
             Progress_Level: progressLevel,
-            Coins:         coins,
-            Score:         score,
+            Coins:         result.Entry.Balance,
+            Score:         result.Score,
+            Lives:         result.Lives.Lives,
+            CoinsEarned:   coinsEarned,
+            PointsEarned:  pointsEarned,
+            CoinsEventID:  coinsBoost.EventID,
+            PointsEventID: pointsBoost.EventID,
         })
     } else {
         sendResponse(ts.channel, replyTo, correlationID, "UpdateScoreResponse", struct {
@@ -428,6 +538,11 @@ func (ts *TournamentService) EndTournament(data []byte, replyTo string, correlat
         return
     }
 
+    publishNotification(ts.channel, models.Notification{
+        Type:         "tournament_ended",
+        TournamentID: tournamentID,
+    })
+
     // Get the ranked players for the latest tournament
     rankedPlayers, err := ts.dynamoDBRepo.FindRankedPlayersForLatestTournament()
     if err != nil {
@@ -441,6 +556,60 @@ func (ts *TournamentService) EndTournament(data []byte, replyTo string, correlat
     }
 
 
+    // Promote and relegate players based on their placement in their group
+    err = ts.applyLeagueMovements(tournamentID)
+    if err != nil {
+        log.Printf("Failed to apply league movements: %v", err)
+        sendResponse(ts.channel, replyTo, correlationID, "EndTournamentResponse", struct {
+            Error string `json:"error"`
+        }{
+            Error: "Failed to apply league movements",
+        })
+        return
+    }
+
+    // Tell connected clients that the tournament ended and its rewards can be claimed
+    publishLiveEvent(ts.channel, models.LiveEvent{
+        Type:         "tournament_ended",
+        TournamentID: tournamentID,
+    })
+    publishLiveEvent(ts.channel, models.LiveEvent{
+        Type:         "rewards_ready",
+        TournamentID: tournamentID,
+        Data:         rankedPlayers,
+    })
+
+    // Notify players that the results are settled and which of them can claim a reward
+    publishNotification(ts.channel, models.Notification{
+        Type:         "results_settled",
+        TournamentID: tournamentID,
+        Data: map[string]interface{}{
+            "ranked_players": rankedPlayers,
+        },
+    })
+    for _, player := range rankedPlayers {
+        if player.Rank >= 1 && player.Rank <= 3 {
+            recordAchievementEvent(ts.channel, player.Username, models.AchievementEventPodium, tournamentID, 1)
+        }
+        if player.Rank == 1 {
+            recordAchievementEvent(ts.channel, player.Username, models.AchievementEventTournamentWon, tournamentID, 1)
+        }
+
+        reward := RewardForPlacement(player.League, player.Rank)
+        if reward <= 0 {
+            continue
+        }
+        publishNotification(ts.channel, models.Notification{
+            Type:         "reward_claimable",
+            TournamentID: tournamentID,
+            Username:     player.Username,
+            Data: map[string]interface{}{
+                "rank":   player.Rank,
+                "reward": reward,
+            },
+        })
+    }
+
     // Send DeleteLeaderboard action to LeaderboardService
 	action := "DeleteLeaderboard" // Define the action
     messageData := map[string]interface{}{
@@ -452,6 +621,32 @@ func (ts *TournamentService) EndTournament(data []byte, replyTo string, correlat
 
     log.Printf("Sent deleteLeaderboard action to LeaderboardService")
 
+    // Send RecordTournamentResults action to LeaderboardService to update the hall of fame
+    action = "RecordTournamentResults"
+    messageData = map[string]interface{}{
+        "action":         action,
+        "tournament_id":  tournamentID,
+        "ranked_players": rankedPlayers,
+    }
+    publishToRabbitMQ(ts.channel, "leaderboardQueue", action, messageData, replyTo, correlationID)
+
+    log.Printf("Sent recordTournamentResults action to LeaderboardService")
+
+    // Pay the clans of the team tournament that ran alongside it
+    action = "SettleTeamTournament"
+    messageData = map[string]interface{}{
+        "action":        action,
+        "tournament_id": tournamentID,
+    }
+    publishToRabbitMQ(ts.channel, "clanQueue", action, messageData, "", "")
+
+    // Update the players' skill ratings from their group placements
+    action = "RateTournament"
+    messageData = map[string]interface{}{
+        "action":        action,
+        "tournament_id": tournamentID,
+    }
+    publishToRabbitMQ(ts.channel, "ratingQueue", action, messageData, "", "")
 
     sendResponse(ts.channel, replyTo, correlationID, "EndTournamentResponse", struct {
         RankedPlayers []models.UserInTournament `json:"ranked_players"`
@@ -548,49 +743,39 @@ func (ts *TournamentService) HandleClaimReward(data []byte, replyTo string, corr
         return
     }
 
-    // Calculate the reward amount based on the user's rank
-    var rewardAmount int
-    switch userInTournament.Rank {
-    case 1:
-        rewardAmount = 5000
-    case 2:
-        rewardAmount = 3000
-    case 3:
-        rewardAmount = 2000
-    case 4:
-        rewardAmount = 1000
-    default:
-        rewardAmount = 0
+    // Quarantined scores have no reward until support staff clear them
+    if userInTournament.Quarantined {
+        sendResponse(ts.channel, replyTo, correlationID, "ClaimRewardResponse", struct {
+            Error string `json:"error"`
+        }{
+            Error: "Reward is on hold until your scores are reviewed",
+        })
+        return
     }
 
-	// Get the user's current coin balance
-	user, err := ts.dynamoDBRepo.GetUserByUsername(username)
-	if err != nil {
-		sendResponse(ts.channel, replyTo, correlationID, "ClaimRewardResponse", struct {
-			Error string `json:"error"`
-		}{
-			Error: "Failed to get user's data",
-		})
-		return
-	}
-	if err != nil {
-		sendResponse(ts.channel, replyTo, correlationID, "ClaimRewardResponse", struct {
-			Error string `json:"error"`
-		}{
-			Error: "Failed to get user's data",
-		})
-		return
-	}
-
-	// Update user's coin balance and mark the reward as claimed
-	err = ts.dynamoDBRepo.UpdateUserField(username, "coins", user.Coins+rewardAmount)
-	if err != nil {
-		sendResponse(ts.channel, replyTo, correlationID, "ClaimRewardResponse", struct {
-			Error string `json:"error"`
-		}{
-			Error: "Failed to update user's coin balance",
+    // Calculate the reward amount based on the user's rank, paid in the league's reward currency
+    rewardAmount := RewardForPlacement(userInTournament.League, userInTournament.Rank)
+    _, league := config.LeagueByName(userInTournament.League)
+
+	// Pay the prize, the transaction ID makes sure it is paid once even if claims race
+	if rewardAmount > 0 {
+		_, err = ts.dynamoDBRepo.PostWalletTransaction(repositories.WalletTransaction{
+			TransactionID: models.LedgerTournamentPrize + ":" + latestTournamentID + ":" + username,
+			Username:      username,
+			Currency:      league.RewardCurrency,
+			Counterparty:  models.SystemAccountRewards,
+			Type:          models.LedgerTournamentPrize,
+			Amount:        rewardAmount,
+			Reference:     latestTournamentID,
 		})
-		return
+		if err != nil {
+			sendResponse(ts.channel, replyTo, correlationID, "ClaimRewardResponse", struct {
+				Error string `json:"error"`
+			}{
+				Error: "Failed to update user's balance",
+			})
+			return
+		}
 	}
 
     // Mark the reward as claimed
@@ -604,12 +789,288 @@ func (ts *TournamentService) HandleClaimReward(data []byte, replyTo string, corr
         return
     }
 
+    // Count the tournament reward in the current season
+    if rewardAmount > 0 && league.RewardCurrency == config.DefaultCurrency {
+        recordSeasonProgress(ts.channel, username, 0, rewardAmount, 0)
+        recordAchievementEvent(ts.channel, username, models.AchievementEventCoinsEarned, models.LedgerTournamentPrize+":"+latestTournamentID, rewardAmount)
+    }
+
     sendResponse(ts.channel, replyTo, correlationID, "ClaimRewardResponse", struct {
-        Success       bool `json:"success"`
-        RewardClaimed int  `json:"reward_claimed"`
+        Success        bool   `json:"success"`
+        RewardClaimed  int    `json:"reward_claimed"`
+        RewardCurrency string `json:"reward_currency"`
     }{
-        Success:       true,
-        RewardClaimed: rewardAmount,
+        Success:        true,
+        RewardClaimed:  rewardAmount,
+        RewardCurrency: league.RewardCurrency,
     })
 }
 
+// Get the coin reward for a final tournament rank
+func RewardForRank(rank int) int {
+    switch rank {
+    case 1:
+        return 5000
+    case 2:
+        return 3000
+    case 3:
+        return 2000
+    case 4:
+        return 1000
+    default:
+        return 0
+    }
+}
+
+// Get the coin reward for a final tournament rank, scaled by the league the user played in
+func RewardForPlacement(league string, rank int) int {
+    _, settings := config.LeagueByName(league)
+    return int(float64(RewardForRank(rank)) * settings.RewardMultiplier)
+}
+
+// Get the name of a user's league, users without one are in the first league
+func leagueName(league string) string {
+    _, settings := config.LeagueByName(league)
+    return settings.Name
+}
+
+// Sort the users of a group by score, highest first
+func sortGroupByScore(usersInGroup []models.UserInTournament) {
+    sort.SliceStable(usersInGroup, func(i, j int) bool {
+        return usersInGroup[i].Score > usersInGroup[j].Score
+    })
+}
+
+// Get the league a player of a league moves to with a league change
+func leagueAfterChange(league string, leagueChange string) string {
+    index, _ := config.LeagueByName(league)
+    switch leagueChange {
+    case "promoted":
+        return config.Leagues[index+1].Name
+    case "relegated":
+        return config.Leagues[index-1].Name
+    }
+    return ""
+}
+
+// Get the league change earned by a placement in a group
+// Returns "promoted", "relegated" or an empty string
+func leagueChangeForPlacement(league string, groupRank int, groupSize int, score int) string {
+    index, settings := config.LeagueByName(league)
+
+    // Only players who scored at least once can be promoted
+    if index < len(config.Leagues)-1 && groupRank <= settings.PromoteTop && score > 0 {
+        return "promoted"
+    }
+    if index > 0 && groupRank > groupSize-settings.RelegateBottom {
+        return "relegated"
+    }
+    return ""
+}
+
+// Move every player of a finished tournament up or down a league based on their group placement
+// Each player is placed once, so applying the movements again after a failure only places the others
+func (ts *TournamentService) applyLeagueMovements(tournamentID string) error {
+    usersInTournament, err := ts.dynamoDBRepo.GetUsersInTournament(tournamentID)
+    if err != nil {
+        return err
+    }
+
+    // Sanctioned players keep their league and give up their group place
+    sanctioned, err := ts.dynamoDBRepo.GetSanctionedUsernames()
+    if err != nil {
+        return err
+    }
+
+    // Quarantined players are placed once their scores are cleared
+    groups := make(map[int][]models.UserInTournament)
+    for _, user := range usersInTournament {
+        if user.Quarantined || sanctioned[user.Username] {
+            continue
+        }
+        groups[user.GroupID] = append(groups[user.GroupID], user)
+    }
+
+    for _, usersInGroup := range groups {
+        sortGroupByScore(usersInGroup)
+
+        for i, user := range usersInGroup {
+            groupRank := i + 1
+            leagueChange := leagueChangeForPlacement(user.League, groupRank, len(usersInGroup), user.Score)
+
+            // Players placed by an earlier attempt keep their placement and league
+            _, err = ts.dynamoDBRepo.PlaceUserInTournament(user.Username, tournamentID, groupRank, leagueChange, leagueName(user.League), leagueAfterChange(user.League, leagueChange))
+            if err != nil {
+                return err
+            }
+        }
+    }
+
+    log.Printf("Applied league movements for tournament %s", tournamentID)
+    return nil
+}
+
+// Get a user's league and what they need to move up
+func (ts *TournamentService) HandleGetLeague(data []byte, replyTo string, correlationID string) {
+    var requestData struct {
+        Action   string `json:"action"`
+        Username string `json:"username"`
+    }
+
+    err := json.Unmarshal(data, &requestData)
+    if err != nil {
+        log.Printf("Failed to unmarshal data: %v", err)
+        return
+    }
+
+    user, err := ts.dynamoDBRepo.GetUserByUsername(requestData.Username)
+    if err != nil || user == nil {
+        sendResponse(ts.channel, replyTo, correlationID, "GetLeagueResponse", struct {
+            Error string `json:"error"`
+        }{
+            Error: "User not found",
+        })
+        return
+    }
+
+    index, league := config.LeagueByName(user.League)
+    nextLeague := ""
+    if index < len(config.Leagues)-1 {
+        nextLeague = config.Leagues[index+1].Name
+    }
+    previousLeague := ""
+    if index > 0 {
+        previousLeague = config.Leagues[index-1].Name
+    }
+
+    response := struct {
+        League           string  `json:"league"`
+        Tier             int     `json:"tier"`
+        EntryFee         int     `json:"entry_fee"`
+        EntryFeeCurrency string  `json:"entry_fee_currency"`
+        RewardMultiplier float64 `json:"reward_multiplier"`
+        RewardCurrency   string  `json:"reward_currency"`
+        NextLeague       string  `json:"next_league"`
+        PreviousLeague   string  `json:"previous_league"`
+        PromoteTop       int     `json:"promote_top"`
+        RelegateBottom   int     `json:"relegate_bottom"`
+        InTournament     bool    `json:"in_tournament"`
+        GroupRank        int     `json:"group_rank"`
+        GroupSize        int     `json:"group_size"`
+        PointsToPromote  int     `json:"points_to_promote"`
+    }{
+        League:           league.Name,
+        Tier:             index + 1,
+        EntryFee:         league.EntryFee,
+        EntryFeeCurrency: league.EntryFeeCurrency,
+        RewardMultiplier: league.RewardMultiplier,
+        RewardCurrency:   league.RewardCurrency,
+        NextLeague:       nextLeague,
+        PreviousLeague:   previousLeague,
+        PromoteTop:       league.PromoteTop,
+        RelegateBottom:   league.RelegateBottom,
+    }
+
+    // Show the live placement if the user is playing in an active tournament
+    if user.Latest_Tournament_ID != "" {
+        isTournamentActive, err := ts.dynamoDBRepo.IsTournamentActive(user.Latest_Tournament_ID)
+        if err != nil {
+            log.Printf("Failed to check if tournament is active: %v", err)
+        }
+
+        if isTournamentActive {
+            usersInGroup, err := ts.dynamoDBRepo.GetUsersInGroup(user.Latest_Tournament_ID, user.Latest_Group_ID)
+            if err != nil {
+                sendResponse(ts.channel, replyTo, correlationID, "GetLeagueResponse", struct {
+                    Error string `json:"error"`
+                }{
+                    Error: "Failed to get user's group",
+                })
+                return
+            }
+            sortGroupByScore(usersInGroup)
+
+            for i, groupUser := range usersInGroup {
+                if groupUser.Username != user.Username {
+                    continue
+                }
+                response.InTournament = true
+                response.GroupRank = i + 1
+                response.GroupSize = len(usersInGroup)
+
+                // Points needed to pass the player on the last promotion place
+                if nextLeague != "" && league.PromoteTop > 0 {
+                    lastPromoted := league.PromoteTop
+                    if lastPromoted > len(usersInGroup) {
+                        lastPromoted = len(usersInGroup)
+                    }
+                    if response.GroupRank > lastPromoted {
+                        response.PointsToPromote = usersInGroup[lastPromoted-1].Score - groupUser.Score + 1
+                    } else if groupUser.Score == 0 {
+                        response.PointsToPromote = 1
+                    }
+                }
+            }
+        }
+    }
+
+    sendResponse(ts.channel, replyTo, correlationID, "GetLeagueResponse", response)
+}
+
+// Check every minute whether the latest tournament is about to end
+func (ts *TournamentService) runEndingSoonNotifier() {
+    ticker := time.NewTicker(time.Minute)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ticker.C:
+            err := ts.notifyTournamentEndingSoon()
+            if err != nil {
+                log.Printf("Failed to notify that the tournament is ending soon: %v", err)
+            }
+        case <-ts.stopChan:
+            return
+        }
+    }
+}
+
+// Publish the ending soon events once per tournament, across all instances
+func (ts *TournamentService) notifyTournamentEndingSoon() error {
+    tournamentID, err := ts.dynamoDBRepo.GetLatestTournament()
+    if err != nil || tournamentID == "" {
+        return err
+    }
+
+    tournament, err := ts.dynamoDBRepo.GetTournamentByID(tournamentID)
+    if err != nil || tournament == nil {
+        return err
+    }
+
+    if tournament.Finished || tournament.EndingSoonNotified || time.Until(tournament.EndTime) > config.TournamentEndingSoonWindow {
+        return nil
+    }
+
+    marked, err := ts.dynamoDBRepo.MarkTournamentEndingSoonNotified(tournamentID)
+    if err != nil || !marked {
+        return err
+    }
+
+    publishLiveEvent(ts.channel, models.LiveEvent{
+        Type:         "tournament_ending_soon",
+        TournamentID: tournamentID,
+        Data: map[string]interface{}{
+            "end_time": tournament.EndTime,
+        },
+    })
+
+    // Registration stays open until the tournament ends
+    publishNotification(ts.channel, models.Notification{
+        Type:         "registration_closing",
+        TournamentID: tournamentID,
+        Data: map[string]interface{}{
+            "end_time": tournament.EndTime,
+        },
+    })
+    return nil
+}
@@ -1,11 +1,21 @@
 package services
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"cloudblast-backend/config"
 	"cloudblast-backend/internal/auth"
 	"cloudblast-backend/internal/models"
 	"cloudblast-backend/internal/repositories"
+	"cloudblast-backend/internal/validation"
 	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	// Timezones are embedded so check-in days can be counted on hosts without a timezone database
+	_ "time/tzdata"
 
 	"github.com/google/uuid"
 	"github.com/streadway/amqp"
@@ -16,10 +26,20 @@ type UserService struct {
 	conn        *amqp.Connection
 	channel     *amqp.Channel
 	dynamoDBRepo *repositories.DynamoDBRepository
+	redisRepo   *repositories.RedisRepo
+	mu          sync.RWMutex
+	nameFilter  *validation.NameFilter
+	stopChan    chan struct{}
 }
 
+// How often the name filter file is checked for a new version
+const nameFilterReloadPeriod = time.Minute
+
+// Set once the countries and username claims of the users created before validation existed are fixed
+const userProfilesMigratedKey = "migration:user-profiles:v1"
+
 // Create a new user service
-func NewUserService(conn *amqp.Connection) (*UserService, error) {
+func NewUserService(conn *amqp.Connection, redisAddr string) (*UserService, error) {
 	channel, err := conn.Channel()
 	if err != nil {
 		return nil, err
@@ -30,10 +50,23 @@ func NewUserService(conn *amqp.Connection) (*UserService, error) {
 		return nil, err
 	}
 
+	redisRepo, err := repositories.NewRedisRepo(redisAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	nameFilter, err := validation.LoadNameFilter(config.NameFilterPath)
+	if err != nil {
+		return nil, err
+	}
+
 	return &UserService{
 		conn:        conn,
 		channel:     channel,
 		dynamoDBRepo: dynamoDBRepo,
+		redisRepo:   redisRepo,
+		nameFilter:  nameFilter,
+		stopChan:    make(chan struct{}),
 	}, nil
 }
 // HashPassword hashes the given password using bcrypt for Create User
@@ -97,6 +130,17 @@ func (uh *UserService) Start() {
 		log.Fatalf("Failed to register a consumer: %v", err)
 	}
 
+    // Pick up new versions of the name filter file
+	go uh.runNameFilterReloader()
+
+    // Fix the profiles of the users created before they were validated
+	go func() {
+		err := uh.MigrateUserProfiles()
+		if err != nil {
+			log.Printf("Failed to migrate user profiles: %v", err)
+		}
+	}()
+
     // Handle messages
 	for msg := range msgs {
 		action, ok := msg.Headers["action"].(string)
@@ -115,10 +159,20 @@ func (uh *UserService) Start() {
 			uh.HandleCreateUser(msg.Body, msg.ReplyTo, msg.CorrelationId)
 		case "UpdateProgress":
 			uh.HandleUpdateProgress(msg.Body, msg.ReplyTo, msg.CorrelationId)
+		case "UpdateProfile":
+			uh.HandleUpdateProfile(msg.Body, msg.ReplyTo, msg.CorrelationId)
         case "GetCountryLeaderboard":
             uh.HandleGetCountryLeaderboard(msg.Body, msg.ReplyTo, msg.CorrelationId)
         case "GetGlobalLeaderboard":
             uh.HandleGetGlobalLeaderboard(msg.Body, msg.ReplyTo, msg.CorrelationId)
+		case "DailyCheckIn":
+			uh.HandleDailyCheckIn(msg.Body, msg.ReplyTo, msg.CorrelationId)
+		case "GetCheckInStatus":
+			uh.HandleGetCheckInStatus(msg.Body, msg.ReplyTo, msg.CorrelationId)
+		case "GetLives":
+			uh.HandleGetLives(msg.Body, msg.ReplyTo, msg.CorrelationId)
+		case "BuyLives":
+			uh.HandleBuyLives(msg.Body, msg.ReplyTo, msg.CorrelationId)
 		default:
 			log.Printf("Unknown action: %s", action)
 		}
@@ -128,6 +182,7 @@ func (uh *UserService) Start() {
 // Stop the user service
 func (uh *UserService) Stop() {
 	log.Println("Stopping user service service...")
+	close(uh.stopChan)
 	if err := uh.channel.Close(); err != nil {
 		log.Printf("Error closing channel: %v", err)
 	}
@@ -172,6 +227,11 @@ func (uh *UserService) HandleSearchUser(data []byte, replyTo string, correlation
         Coins int `json:"coins"`
         Latest_Tournament_ID string `json:"latest_tournament_id"`
         Latest_Group_ID int `json:"latest_group_id"`
+        League string `json:"league"`
+        DisplayName string `json:"display_name"`
+        AvatarID string `json:"avatar_id"`
+        FrameID string `json:"frame_id"`
+        Rating ratingSummary `json:"rating"`
     }{
         ID:   user.ID,
         Username: user.Username,
@@ -180,6 +240,11 @@ func (uh *UserService) HandleSearchUser(data []byte, replyTo string, correlation
         Coins: user.Coins,
         Latest_Tournament_ID: user.Latest_Tournament_ID,
         Latest_Group_ID: user.Latest_Group_ID,
+        League: leagueName(user.League),
+        DisplayName: user.Display_Name,
+        AvatarID: user.Avatar_ID,
+        FrameID: user.Frame_ID,
+        Rating: summarizeSkillRating(user.Skill_Rating, time.Now()),
     })
 }
 
@@ -199,6 +264,26 @@ func (uh *UserService) HandleCreateUser(data []byte, replyTo string, correlation
         return
     }
 
+    sendError := func(message string) {
+        sendResponse(uh.channel, replyTo, correlationID, "CreateUserResponse", struct {
+            Error string `json:"error"`
+        }{
+            Error: message,
+        })
+    }
+
+    // Check the username against the naming rules and the country against ISO 3166-1
+    err = validation.ValidateUsername(requestData.Username, uh.getNameFilter())
+    if err != nil {
+        sendError(err.Error())
+        return
+    }
+    country, err := validation.NormalizeCountry(requestData.Country)
+    if err != nil {
+        sendError(err.Error())
+        return
+    }
+
     // Check if the username already exists
     existingUser, err := uh.dynamoDBRepo.GetUserByUsername(requestData.Username)
     if err != nil {
@@ -223,6 +308,19 @@ func (uh *UserService) HandleCreateUser(data []byte, replyTo string, correlation
         return
     }
 
+    // Claim the username's skeleton, so that no one can take a username that looks the same
+    skeleton := validation.UsernameSkeleton(requestData.Username)
+    claimed, err := uh.dynamoDBRepo.ClaimUsernameSkeleton(skeleton, requestData.Username)
+    if err != nil {
+        log.Printf("Error claiming username: %v", err)
+        sendError("Failed to create user")
+        return
+    }
+    if !claimed {
+        sendError("Username is too similar to an existing username")
+        return
+    }
+
     // Generate a unique identifier
     uniqueID := uuid.New().String()
 
@@ -231,17 +329,19 @@ func (uh *UserService) HandleCreateUser(data []byte, replyTo string, correlation
         ID:       uniqueID,
         Username: requestData.Username,
         Password: hashedPassword,
-        Country: requestData.Country,
+        Country: country,
         Progress_Level:    1,
         Coins:    100,
         Latest_Tournament_ID: "",
         Latest_Group_ID: -1,
+        League: config.Leagues[0].Name,
     }
 
     // Create user in the database
     err = uh.dynamoDBRepo.CreateUser(&user)
     if err != nil {
         log.Printf("Error creating user: %v", err)
+        uh.dynamoDBRepo.ReleaseUsernameSkeleton(skeleton, requestData.Username)
         return
     }
 
@@ -304,19 +404,64 @@ func (uh *UserService) HandleLogin(data []byte, replyTo string, correlationID st
         return
     }
 
+    // Banned and suspended users cannot sign in, shadow-banned users are not told
+    switch user.ModerationStatusAt(time.Now()) {
+    case models.ModerationBanned, models.ModerationSuspended:
+        sendResponse(uh.channel, replyTo, correlationID, "LoginResponse", struct {
+            Success bool `json:"success"`
+            Error string `json:"error"`
+        }{
+            Success: false,
+            Error: auth.SanctionMessage(&models.Sanction{
+                Status: user.Moderation_Status,
+                SuspendedUntil: user.Suspended_Until,
+            }),
+        })
+        return
+    }
+
     // Generate a JWT token
-    token, err := auth.CreateToken(user.Username)
+    sessionID := uuid.New().String()
+    token, err := auth.CreateToken(user.Username, sessionID)
     if err != nil {
         log.Fatalf("Failed to generate JWT token: %v", err)
         return
     }
 
+    // Issue the key the session signs its game results with
+    keyVersion := config.CurrentSigningKeyVersion()
+    signingKey, err := auth.SessionSigningKey(keyVersion, user.Username, sessionID)
+    if err != nil {
+        log.Printf("Failed to derive signing key: %v", err)
+        return
+    }
+
+    // Keep the session for the player's data export
+    now := time.Now().UTC()
+    err = uh.dynamoDBRepo.CreateSession(&models.Session{
+        Username: user.Username,
+        SessionID: sessionID,
+        KeyVersion: keyVersion,
+        CreatedAt: now,
+        ExpiresAt: now.Add(auth.TokenLifetime),
+    })
+    if err != nil {
+        log.Printf("Failed to record session: %v", err)
+    }
+
+    // Players who asked for their account to be deleted are told when, so they can still cancel
     sendResponse(uh.channel, replyTo, correlationID, "LoginResponse", struct {
         Token  string `json:"token"`
         Success bool `json:"success"`
+        SigningKey string `json:"signing_key"`
+        SigningKeyVersion int `json:"signing_key_version"`
+        DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
     }{
         Token:  token,
         Success: true,
+        SigningKey: base64.StdEncoding.EncodeToString(signingKey),
+        SigningKeyVersion: keyVersion,
+        DeletionScheduledAt: user.Deletion_Scheduled_At,
     })
 }
 
@@ -337,12 +482,11 @@ func (uh *UserService) HandleUpdateProgress(data []byte, replyTo string, correla
     user, err := uh.dynamoDBRepo.GetUserByUsername(requestData.Username)
     if err != nil {
         log.Printf("Error fetching user: %v", err)
-        return
-    }
-
-
-    if err != nil {
-        log.Printf("Error fetching user: %v", err)
+        sendResponse(uh.channel, replyTo, correlationID, "UpdateProgressResponse", struct {
+            Error string `json:"error"`
+        }{
+            Error: "Failed to update progress",
+        })
         return
     }
 
@@ -358,26 +502,71 @@ func (uh *UserService) HandleUpdateProgress(data []byte, replyTo string, correla
         return
     }
 
-    // Update the user's progress
-    err = uh.dynamoDBRepo.UpdateUserField(user.Username, "progress_level", user.Progress_Level+1)
+    // Reject levels completed faster than they can be played
+    err = checkScoreSubmission(uh.redisRepo, user.Username, 0)
+    if err == ErrScoreTooFast || err == ErrTooManyScores {
+        sendResponse(uh.channel, replyTo, correlationID, "UpdateProgressResponse", struct {
+            Error string `json:"error"`
+        }{
+            Error: err.Error(),
+        })
+        return
+    }
     if err != nil {
-        log.Printf("Error updating user progress_level: %v", err)
+        log.Printf("Error checking score submission: %v", err)
+        sendResponse(uh.channel, replyTo, correlationID, "UpdateProgressResponse", struct {
+            Error string `json:"error"`
+        }{
+            Error: "Failed to update progress",
+        })
         return
     }
 
-    // Update the user's coins
-    err = uh.dynamoDBRepo.UpdateUserField(user.Username, "coins", user.Coins+100)
+    // Raise the user's progress and reward the new level with 100 coins, live events can multiply them
+    coinsBoost := eventBoostFor(activeEvents(uh.redisRepo, time.Now().UTC()), user, models.EventEffectLevelCoins)
+    coinsEarned := coinsBoost.apply(100)
+    // Every level played uses a life, in the same transaction as the level and its coins
+    progressLevel, result, err := uh.dynamoDBRepo.LevelUp(user.Username, coinsEarned, coinsBoost.EventID)
     if err != nil {
-        log.Printf("Error updating user coins: %v", err)
+        message := err.Error()
+        if err != repositories.ErrNoLives {
+            log.Printf("Error updating user progress: %v", err)
+            message = "Failed to update progress"
+        }
+        sendResponse(uh.channel, replyTo, correlationID, "UpdateProgressResponse", struct {
+            Error string `json:"error"`
+        }{
+            Error: message,
+        })
         return
     }
 
+    // Count the level and coins in the current season, towards achievements and in running challenges
+    recordSeasonProgress(uh.channel, user.Username, 1, coinsEarned, 0)
+    recordChallengeScore(uh.channel, user.Username, 1, time.Now().UTC())
+    recordAchievementEvent(uh.channel, user.Username, models.AchievementEventLevelUp, strconv.Itoa(progressLevel), 1)
+    recordAchievementEvent(uh.channel, user.Username, models.AchievementEventCoinsEarned, result.Entry.TransactionID, coinsEarned)
+
+    publishNotification(uh.channel, models.Notification{
+        Type:     "level_up",
+        Username: user.Username,
+        Data: map[string]interface{}{
+            "progress_level": progressLevel,
+        },
+    })
+
     sendResponse(uh.channel, replyTo, correlationID, "UpdateProgressResponse", struct {
         Progress_Level int `json:"progress_level"`
         Coins int `json:"coins"`
+        Lives int `json:"lives"`
+        CoinsEarned int `json:"coins_earned"`
+        EventID string `json:"event_id,omitempty"`
     }{
-        Progress_Level: user.Progress_Level + 1,
-        Coins: user.Coins + 100,
+        Progress_Level: progressLevel,
+        Coins: result.Entry.Balance,
+        Lives: result.Lives.Lives,
+        CoinsEarned: coinsEarned,
+        EventID: coinsBoost.EventID,
     })
 }
 
@@ -411,7 +600,8 @@ func (uh *UserService) HandleGetCountryLeaderboard(data []byte, replyTo string,
 // Get the global leaderboard
 func (uh *UserService) HandleGetGlobalLeaderboard(data []byte, replyTo string, correlationID string) {
 	var requestData struct {
-		Action string `json:"action"`
+		Action   string `json:"action"`
+		Username string `json:"username"`
 	}
 
 	err := json.Unmarshal(data, &requestData)
@@ -421,7 +611,7 @@ func (uh *UserService) HandleGetGlobalLeaderboard(data []byte, replyTo string, c
 	}
 
     // Get the global leaderboard
-	users, err := uh.dynamoDBRepo.GetGlobalLeaderboard()
+	users, err := uh.dynamoDBRepo.GetGlobalLeaderboard(requestData.Username)
 	if err != nil {
 		log.Printf("Error fetching global leaderboard: %v", err)
 		return
@@ -433,3 +623,651 @@ func (uh *UserService) HandleGetGlobalLeaderboard(data []byte, replyTo string, c
 		Users: users,
 	})
 }
+
+// Reload the name filter whenever the version in its file changes
+func (uh *UserService) runNameFilterReloader() {
+	ticker := time.NewTicker(nameFilterReloadPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-uh.stopChan:
+			return
+		case <-ticker.C:
+			nameFilter, err := validation.LoadNameFilter(config.NameFilterPath)
+			if err != nil {
+				log.Printf("Error loading name filter, keeping version %d: %v", uh.getNameFilter().Version, err)
+				continue
+			}
+
+			uh.mu.Lock()
+			if nameFilter.Version != uh.nameFilter.Version {
+				log.Printf("Loaded name filter version %d, replacing version %d", nameFilter.Version, uh.nameFilter.Version)
+				uh.nameFilter = nameFilter
+			}
+			uh.mu.Unlock()
+		}
+	}
+}
+
+// Get the name filter currently in use
+func (uh *UserService) getNameFilter() *validation.NameFilter {
+	uh.mu.RLock()
+	defer uh.mu.RUnlock()
+	return uh.nameFilter
+}
+
+// Normalize the countries of the users created before they were validated, and claim their username skeletons
+// Runs only once, countries that cannot be matched and look-alike usernames are logged for support staff
+func (uh *UserService) MigrateUserProfiles() error {
+	migrated, err := uh.redisRepo.KeyExists(userProfilesMigratedKey)
+	if err != nil || migrated {
+		return err
+	}
+
+	// Only one instance runs the migration
+	acquired, err := uh.redisRepo.AcquireLock(userProfilesMigratedKey+":lock", time.Hour)
+	if err != nil || !acquired {
+		return err
+	}
+
+	fixed, unknown, lookAlikes := 0, 0, 0
+	err = uh.dynamoDBRepo.ForEachUser(func(user models.User) error {
+		if !validation.IsCountryCode(user.Country) {
+			country, err := validation.NormalizeCountry(user.Country)
+			if err != nil {
+				log.Printf("Cannot normalize country %q of %s: %v", user.Country, user.Username, err)
+				unknown++
+			} else {
+				err = uh.dynamoDBRepo.UpdateUserField(user.Username, "country", country)
+				if err != nil {
+					return err
+				}
+				err = moveHallOfFameCountry(uh.redisRepo, user.Username, user.Country, country)
+				if err != nil {
+					return err
+				}
+				fixed++
+			}
+		}
+
+		skeleton := validation.UsernameSkeleton(user.Username)
+		claimed, err := uh.dynamoDBRepo.ClaimUsernameSkeleton(skeleton, user.Username)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			owner, err := uh.dynamoDBRepo.GetUsernameSkeletonOwner(skeleton)
+			if err != nil {
+				return err
+			}
+			if owner != user.Username {
+				log.Printf("Username %s looks like %s", user.Username, owner)
+				lookAlikes++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Migrated user profiles: %d countries fixed, %d unknown countries, %d look-alike usernames", fixed, unknown, lookAlikes)
+	return uh.redisRepo.SetFlag(userProfilesMigratedKey)
+}
+
+// Update a user's display name, country, avatar or frame
+// Fields that are not given are kept, an empty display name, avatar or frame is removed
+func (uh *UserService) HandleUpdateProfile(data []byte, replyTo string, correlationID string) {
+	var requestData struct {
+		Action      string  `json:"action"`
+		Username    string  `json:"username"`
+		DisplayName *string `json:"display_name"`
+		Country     *string `json:"country"`
+		AvatarID    *string `json:"avatar_id"`
+		FrameID     *string `json:"frame_id"`
+		Timezone    *string `json:"timezone"`
+	}
+
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		log.Printf("Failed to unmarshal data: %v", err)
+		return
+	}
+
+	sendError := func(message string) {
+		sendResponse(uh.channel, replyTo, correlationID, "UpdateProfileResponse", struct {
+			Error string `json:"error"`
+		}{
+			Error: message,
+		})
+	}
+
+	user, err := uh.dynamoDBRepo.GetUserByUsername(requestData.Username)
+	if err != nil || user == nil {
+		sendError("User not found")
+		return
+	}
+
+	// Check every change before making any of them
+	if requestData.DisplayName != nil && *requestData.DisplayName != "" {
+		err = validation.ValidateDisplayName(*requestData.DisplayName, uh.getNameFilter())
+		if err != nil {
+			sendError(err.Error())
+			return
+		}
+	}
+	country := user.Country
+	if requestData.Country != nil {
+		country, err = validation.NormalizeCountry(*requestData.Country)
+		if err != nil {
+			sendError(err.Error())
+			return
+		}
+	}
+	timezone := user.Timezone
+	if requestData.Timezone != nil {
+		timezone = *requestData.Timezone
+		if _, err := time.LoadLocation(timezone); timezone == "Local" || err != nil {
+			sendError("Unknown timezone " + timezone)
+			return
+		}
+	}
+	if requestData.AvatarID != nil {
+		err = uh.checkProfileItem(user.Username, *requestData.AvatarID, "avatar")
+		if err != nil {
+			sendError(err.Error())
+			return
+		}
+	}
+	if requestData.FrameID != nil {
+		err = uh.checkProfileItem(user.Username, *requestData.FrameID, "frame")
+		if err != nil {
+			sendError(err.Error())
+			return
+		}
+	}
+
+	// The country and timezone can only change once per cooldown, the timezone moves the player's check-in days
+	now := time.Now()
+	if country != user.Country && user.Country_Changed_At != nil && now.Before(user.Country_Changed_At.Add(config.CountryChangeCooldown)) {
+		sendError("Country can be changed again after " + user.Country_Changed_At.Add(config.CountryChangeCooldown).UTC().Format(time.RFC3339))
+		return
+	}
+	if timezone != user.Timezone && user.Timezone_Changed_At != nil && now.Before(user.Timezone_Changed_At.Add(config.TimezoneChangeCooldown)) {
+		sendError("Timezone can be changed again after " + user.Timezone_Changed_At.Add(config.TimezoneChangeCooldown).UTC().Format(time.RFC3339))
+		return
+	}
+
+	update := repositories.ProfileUpdate{
+		CountryCooldown:  config.CountryChangeCooldown,
+		TimezoneCooldown: config.TimezoneChangeCooldown,
+	}
+	fields := []struct {
+		value  *string
+		field  *string
+		update **string
+	}{
+		{requestData.DisplayName, &user.Display_Name, &update.DisplayName},
+		{&country, &user.Country, &update.Country},
+		{&timezone, &user.Timezone, &update.Timezone},
+		{requestData.AvatarID, &user.Avatar_ID, &update.AvatarID},
+		{requestData.FrameID, &user.Frame_ID, &update.FrameID},
+	}
+	for _, f := range fields {
+		if f.value != nil && *f.value != *f.field {
+			*f.update = f.value
+		}
+	}
+
+	// Country leaderboards are read from the user table, the hall of fame keeps its own. Its scores move
+	// before the profile is written and move back if it is not, so that a failure changes neither.
+	moveBack := func() {}
+	if update.Country != nil && user.Country != "" {
+		from := user.Country
+		moveBack = func() {
+			err := moveHallOfFameCountry(uh.redisRepo, user.Username, country, from)
+			if err != nil {
+				log.Printf("Error moving hall of fame scores of %s back to %s: %v", user.Username, from, err)
+			}
+		}
+		err = moveHallOfFameCountry(uh.redisRepo, user.Username, from, country)
+		if err != nil {
+			log.Printf("Error moving hall of fame scores of %s to %s: %v", user.Username, country, err)
+			moveBack()
+			sendError("Failed to update country")
+			return
+		}
+	}
+
+	updated, err := uh.dynamoDBRepo.UpdateUserProfile(user, update)
+	if err != nil || !updated {
+		moveBack()
+		if err != nil {
+			log.Printf("Error updating profile of %s: %v", user.Username, err)
+			sendError("Failed to update profile")
+		} else {
+			sendError("Profile was changed by another request, try again")
+		}
+		return
+	}
+	for _, f := range fields {
+		if *f.update != nil {
+			*f.field = **f.update
+		}
+	}
+
+	sendResponse(uh.channel, replyTo, correlationID, "UpdateProfileResponse", struct {
+		Username    string `json:"username"`
+		DisplayName string `json:"display_name"`
+		Country     string `json:"country"`
+		AvatarID    string `json:"avatar_id"`
+		FrameID     string `json:"frame_id"`
+		Timezone    string `json:"timezone"`
+	}{
+		Username:    user.Username,
+		DisplayName: user.Display_Name,
+		Country:     user.Country,
+		AvatarID:    user.Avatar_ID,
+		FrameID:     user.Frame_ID,
+		Timezone:    user.Timezone,
+	})
+}
+
+// Check that a user holds an inventory item of the given type, an empty item ID is always allowed
+func (uh *UserService) checkProfileItem(username string, itemID string, itemType string) error {
+	if itemID == "" {
+		return nil
+	}
+
+	item, err := uh.dynamoDBRepo.GetInventoryItem(username, itemID)
+	if err != nil {
+		log.Printf("Error fetching inventory item: %v", err)
+		return errors.New("Failed to get inventory item")
+	}
+	if item == nil || item.Quantity <= 0 || (item.ExpiresAt != nil && !time.Now().Before(*item.ExpiresAt)) {
+		return errors.New("Item " + itemID + " is not in your inventory")
+	}
+	if item.Type != itemType {
+		return errors.New("Item " + itemID + " is not a " + itemType)
+	}
+	return nil
+}
+
+// Layout of the days of daily check-ins
+const checkInDayLayout = "2006-01-02"
+
+// Check a player in for the day in their timezone, paying the reward of their streak day
+// Only one check-in per day can succeed, concurrent requests for the same day are refused
+func (uh *UserService) HandleDailyCheckIn(data []byte, replyTo string, correlationID string) {
+	var requestData struct {
+		Action   string `json:"action"`
+		Username string `json:"username"`
+	}
+
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		log.Printf("Failed to unmarshal data: %v", err)
+		return
+	}
+
+	sendError := func(message string) {
+		sendResponse(uh.channel, replyTo, correlationID, "DailyCheckInResponse", struct {
+			Error string `json:"error"`
+		}{
+			Error: message,
+		})
+	}
+
+	user, err := uh.dynamoDBRepo.GetUserByUsername(requestData.Username)
+	if err != nil || user == nil {
+		sendError("User not found")
+		return
+	}
+	location := userLocation(user)
+
+	previous, err := uh.dynamoDBRepo.GetDailyCheckIn(user.Username)
+	if err != nil {
+		log.Printf("Error fetching daily check-in: %v", err)
+		sendError("Failed to check in")
+		return
+	}
+
+	now := time.Now().UTC()
+	plan := planCheckIn(previous, now, location)
+	if plan.CheckedInToday {
+		sendError("Already checked in today, come back after " + nextLocalDay(now, location).UTC().Format(time.RFC3339))
+		return
+	}
+
+	checkIn := models.DailyCheckIn{
+		Username:      user.Username,
+		Streak:        plan.Streak,
+		LongestStreak: plan.Streak,
+		FreezesUsed:   plan.FreezesUsed,
+		TotalCheckIns: 1,
+		LastDay:       plan.Day,
+		LastCheckInAt: now,
+	}
+	var previousCheckInAt *time.Time
+	if previous != nil {
+		previousCheckInAt = &previous.LastCheckInAt
+		checkIn.TotalCheckIns = previous.TotalCheckIns + 1
+		if previous.LongestStreak > checkIn.LongestStreak {
+			checkIn.LongestStreak = previous.LongestStreak
+		}
+	}
+
+	// The check-in is stored together with its reward, and concurrent requests read the same check-in,
+	// so they share the transaction ID and only one of them checks in
+	reward := checkInReward(plan.Streak)
+	transactionID := models.LedgerCheckInReward + ":" + user.Username + ":" + strconv.Itoa(checkIn.TotalCheckIns)
+	entry, saved, err := uh.dynamoDBRepo.SaveDailyCheckIn(&checkIn, previousCheckInAt, repositories.WalletTransaction{
+		TransactionID: transactionID,
+		Username:      user.Username,
+		Currency:      reward.Currency,
+		Counterparty:  models.SystemAccountRewards,
+		Type:          models.LedgerCheckInReward,
+		Amount:        reward.Amount,
+		Reference:     plan.Day,
+	})
+	if err != nil {
+		log.Printf("Error saving daily check-in: %v", err)
+		sendError("Failed to check in")
+		return
+	}
+	if !saved {
+		sendError("Already checked in today")
+		return
+	}
+
+	if reward.Currency == config.DefaultCurrency {
+		recordSeasonProgress(uh.channel, user.Username, 0, reward.Amount, 0)
+		recordAchievementEvent(uh.channel, user.Username, models.AchievementEventCoinsEarned, transactionID, reward.Amount)
+	}
+	recordAchievementEvent(uh.channel, user.Username, models.AchievementEventDailyCheckIn, strconv.Itoa(checkIn.TotalCheckIns), 1)
+
+	sendResponse(uh.channel, replyTo, correlationID, "DailyCheckInResponse", struct {
+		Username       string               `json:"username"`
+		Day            string               `json:"day"`
+		Streak         int                  `json:"streak"`
+		FreezesLeft    int                  `json:"freezes_left"`
+		RewardCurrency string               `json:"reward_currency"`
+		RewardAmount   int                  `json:"reward_amount"`
+		Balance        int                  `json:"balance"`
+		NextReward     config.CheckInReward `json:"next_reward"`
+		NextCheckInAt  time.Time            `json:"next_check_in_at"`
+	}{
+		Username:       user.Username,
+		Day:            plan.Day,
+		Streak:         checkIn.Streak,
+		FreezesLeft:    config.CheckInFreezeDays - checkIn.FreezesUsed,
+		RewardCurrency: reward.Currency,
+		RewardAmount:   reward.Amount,
+		Balance:        entry.Balance,
+		NextReward:     checkInReward(checkIn.Streak + 1),
+		NextCheckInAt:  nextLocalDay(now, location).UTC(),
+	})
+}
+
+// Get a player's check-in streak, whether they checked in today and the reward of their next check-in
+func (uh *UserService) HandleGetCheckInStatus(data []byte, replyTo string, correlationID string) {
+	var requestData struct {
+		Action   string `json:"action"`
+		Username string `json:"username"`
+	}
+
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		log.Printf("Failed to unmarshal data: %v", err)
+		return
+	}
+
+	sendError := func(message string) {
+		sendResponse(uh.channel, replyTo, correlationID, "GetCheckInStatusResponse", struct {
+			Error string `json:"error"`
+		}{
+			Error: message,
+		})
+	}
+
+	user, err := uh.dynamoDBRepo.GetUserByUsername(requestData.Username)
+	if err != nil || user == nil {
+		sendError("User not found")
+		return
+	}
+	location := userLocation(user)
+
+	previous, err := uh.dynamoDBRepo.GetDailyCheckIn(user.Username)
+	if err != nil {
+		log.Printf("Error fetching daily check-in: %v", err)
+		sendError("Failed to get check-in status")
+		return
+	}
+
+	now := time.Now().UTC()
+	plan := planCheckIn(previous, now, location)
+
+	// A streak the next check-in would start over is shown as lost
+	streak, freezesUsed, longest, nextStreak, nextCheckInAt := 0, 0, 0, plan.Streak, now
+	if previous != nil {
+		longest = previous.LongestStreak
+		if plan.CheckedInToday || plan.Streak > 1 {
+			streak = previous.Streak
+			freezesUsed = plan.FreezesUsed
+		}
+	}
+	if plan.CheckedInToday {
+		nextStreak = streak + 1
+		nextCheckInAt = nextLocalDay(now, location).UTC()
+	}
+
+	sendResponse(uh.channel, replyTo, correlationID, "GetCheckInStatusResponse", struct {
+		Username       string                 `json:"username"`
+		Timezone       string                 `json:"timezone"`
+		Streak         int                    `json:"streak"`
+		LongestStreak  int                    `json:"longest_streak"`
+		CheckedInToday bool                   `json:"checked_in_today"`
+		FreezesLeft    int                    `json:"freezes_left"`
+		NextReward     config.CheckInReward   `json:"next_reward"`
+		NextCheckInAt  time.Time              `json:"next_check_in_at"`
+		Calendar       []config.CheckInReward `json:"calendar"`
+	}{
+		Username:       user.Username,
+		Timezone:       location.String(),
+		Streak:         streak,
+		LongestStreak:  longest,
+		CheckedInToday: plan.CheckedInToday,
+		FreezesLeft:    config.CheckInFreezeDays - freezesUsed,
+		NextReward:     checkInReward(nextStreak),
+		NextCheckInAt:  nextCheckInAt,
+		Calendar:       config.CheckInRewardCalendar,
+	})
+}
+
+// checkInPlan is what a check-in at a given time would record
+type checkInPlan struct {
+	Day            string
+	Streak         int
+	FreezesUsed    int
+	CheckedInToday bool
+}
+
+// Work out the streak a check-in at the given time continues, or starts over
+// The last check-in's day is counted in the player's current timezone, so changing timezones cannot give two check-ins on one day
+func planCheckIn(previous *models.DailyCheckIn, now time.Time, location *time.Location) checkInPlan {
+	today := now.In(location)
+	plan := checkInPlan{Day: today.Format(checkInDayLayout), Streak: 1}
+	if previous == nil {
+		return plan
+	}
+
+	gap := daysBetween(previous.LastCheckInAt.In(location), today)
+	if gap <= 0 {
+		plan.Streak = previous.Streak
+		plan.FreezesUsed = previous.FreezesUsed
+		plan.CheckedInToday = true
+		return plan
+	}
+
+	// Missed days are covered by the streak freeze until it is used up
+	missed := gap - 1
+	if previous.FreezesUsed+missed <= config.CheckInFreezeDays {
+		plan.Streak = previous.Streak + 1
+		plan.FreezesUsed = previous.FreezesUsed + missed
+	}
+	return plan
+}
+
+// Get the number of calendar days from one time to another, each in its own location
+func daysBetween(from, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDay.Sub(fromDay).Hours() / 24)
+}
+
+// Get the start of the day after the given time in a location
+func nextLocalDay(now time.Time, location *time.Location) time.Time {
+	year, month, day := now.In(location).Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, location)
+}
+
+// Get the reward of a day of a check-in streak, the calendar starts over after its last day
+func checkInReward(streak int) config.CheckInReward {
+	calendar := config.CheckInRewardCalendar
+	return calendar[(streak-1)%len(calendar)]
+}
+
+// Get the location a user's days are counted in
+func userLocation(user *models.User) *time.Location {
+	if user.Timezone != "" {
+		location, err := time.LoadLocation(user.Timezone)
+		if err == nil {
+			return location
+		}
+	}
+	return time.UTC
+}
+
+
+// livesStatus is a player's lives and when the next ones are refilled
+type livesStatus struct {
+	Lives    int `json:"lives"`
+	MaxLives int `json:"max_lives"`
+	// Unset at or above the cap, where lives are not refilled
+	NextLifeAt     *time.Time `json:"next_life_at,omitempty"`
+	SecondsToNext  int        `json:"seconds_to_next_life"`
+	FullAt         *time.Time `json:"full_at,omitempty"`
+	RefillInterval int        `json:"refill_interval_seconds"`
+	LifePrice      int        `json:"life_price"`
+}
+
+// Summarize a player's lives at the given time
+func summarizeLives(lives models.Lives, now time.Time) livesStatus {
+	status := livesStatus{
+		Lives:          lives.Lives,
+		MaxLives:       config.LivesMax,
+		RefillInterval: int(config.LifeRefillInterval / time.Second),
+		LifePrice:      config.LifePrice,
+	}
+	if lives.Lives < config.LivesMax {
+		nextLifeAt := lives.RefillFrom.Add(config.LifeRefillInterval)
+		fullAt := lives.RefillFrom.Add(time.Duration(config.LivesMax-lives.Lives) * config.LifeRefillInterval)
+		status.NextLifeAt = &nextLifeAt
+		status.FullAt = &fullAt
+		status.SecondsToNext = int(math.Ceil(nextLifeAt.Sub(now).Seconds()))
+	}
+	return status
+}
+
+// Get a player's lives and the time to the next refill
+func (uh *UserService) HandleGetLives(data []byte, replyTo string, correlationID string) {
+	var requestData struct {
+		Action   string `json:"action"`
+		Username string `json:"username"`
+	}
+
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		log.Printf("Failed to unmarshal data: %v", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	lives, err := uh.dynamoDBRepo.GetCurrentLives(requestData.Username)
+	if err != nil {
+		log.Printf("Error fetching lives: %v", err)
+		sendResponse(uh.channel, replyTo, correlationID, "GetLivesResponse", struct {
+			Error string `json:"error"`
+		}{
+			Error: "Failed to get lives",
+		})
+		return
+	}
+
+	sendResponse(uh.channel, replyTo, correlationID, "GetLivesResponse", summarizeLives(lives, now))
+}
+
+// Buy lives with coins, bought lives can go above the cap
+func (uh *UserService) HandleBuyLives(data []byte, replyTo string, correlationID string) {
+	var requestData struct {
+		Action     string `json:"action"`
+		Username   string `json:"username"`
+		Quantity   int    `json:"quantity"`
+		PurchaseID string `json:"purchase_id"`
+	}
+
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		log.Printf("Failed to unmarshal data: %v", err)
+		return
+	}
+
+	sendError := func(message string) {
+		sendResponse(uh.channel, replyTo, correlationID, "BuyLivesResponse", struct {
+			Error string `json:"error"`
+		}{
+			Error: message,
+		})
+	}
+
+	if requestData.Quantity <= 0 || requestData.Quantity > config.LivesMax {
+		sendError("Quantity must be between 1 and " + strconv.Itoa(config.LivesMax))
+		return
+	}
+
+	// Clients retrying a purchase send the same purchase ID, so it is only charged once
+	purchaseID := requestData.PurchaseID
+	if purchaseID == "" {
+		purchaseID = uuid.New().String()
+	}
+
+	entry, err := uh.dynamoDBRepo.PurchaseLives(requestData.Username, requestData.Quantity, purchaseID)
+	if err == repositories.ErrInsufficientFunds || err == repositories.ErrUserNotFound {
+		sendError(err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error buying lives for %s: %v", requestData.Username, err)
+		sendError("Failed to buy lives")
+		return
+	}
+
+	now := time.Now().UTC()
+	lives, err := uh.dynamoDBRepo.GetCurrentLives(requestData.Username)
+	if err != nil {
+		log.Printf("Error fetching lives: %v", err)
+		sendError("Failed to get lives")
+		return
+	}
+
+	sendResponse(uh.channel, replyTo, correlationID, "BuyLivesResponse", struct {
+		PurchaseID  string             `json:"purchase_id"`
+		Transaction models.LedgerEntry `json:"transaction"`
+		livesStatus
+	}{
+		PurchaseID:  purchaseID,
+		Transaction: *entry,
+		livesStatus: summarizeLives(lives, now),
+	})
+}
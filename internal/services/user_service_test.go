@@ -0,0 +1,103 @@
+package services
+
+import (
+	"cloudblast-backend/config"
+	"cloudblast-backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestPlanCheckIn(t *testing.T) {
+	previousFreezeDays := config.CheckInFreezeDays
+	config.CheckInFreezeDays = 1
+	t.Cleanup(func() { config.CheckInFreezeDays = previousFreezeDays })
+
+	istanbul := time.FixedZone("UTC+3", 3*60*60)
+	// Noon on March 10th in Istanbul
+	now := time.Date(2024, time.March, 10, 9, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+
+	tests := []struct {
+		name     string
+		previous *models.DailyCheckIn
+		location *time.Location
+		want     checkInPlan
+	}{
+		{
+			name:     "first check-in starts a streak",
+			location: istanbul,
+			want:     checkInPlan{Day: "2024-03-10", Streak: 1},
+		},
+		{
+			name:     "next day continues the streak",
+			previous: &models.DailyCheckIn{Streak: 4, LastCheckInAt: daysAgo(1)},
+			location: istanbul,
+			want:     checkInPlan{Day: "2024-03-10", Streak: 5},
+		},
+		{
+			name:     "second check-in on one day changes nothing",
+			previous: &models.DailyCheckIn{Streak: 4, FreezesUsed: 1, LastCheckInAt: now.Add(-8 * time.Hour)},
+			location: istanbul,
+			want:     checkInPlan{Day: "2024-03-10", Streak: 4, FreezesUsed: 1, CheckedInToday: true},
+		},
+		{
+			name:     "one missed day is covered by the freeze",
+			previous: &models.DailyCheckIn{Streak: 4, LastCheckInAt: daysAgo(2)},
+			location: istanbul,
+			want:     checkInPlan{Day: "2024-03-10", Streak: 5, FreezesUsed: 1},
+		},
+		{
+			name:     "a second missed day after the freeze is used resets the streak",
+			previous: &models.DailyCheckIn{Streak: 6, FreezesUsed: 1, LastCheckInAt: daysAgo(2)},
+			location: istanbul,
+			want:     checkInPlan{Day: "2024-03-10", Streak: 1},
+		},
+		{
+			name:     "two missed days at once reset the streak",
+			previous: &models.DailyCheckIn{Streak: 6, LastCheckInAt: daysAgo(3)},
+			location: istanbul,
+			want:     checkInPlan{Day: "2024-03-10", Streak: 1},
+		},
+		{
+			// 23:00 on March 9th in Istanbul was the day before, though only 13 hours ago
+			name:     "days change at local midnight",
+			previous: &models.DailyCheckIn{Streak: 2, LastCheckInAt: time.Date(2024, time.March, 9, 20, 0, 0, 0, time.UTC)},
+			location: istanbul,
+			want:     checkInPlan{Day: "2024-03-10", Streak: 3},
+		},
+		{
+			// Checked in at 23:00 on March 9th in Los Angeles, which is March 10th in Istanbul
+			name:     "moving east cannot give a second check-in on one day",
+			previous: &models.DailyCheckIn{Streak: 2, LastDay: "2024-03-09", LastCheckInAt: time.Date(2024, time.March, 10, 7, 0, 0, 0, time.UTC)},
+			location: istanbul,
+			want:     checkInPlan{Day: "2024-03-10", Streak: 2, CheckedInToday: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := planCheckIn(test.previous, now, test.location); got != test.want {
+				t.Errorf("planCheckIn() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckInRewardStartsOver(t *testing.T) {
+	calendar := config.CheckInRewardCalendar
+	days := len(calendar)
+
+	checks := map[int]config.CheckInReward{
+		1:           calendar[0],
+		days:        calendar[days-1],
+		days + 1:    calendar[0],
+		days + 2:    calendar[1],
+		2*days + 1:  calendar[0],
+		10*days + 3: calendar[2],
+	}
+	for streak, want := range checks {
+		if got := checkInReward(streak); got != want {
+			t.Errorf("checkInReward(%d) = %+v, want %+v", streak, got, want)
+		}
+	}
+}
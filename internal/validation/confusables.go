@@ -0,0 +1,83 @@
+package validation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Accented Latin letters folded to their base letters
+var latinFolds = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'ĉ': "c", 'ċ': "c", 'č': "c",
+	'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ĕ': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ĝ': "g", 'ğ': "g", 'ġ': "g", 'ģ': "g",
+	'ĥ': "h", 'ħ': "h",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ĩ': "i", 'ī': "i", 'ĭ': "i", 'į': "i", 'ı': "i",
+	'ĵ': "j",
+	'ķ': "k",
+	'ĺ': "l", 'ļ': "l", 'ľ': "l", 'ŀ': "l", 'ł': "l",
+	'ñ': "n", 'ń': "n", 'ņ': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ŏ': "o", 'ő': "o",
+	'ŕ': "r", 'ŗ': "r", 'ř': "r",
+	'ś': "s", 'ŝ': "s", 'ş': "s", 'š': "s", 'ß': "ss",
+	'ţ': "t", 'ť': "t", 'ŧ': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ũ': "u", 'ū': "u", 'ŭ': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ŵ': "w",
+	'ý': "y", 'ÿ': "y", 'ŷ': "y",
+	'ź': "z", 'ż': "z", 'ž': "z",
+	'æ': "ae", 'œ': "oe", 'þ': "th",
+}
+
+// Letters of other scripts, and ASCII characters, that look like Latin letters
+var confusables = map[rune]string{
+	// Cyrillic
+	'а': "a", 'в': "b", 'е': "e", 'ё': "e", 'з': "3", 'і': "l", 'ї': "l", 'ј': "j", 'к': "k", 'м': "m",
+	'н': "h", 'о': "o", 'р': "p", 'с': "c", 'т': "t", 'у': "y", 'х': "x", 'ѕ': "s", 'һ': "h", 'ԁ': "d",
+	'ԛ': "q", 'ԝ': "w", 'ь': "b",
+	// Greek
+	'α': "a", 'β': "b", 'γ': "y", 'ε': "e", 'η': "n", 'ι': "l", 'κ': "k", 'ν': "v", 'ο': "o", 'ρ': "p",
+	'τ': "t", 'υ': "u", 'χ': "x", 'ω': "w",
+	// Look-alike digits and letters
+	'0': "o", '1': "l", 'i': "l", '|': "l",
+}
+
+// Get the skeleton of a username: two usernames that look alike have the same skeleton
+// Case, accents, full-width forms, look-alike letters of other scripts and separators are folded away
+func UsernameSkeleton(username string) string {
+	var skeleton strings.Builder
+	for _, r := range strings.ToLower(username) {
+		// Full-width forms of ASCII characters
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r = unicode.ToLower(r - 0xFEE0)
+		}
+		if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r) || isSeparator(r) {
+			continue
+		}
+		folded, ok := latinFolds[r]
+		if !ok {
+			folded = string(r)
+		}
+		for _, f := range folded {
+			if confusable, ok := confusables[f]; ok {
+				skeleton.WriteString(confusable)
+			} else {
+				skeleton.WriteRune(f)
+			}
+		}
+	}
+
+	// Letter pairs that read as a single letter
+	result := skeleton.String()
+	result = strings.ReplaceAll(result, "rn", "m")
+	result = strings.ReplaceAll(result, "vv", "w")
+	return result
+}
+
+// Digits and symbols used in place of letters to get past word filters
+var leetFolds = strings.NewReplacer("3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "@", "a", "$", "s")
+
+// Get the form usernames and filter words are compared in
+func filterForm(text string) string {
+	return leetFolds.Replace(UsernameSkeleton(leetFolds.Replace(text)))
+}
@@ -0,0 +1,33 @@
+package validation
+
+import "testing"
+
+func TestUsernameSkeleton(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		want     string
+	}{
+		{"lower case", "bob", "bob"},
+		{"case is folded", "BoB", "bob"},
+		{"accents are folded", "bób", "bob"},
+		{"combining accents are dropped", "bo\u0301b", "bob"},
+		{"separators are dropped", "b_o.b-", "bob"},
+		{"cyrillic look-alikes", "bоb", "bob"},
+		{"greek look-alikes", "βοβ", "bob"},
+		{"full-width forms", "ＢＯＢ", "bob"},
+		{"digits that look like letters", "b0b", "bob"},
+		{"i and l look alike", "Ali", "all"},
+		{"rn reads as m", "rnax", "max"},
+		{"vv reads as w", "vvill", "wlll"},
+		{"zero-width characters are dropped", "bo\u200bb", "bob"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UsernameSkeleton(test.username); got != test.want {
+				t.Errorf("UsernameSkeleton(%q) = %q, want %q", test.username, got, test.want)
+			}
+		})
+	}
+}
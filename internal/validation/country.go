@@ -0,0 +1,66 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+)
+
+// Returned when a country is not given
+var ErrCountryRequired = errors.New("Country is required")
+
+// Returned when a country cannot be matched to an ISO 3166-1 country
+var ErrUnknownCountry = errors.New("Unknown country, use an ISO 3166-1 alpha-2 code such as \"TR\"")
+
+// Alpha-2 codes by folded alpha-2 code, alpha-3 code, name and alias
+var countryCodes = buildCountryCodes()
+
+// Normalize a country given as an alpha-2 code, an alpha-3 code or an English name to its ISO 3166-1 alpha-2 code
+// "Turkey", "TUR", "tr" and "Türkiye" all become "TR"
+func NormalizeCountry(country string) (string, error) {
+	key := countryKey(country)
+	if key == "" {
+		return "", ErrCountryRequired
+	}
+
+	code, ok := countryCodes[key]
+	if !ok {
+		return "", ErrUnknownCountry
+	}
+	return code, nil
+}
+
+// Check whether a country is already a known alpha-2 code
+func IsCountryCode(country string) bool {
+	code, ok := countryCodes[countryKey(country)]
+	return ok && code == country
+}
+
+// Fold a country to the form it is looked up by: lower case, without accents, dots and extra spaces
+func countryKey(country string) string {
+	var key strings.Builder
+	for _, r := range strings.ToLower(country) {
+		if r == '.' {
+			continue
+		}
+		if folded, ok := latinFolds[r]; ok {
+			key.WriteString(folded)
+			continue
+		}
+		key.WriteRune(r)
+	}
+	return strings.Join(strings.Fields(key.String()), " ")
+}
+
+func buildCountryCodes() map[string]string {
+	codes := make(map[string]string, 3*len(isoCountries)+len(countryAliases))
+	for _, entry := range isoCountries {
+		parts := strings.SplitN(entry, " ", 3)
+		codes[countryKey(parts[0])] = parts[0]
+		codes[countryKey(parts[1])] = parts[0]
+		codes[countryKey(parts[2])] = parts[0]
+	}
+	for alias, code := range countryAliases {
+		codes[countryKey(alias)] = code
+	}
+	return codes
+}
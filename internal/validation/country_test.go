@@ -0,0 +1,34 @@
+package validation
+
+import "testing"
+
+func TestNormalizeCountry(t *testing.T) {
+	tests := []struct {
+		country string
+		want    string
+		err     error
+	}{
+		{"TR", "TR", nil},
+		{"tr", "TR", nil},
+		{"TUR", "TR", nil},
+		{"Turkey", "TR", nil},
+		{"Türkiye", "TR", nil},
+		{"  turkey ", "TR", nil},
+		{"DEU", "DE", nil},
+		{"Germany", "DE", nil},
+		{"U.S.", "US", nil},
+		{"", "", ErrCountryRequired},
+		{"   ", "", ErrCountryRequired},
+		{"Atlantis", "", ErrUnknownCountry},
+		{"XX", "", ErrUnknownCountry},
+	}
+
+	for _, test := range tests {
+		t.Run(test.country, func(t *testing.T) {
+			got, err := NormalizeCountry(test.country)
+			if got != test.want || err != test.err {
+				t.Errorf("NormalizeCountry(%q) = %q, %v, want %q, %v", test.country, got, err, test.want, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package validation
+
+import (
+	"cloudblast-backend/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Returned when a username is too short or too long
+var ErrUsernameLength = fmt.Errorf("Username must be %d to %d characters long", config.UsernameMinLength, config.UsernameMaxLength)
+
+// Returned when a username has characters other than letters, digits and single separators between them
+var ErrUsernameCharset = errors.New("Username may only contain letters, digits and single '_', '.' or '-' between them")
+
+// Returned when a username mixes letters of several scripts
+var ErrUsernameMixedScripts = errors.New("Username may not mix letters of different alphabets")
+
+// Returned when a name is reserved for the game or its staff
+var ErrNameReserved = errors.New("Name is reserved")
+
+// Returned when a name contains a filtered word
+var ErrNameProfane = errors.New("Name contains a word that is not allowed")
+
+// Returned when a display name is empty or too long
+var ErrDisplayNameLength = fmt.Errorf("Display name must be 1 to %d characters long", config.DisplayNameMaxLength)
+
+// Returned when a display name has characters other than letters, digits, single spaces and separators
+var ErrDisplayNameCharset = errors.New("Display name may only contain letters, digits and single spaces, '_', '.' or '-' between them")
+
+// NameFilter is a version of the profanity and reserved-name lists, loaded from a file
+type NameFilter struct {
+	Version int `json:"version"`
+	// Words that may not appear anywhere in a username
+	Profanity []string `json:"profanity"`
+	// Usernames that may not be taken, however they are written
+	Reserved []string `json:"reserved"`
+	// Words that contain a filtered word but are fine, such as "Scunthorpe"
+	Allowed []string `json:"allowed"`
+
+	profanity []string
+	reserved  map[string]bool
+	allowed   []string
+}
+
+// Load a name filter file
+func LoadNameFilter(path string) (*NameFilter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var filter NameFilter
+	err = json.Unmarshal(data, &filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare the words in the form usernames are compared in, so look-alike spellings are caught
+	filter.reserved = make(map[string]bool, len(filter.Reserved))
+	for _, name := range filter.Reserved {
+		filter.reserved[filterForm(name)] = true
+	}
+	for _, word := range filter.Profanity {
+		if form := filterForm(word); form != "" {
+			filter.profanity = append(filter.profanity, form)
+		}
+	}
+	for _, word := range filter.Allowed {
+		if form := filterForm(word); form != "" {
+			filter.allowed = append(filter.allowed, form)
+		}
+	}
+
+	return &filter, nil
+}
+
+// Check a username against the length and charset rules and the name filter
+func ValidateUsername(username string, filter *NameFilter) error {
+	length := len([]rune(username))
+	if length < config.UsernameMinLength || length > config.UsernameMaxLength {
+		return ErrUsernameLength
+	}
+
+	err := checkUsernameCharset(username)
+	if err != nil {
+		return err
+	}
+
+	return checkNameFilter(username, filter)
+}
+
+// Check a name against the reserved names and the filtered words
+func checkNameFilter(name string, filter *NameFilter) error {
+	if filter == nil {
+		return nil
+	}
+	form := filterForm(name)
+	if filter.reserved[form] {
+		return ErrNameReserved
+	}
+	for _, word := range filter.allowed {
+		form = strings.ReplaceAll(form, word, " ")
+	}
+	for _, word := range filter.profanity {
+		if strings.Contains(form, word) {
+			return ErrNameProfane
+		}
+	}
+
+	return nil
+}
+
+// Check a display name against the length and charset rules and the name filter
+// Display names may contain spaces and need not be unique, but cannot be reserved names or contain filtered words
+func ValidateDisplayName(displayName string, filter *NameFilter) error {
+	length := len([]rune(displayName))
+	if length < 1 || length > config.DisplayNameMaxLength {
+		return ErrDisplayNameLength
+	}
+
+	// Spaces are checked like the other separators
+	err := checkUsernameCharset(strings.ReplaceAll(displayName, " ", "_"))
+	if err == ErrUsernameCharset {
+		return ErrDisplayNameCharset
+	}
+	if err != nil {
+		return err
+	}
+
+	return checkNameFilter(strings.ReplaceAll(displayName, " ", ""), filter)
+}
+
+// Check that a username is made of letters and digits of one script, with single separators between them
+func checkUsernameCharset(username string) error {
+	runes := []rune(username)
+	script := ""
+	for i, r := range runes {
+		if isSeparator(r) {
+			if i == 0 || i == len(runes)-1 || isSeparator(runes[i-1]) {
+				return ErrUsernameCharset
+			}
+			continue
+		}
+		if unicode.IsDigit(r) {
+			continue
+		}
+		if !unicode.IsLetter(r) {
+			return ErrUsernameCharset
+		}
+
+		letterScript := scriptOf(r)
+		if script != "" && letterScript != script {
+			return ErrUsernameMixedScripts
+		}
+		script = letterScript
+	}
+	return nil
+}
+
+// Check whether a character separates the parts of a username
+func isSeparator(r rune) bool {
+	return r == '_' || r == '.' || r == '-'
+}
+
+// Scripts that are written together, such as Han and Kana in Japanese
+var scriptGroups = map[string]string{
+	"Han":      "CJK",
+	"Hiragana": "CJK",
+	"Katakana": "CJK",
+	"Hangul":   "CJK",
+	"Bopomofo": "CJK",
+}
+
+// Get the script of a letter
+func scriptOf(r rune) string {
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			if group, ok := scriptGroups[name]; ok {
+				return group
+			}
+			return name
+		}
+	}
+	return ""
+}